@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"connectrpc.com/connect"
+
+	apiv1 "github.com/runar-rkmedia/audio-mirror/gen/api/v1"
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+// Limits for the feeds and artwork that are fetched to check a feed
+const (
+	maxFeedSize = 20 << 20
+	// Artwork is usually shared between episodes, so this is only reached by feeds with artwork for every episode
+	maxImageProbes = 20
+	checkTimeout   = 2 * time.Minute
+)
+
+var (
+	errNotPublic  = errors.New("the address is not public")
+	sharedAddress = netip.MustParsePrefix("100.64.0.0/10")
+)
+
+// CheckFeed implements apiv1connect.FeedServiceHandler.
+func (s *APIServer) CheckFeed(
+	ctx context.Context,
+	req *connect.Request[apiv1.CheckFeedRequest],
+) (*connect.Response[apiv1.CheckFeedResponse], error) {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	msg := req.Msg
+	specs, err := parseSpecs(msg.Specs)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	var channel *rss.Channel
+	switch {
+	case msg.Id != "":
+		chlists := genapi.FlattenAndDeduplicate(nil, s.TempChannelList)
+		for _, c := range chlists.Channels {
+			if c.GUID == msg.Id || c.Meta.ID == msg.Id {
+				// As it is served by HandleRssFeed
+				served := rss.RssHeader(c.Channel).Channel
+				channel = &served
+				break
+			}
+		}
+		if channel == nil {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("no channel with id %s", msg.Id))
+		}
+	case msg.Url != "":
+		channel, err = fetchFeed(ctx, s.Client, msg.Url)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	case msg.Body != "":
+		channel, err = rss.ParseWithOptions(strings.NewReader(msg.Body), rss.ParseOptions{Raw: true})
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	default:
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("one of id, url or body is required"))
+	}
+	opts := rss.CheckOptions{Specs: specs}
+	if msg.ProbeImages {
+		opts.ImageProber = rss.HTTPImageProber{Client: s.Client}
+		opts.MaxImageProbes = maxImageProbes
+	}
+	report := rss.Check(ctx, *channel, opts)
+	res := &apiv1.CheckFeedResponse{
+		Errors:   int32(report.Count(rss.SeverityError)),
+		Warnings: int32(report.Count(rss.SeverityWarning)),
+	}
+	for _, f := range report.Findings {
+		res.Findings = append(res.Findings, &apiv1.ComplianceFinding{
+			Severity:  severity(f.Severity),
+			Spec:      string(f.Spec),
+			Path:      f.Path,
+			Rule:      f.Rule,
+			Message:   f.Message,
+			Reference: f.Reference,
+		})
+	}
+	return connect.NewResponse(res), nil
+}
+
+func severity(s rss.Severity) apiv1.Severity {
+	switch s {
+	case rss.SeverityError:
+		return apiv1.Severity_SEVERITY_ERROR
+	case rss.SeverityWarning:
+		return apiv1.Severity_SEVERITY_WARNING
+	}
+	return apiv1.Severity_SEVERITY_UNSPECIFIED
+}
+
+func parseSpecs(names []string) ([]rss.Spec, error) {
+	var specs []rss.Spec
+	for _, name := range names {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		spec := rss.Spec(name)
+		if !slices.Contains(rss.Specs, spec) {
+			return nil, fmt.Errorf("unknown spec %q, expected one of %v", name, rss.Specs)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// newPublicHTTPClient creates a client for urls from callers, which only connects to public addresses,
+// so that the server cannot be used to reach internal services.
+func newPublicHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: dialPublic}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			// A proxy would hide the address that is connected to
+			Proxy:                  nil,
+			DialContext:            dialer.DialContext,
+			TLSHandshakeTimeout:    10 * time.Second,
+			ResponseHeaderTimeout:  10 * time.Second,
+			MaxResponseHeaderBytes: 1 << 20,
+		},
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			return checkScheme(r.URL)
+		},
+	}
+}
+
+// dialPublic is called with the resolved address, so a hostname cannot be re-resolved to an internal address
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddress.Contains(ip) {
+		return fmt.Errorf("%w: %s", errNotPublic, ip)
+	}
+	return nil
+}
+
+func checkScheme(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("the url must be an absolute http(s)-url, was %q", u)
+	}
+	return nil
+}
+
+// fetchFeed retrieves and parses the feed at the url, with its raw values so that Check can find their problems
+func fetchFeed(ctx context.Context, client genapi.HttpClient, feedURL string) (*rss.Channel, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("user-agent", "audio-mirror")
+	res, err := client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the feed %s: %w", feedURL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to fetch the feed %s: status-code %d", feedURL, res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the feed %s: %w", feedURL, err)
+	}
+	if len(body) > maxFeedSize {
+		return nil, fmt.Errorf("the feed %s is larger than %d bytes", feedURL, maxFeedSize)
+	}
+	return rss.ParseWithOptions(bytes.NewReader(body), rss.ParseOptions{ContentType: res.Header.Get("content-type"), Raw: true})
+}
+
+// checkFeed prints the compliance-report of the feed, which is either an url or a file, to w.
+// It returns an error if the feed cannot be read, or if the report has errors.
+func checkFeed(ctx context.Context, w io.Writer, client genapi.HttpClient, source string, specNames string) error {
+	specs, err := parseSpecs(strings.Split(specNames, ","))
+	if err != nil {
+		return err
+	}
+	var channel *rss.Channel
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		channel, err = fetchFeed(ctx, client, source)
+	} else {
+		var f *os.File
+		f, err = os.Open(source)
+		if err != nil {
+			return fmt.Errorf("failed to read the feed: %w", err)
+		}
+		defer f.Close()
+		channel, err = rss.ParseWithOptions(f, rss.ParseOptions{Raw: true})
+	}
+	if err != nil {
+		return err
+	}
+	report := rss.Check(ctx, *channel, rss.CheckOptions{Specs: specs, ImageProber: rss.HTTPImageProber{Client: client}})
+	for _, f := range report.Findings {
+		fmt.Fprintf(w, "%s\n\t%s\n", f, f.Reference)
+	}
+	errs, warnings := report.Count(rss.SeverityError), report.Count(rss.SeverityWarning)
+	fmt.Fprintf(w, "%d errors, %d warnings in %d episodes\n", errs, warnings, len(channel.Item))
+	if errs > 0 {
+		return fmt.Errorf("the feed has %d errors", errs)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_dialPublic(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.215.14:443":      true,
+		"[2606:2800:21f::1]:443": true,
+		"127.0.0.1:80":           false,
+		"10.0.0.1:80":            false,
+		"192.168.1.1:80":         false,
+		"169.254.169.254:80":     false,
+		"100.64.0.1:80":          false,
+		"0.0.0.0:80":             false,
+		"[::1]:80":               false,
+		"[fd00::1]:80":           false,
+		"[::ffff:127.0.0.1]:80":  false,
+		"[fe80::1%eth0]:80":      false,
+	} {
+		err := dialPublic("tcp", address, nil)
+		if public && err != nil {
+			t.Errorf("expected %s to be allowed, got %v", address, err)
+		}
+		if !public && !errors.Is(err, errNotPublic) {
+			t.Errorf("expected %s to be rejected with errNotPublic, got %v", address, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+	"github.com/runar-rkmedia/audio-mirror/genapi/fixture"
+)
+
+const (
+	httpModeLive   = "live"
+	httpModeRecord = "record"
+	httpModeReplay = "replay"
+)
+
+// newHTTPClient returns the client for the providers, which either performs requests, records them to fixtures, or replays fixtures.
+func newHTTPClient(mode, fixtureDir, match string, redact fixture.Redaction) (genapi.HttpClient, error) {
+	client := &http.Client{}
+	switch mode {
+	case "", httpModeLive:
+		return client, nil
+	case httpModeRecord:
+		rec := fixture.NewRecorder(client, fixtureDir)
+		rec.Redact = redact
+		return rec, nil
+	case httpModeReplay:
+		matchMode, err := fixture.ParseMatchMode(match)
+		if err != nil {
+			return nil, err
+		}
+		replayer := fixture.NewReplayer(fixtureDir, matchMode)
+		replayer.Redact = redact
+		return replayer, nil
+	}
+	return nil, fmt.Errorf("unknown http-mode %q, expected one of %s, %s, %s", mode, httpModeLive, httpModeRecord, httpModeReplay)
+}
+
+// fixtureRedaction redacts the comma-separated query-parameters and body-fields, in addition to the defaults
+func fixtureRedaction(query, fields string) fixture.Redaction {
+	split := func(s string, defaults []string) []string {
+		names := slices.Clone(defaults)
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return fixture.Redaction{
+		Query:  split(query, genapi.DefaultRedactQuery),
+		Fields: split(fields, fixture.DefaultRedactFields),
+	}
+}
+
+// redactedSecrets resolves every secret, since the secrets are redacted from the fixtures when replaying
+type redactedSecrets struct{}
+
+func (redactedSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	return fixture.Redacted, nil
+}
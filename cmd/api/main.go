@@ -3,14 +3,17 @@ package main
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"connectrpc.com/connect"
@@ -22,16 +25,25 @@ import (
 	apiv1 "github.com/runar-rkmedia/audio-mirror/gen/api/v1" // generated by protoc-gen-go
 	"github.com/runar-rkmedia/audio-mirror/gen/api/v1/apiv1connect"
 	"github.com/runar-rkmedia/audio-mirror/genapi"
+	feed "github.com/runar-rkmedia/audio-mirror/genapi/apifeed"
 	untold "github.com/runar-rkmedia/audio-mirror/genapi/apiuntold"
+	"github.com/runar-rkmedia/audio-mirror/genapi/fixture"
 	"github.com/runar-rkmedia/audio-mirror/logger"
 	"github.com/runar-rkmedia/audio-mirror/rss"
+	"github.com/runar-rkmedia/audio-mirror/secrets"
 )
 
 type APIServer struct {
-	OriginHost         string
-	OriginScheme       string
-	TempChannelList    []genapi.GenAPIChannelList
-	TempChannelFinders []ChannelFinder
+	OriginHost      string
+	OriginScheme    string
+	TempChannelList []genapi.GenAPIChannelList
+	// Routes requests for a channel to the provider it came from
+	Registry *genapi.Registry
+	// By name, used to preview mappings against cached responses
+	Providers map[string]*genapi.GenAPI
+	// Used to fetch feeds and artwork for CheckFeed. The urls come from the caller,
+	// so it must only connect to public addresses, see newPublicHTTPClient
+	Client genapi.HttpClient
 }
 
 // GetEpisodes implements apiv1connect.FeedServiceHandler.
@@ -42,19 +54,16 @@ func (s *APIServer) GetEpisodes(ctx context.Context, req *connect.Request[apiv1.
 		if v.Meta.ID != req.Msg.Id {
 			continue
 		}
-		for _, lister := range s.TempChannelFinders {
-			episodes, _, err := lister.ListEpisodes(ctx, v.Meta.ID)
-			if err != nil {
-				return nil, err
-			}
-			episodesPayload := mapEpsiodes(episodes.Items)
-			return &connect.Response[apiv1.GetEpisodesResponse]{
-				Msg: &apiv1.GetEpisodesResponse{
-					Episodes: episodesPayload,
-				},
-			}, nil
-
+		episodes, _, err := s.Registry.ListEpisodes(ctx, v)
+		if err != nil {
+			return nil, err
 		}
+		episodesPayload := mapEpsiodes(episodes.Items)
+		return &connect.Response[apiv1.GetEpisodesResponse]{
+			Msg: &apiv1.GetEpisodesResponse{
+				Episodes: episodesPayload,
+			},
+		}, nil
 	}
 	return nil, nil
 }
@@ -108,7 +117,6 @@ func (s *APIServer) GetChannels(
 	res := connect.NewResponse(&apiv1.GetChannelsResponse{
 		Channels: []*apiv1.Channel{},
 	})
-	fmt.Println("source", s.TempChannelList[0].Channels[0].Meta)
 	chlists := genapi.FlattenAndDeduplicate(nil, s.TempChannelList)
 	for _, v := range chlists.Channels {
 		res.Msg.Channels = append(res.Msg.Channels, s.mapChannel(v, req))
@@ -134,16 +142,12 @@ func (s *APIServer) GetChannel(
 		}
 		ch := s.mapChannel(v, req)
 		res.Msg.Channel = ch
-		for _, lister := range s.TempChannelFinders {
-			episodes, _, err := lister.ListEpisodes(ctx, v.Meta.ID)
-			if err != nil {
-				return nil, err
-			}
-			episodesPayload := mapEpsiodes(episodes.Items)
-			res.Msg.Episodes = episodesPayload
-			return res, nil
-
+		episodes, _, err := s.Registry.ListEpisodes(ctx, v)
+		if err != nil {
+			return nil, err
 		}
+		res.Msg.Episodes = mapEpsiodes(episodes.Items)
+		return res, nil
 	}
 	return res, nil
 }
@@ -160,6 +164,18 @@ func ChanType(c genapi.ChannelType) apiv1.ChannelType {
 
 func main() {
 	originHost := flag.String("originhost", "", "Set the host to use. Most proxies does not expose the real host to server, so this can set it manually")
+	providersDir := flag.String("providers", "", "Directory with provider-definitions (yaml or json) to load in addition to the builtin providers")
+	feedsFile := flag.String("feeds", "", "File (yaml or json) with public podcast-feeds to mirror alongside the providers")
+	setSecret := flag.String("set-secret", "", "Store the secret with this name, reading the value from stdin, and exit. Requires "+secrets.MasterKeyEnv)
+	rotateMasterKey := flag.Bool("rotate-master-key", false, "Re-encrypt all secrets with the master-key in "+newMasterKeyEnv+" and exit")
+	httpMode := flag.String("http-mode", httpModeLive, "How providers perform requests, one of live, record (to fixtures) or replay (from fixtures, offline)")
+	fixtureDir := flag.String("fixtures", "./fixtures", "Directory for recorded http-fixtures")
+	fetchConcurrency := flag.Int("fetch-concurrency", genapi.DefaultFetchConcurrency, "The number of channels whose episodes are fetched concurrently, for providers that do not set it themselves")
+	fixtureMatch := flag.String("fixture-match", string(fixture.MatchStrict), "How requests are matched to fixtures when replaying, strict or lenient")
+	fixtureRedactQuery := flag.String("fixture-redact-query", "", "Comma-separated query-parameters to redact from fixtures, in addition to "+strings.Join(genapi.DefaultRedactQuery, ","))
+	fixtureRedactFields := flag.String("fixture-redact-fields", "", "Comma-separated fields of form- and json-bodies to redact from fixtures, in addition to "+strings.Join(fixture.DefaultRedactFields, ","))
+	checkFeedSource := flag.String("check-feed", "", "Print the compliance-report of the feed (an url or a file) and exit, with a non-zero status if it has errors")
+	checkSpecs := flag.String("check-specs", "", "Comma-separated specifications for -check-feed, of rss, apple, spotify and podcast. Defaults to all of them")
 	flag.Parse()
 	if *originHost == "" {
 		*originHost = os.Getenv("AUDIO_MIRROR_ORIGINHOST")
@@ -169,6 +185,16 @@ func main() {
 		panic("Failed to create logger" + err.Error())
 	}
 	slog.SetDefault(l.Logger)
+	if *checkFeedSource != "" {
+		client, err := newHTTPClient(*httpMode, *fixtureDir, *fixtureMatch, fixtureRedaction(*fixtureRedactQuery, *fixtureRedactFields))
+		if err != nil {
+			l.FatalErr("failed to create http-client", err)
+		}
+		if err := checkFeed(context.TODO(), os.Stdout, client, *checkFeedSource, *checkSpecs); err != nil {
+			l.FatalErr("failed to check the feed", err, slog.String("feed", *checkFeedSource))
+		}
+		return
+	}
 	db, err := db.CreateDatabase(db.DBOptions{
 		InMemory: false,
 		FilePath: "./db.sqlite3",
@@ -178,16 +204,82 @@ func main() {
 	}
 	ctx := context.TODO()
 	db.GetChannels(ctx)
-	untold, err := initUntold(l)
+	secretStore, err := secrets.NewStoreFromEnv(db)
+	if err != nil && !errors.Is(err, secrets.ErrNoMasterKey) {
+		l.FatalErr("failed to create secret-store", err)
+	}
+	if *setSecret != "" || *rotateMasterKey {
+		if secretStore == nil {
+			l.Fatal(secrets.MasterKeyEnv + " must be set to manage secrets")
+		}
+		if err := manageSecrets(ctx, l, secretStore, *setSecret, *rotateMasterKey); err != nil {
+			l.FatalErr("failed to manage secrets", err)
+		}
+		return
+	}
+	secretStores := genapi.SecretStores{}
+	if secretStore != nil {
+		secretStores = append(secretStores, secretStore)
+	} else {
+		l.Warn("No master-key set, secrets will only be read from the environment", slog.String("env", secrets.MasterKeyEnv))
+	}
+	secretStores = append(secretStores, genapi.EnvSecrets{})
+	if *httpMode == httpModeReplay {
+		secretStores = append(secretStores, redactedSecrets{})
+	}
+	client, err := newHTTPClient(*httpMode, *fixtureDir, *fixtureMatch, fixtureRedaction(*fixtureRedactQuery, *fixtureRedactFields))
 	if err != nil {
-		l.FatalErr("failed to init untold", err)
+		l.FatalErr("failed to create http-client", err)
 	}
-	// Temp
-	channelLists, err := FindChannels(context.TODO(), l, untold)
+	if *httpMode != httpModeLive {
+		l.Info("Using http-fixtures", slog.String("mode", *httpMode), slog.String("dir", *fixtureDir))
+	}
+	genOptions := newGenAPIOptions(l, secretStores, client)
+	if secretStore != nil {
+		// Tokens are encrypted like the other secrets
+		genOptions.Tokens = secretStore
+	} else {
+		l.Warn("No master-key set, tokens from logins will not be persisted", slog.String("env", secrets.MasterKeyEnv))
+	}
+	untold, err := initUntold(l, genOptions)
 	if err != nil {
-		l.FatalErr("Failed to Find channels", err)
+		l.FatalErr("failed to init untold", err)
+	}
+	registry := genapi.NewRegistry()
+	if err := registry.Register(untold.Name, untold); err != nil {
+		l.FatalErr("failed to register provider", err)
+	}
+	providers := map[string]*genapi.GenAPI{untold.Name: untold.GenAPI}
+	fetchOptions := genapi.FetchOptions{Concurrency: *fetchConcurrency, ProviderConcurrency: map[string]int{untold.Name: untold.FetchConcurrency}}
+	if *providersDir != "" {
+		apis, err := genapi.LoadDefinitionDir(*providersDir, genOptions)
+		if err != nil {
+			l.FatalErr("failed to load provider-definitions", err, slog.String("dir", *providersDir))
+		}
+		for _, api := range apis {
+			l.Info("Loaded provider from definition", slog.String("name", api.Name))
+			if err := registry.Register(api.Name, api); err != nil {
+				l.FatalErr("failed to register provider", err, slog.String("name", api.Name))
+			}
+			providers[api.Name] = api
+			fetchOptions.ProviderConcurrency[api.Name] = api.FetchConcurrency
+		}
+	}
+	if *feedsFile != "" {
+		feeds, err := initFeeds(*feedsFile, genOptions)
+		if err != nil {
+			l.FatalErr("failed to load feeds", err, slog.String("file", *feedsFile))
+		}
+		if err := registry.Register(feeds.Name, feeds); err != nil {
+			l.FatalErr("failed to register provider", err, slog.String("name", feeds.Name))
+		}
+		providers[feeds.Name] = feeds.GenAPI
+		fetchOptions.ProviderConcurrency[feeds.Name] = feeds.FetchConcurrency
+		l.Info("Subscribed to feeds", slog.Int("count", len(feeds.Feeds())))
 	}
-	feedServer := &APIServer{TempChannelList: channelLists, TempChannelFinders: []ChannelFinder{untold}, OriginHost: *originHost}
+	// Temp
+	channelLists := fetchChannels(ctx, l, registry, fetchOptions)
+	feedServer := &APIServer{TempChannelList: channelLists, Registry: registry, Providers: providers, OriginHost: *originHost, Client: newPublicHTTPClient()}
 
 	switch feedServer.OriginScheme {
 	case "":
@@ -198,9 +290,11 @@ func main() {
 		feedServer.OriginScheme = "http://"
 	}
 	mux := http.NewServeMux()
-	path, handler := apiv1connect.NewFeedServiceHandler(feedServer)
+	// Limits the sample-bodies of PreviewMapping, SuggestMapping and CheckFeed
+	path, handler := apiv1connect.NewFeedServiceHandler(feedServer, connect.WithReadMaxBytes(maxFeedSize))
 	mux.Handle(path, handler)
 	mux.HandleFunc("GET /feed/{id}", feedServer.HandleRssFeed)
+	mux.HandleFunc("POST /feed/{id}/refresh", feedServer.HandleRefreshFeed)
 	mux.HandleFunc("/", proxyPass)
 	address := "0.0.0.0:8080"
 	l.Info("Webserver starting", slog.String("address", address), slog.String("originHost", feedServer.OriginHost))
@@ -227,36 +321,105 @@ func proxyPass(res http.ResponseWriter, req *http.Request) {
 func (s *APIServer) HandleRssFeed(w http.ResponseWriter, req *http.Request) {
 	idString := req.PathValue("id")
 	fmt.Println("incoming", idString)
+	channel, ok := s.findChannel(idString)
+	if !ok {
+		w.WriteHeader(400)
+		return
+	}
+	// The episodes are listed on every request, so that the providers can serve them from their cache,
+	// or fetch them again when it has expired. The episodes from startup are only used if that fails
+	episodes, _, err := s.Registry.ListEpisodes(req.Context(), channel)
+	switch {
+	case err == nil:
+		channel.Item = episodes.Items
+	case len(channel.Item) > 0:
+		slog.Warn("Failed to list episodes, serving the episodes from startup", slog.String("id", idString), slog.Any("error", err))
+	default:
+		slog.Error("Failed to list episodes", slog.String("id", idString), slog.Any("error", err))
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	s.writeRssFeed(w, channel)
+}
+
+// HandleRefreshFeed revalidates the channel now, for providers that cache their channels, like feeds, and serves the refreshed feed
+func (s *APIServer) HandleRefreshFeed(w http.ResponseWriter, req *http.Request) {
+	channel, ok := s.findChannel(req.PathValue("id"))
+	if !ok {
+		w.WriteHeader(400)
+		return
+	}
+	refreshed, err := s.Registry.Refresh(req.Context(), channel)
+	if errors.Is(err, genapi.ErrUnsupported) {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to refresh channel", slog.String("id", channel.Meta.ID), slog.Any("error", err))
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	s.writeRssFeed(w, refreshed)
+}
+
+// findChannel finds the channel by its GUID or id
+func (s *APIServer) findChannel(id string) (genapi.GenApiChannel, bool) {
 	chlists := genapi.FlattenAndDeduplicate(nil, s.TempChannelList)
 	for _, channel := range chlists.Channels {
-		if channel.GUID == idString || channel.Meta.ID == idString {
-			rssFeed := rss.RssHeader(channel.Channel)
-			x, err := xml.MarshalIndent(rssFeed, "", "  ")
-			fmt.Println("channel", channel.Title, len(channel.Item))
-			w.Header().Set("Content-Type", "application/xml")
-			if err != nil {
-				w.WriteHeader(500)
-				return
-			}
-			// return
-			if _, err := w.Write(x); err != nil {
-				w.WriteHeader(500)
-				return
-			}
-			return
+		if channel.GUID == id || channel.Meta.ID == id {
+			return channel, true
 		}
 	}
-	w.WriteHeader(400)
+	return genapi.GenApiChannel{}, false
 }
 
-type ChannelFinder interface {
-	FindAllChannels(ctx context.Context) ([]genapi.GenAPIChannelList, error)
-	ListEpisodes(ctx context.Context, id string) (*genapi.GenAPIEpisodeList, *http.Response, error)
+func (s *APIServer) writeRssFeed(w http.ResponseWriter, channel genapi.GenApiChannel) {
+	rssFeed := rss.RssHeader(channel.Channel)
+	x, err := xml.MarshalIndent(rssFeed, "", "  ")
+	fmt.Println("channel", channel.Title, len(channel.Item))
+	w.Header().Set("Content-Type", "application/xml")
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+	if _, err := w.Write(x); err != nil {
+		w.WriteHeader(500)
+		return
+	}
 }
 
-// deprecated only here temproarily during development until there is a database
-func initUntold(l *logger.Logger) (ChannelFinder, error) {
-	client := http.Client{}
+const newMasterKeyEnv = "AUDIO_MIRROR_NEW_MASTER_KEY"
+
+func manageSecrets(ctx context.Context, l *logger.Logger, store *secrets.Store, setSecret string, rotate bool) error {
+	if setSecret != "" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read secret from stdin: %w", err)
+		}
+		value := strings.TrimRight(string(b), "\r\n")
+		if value == "" {
+			return fmt.Errorf("the secret %s is empty", setSecret)
+		}
+		if err := store.SetSecret(ctx, setSecret, value); err != nil {
+			return err
+		}
+		l.Info("Stored secret", slog.String("name", setSecret))
+	}
+	if rotate {
+		newKey := os.Getenv(newMasterKeyEnv)
+		if newKey == "" {
+			return fmt.Errorf("%s must be set to rotate the master-key", newMasterKeyEnv)
+		}
+		n, err := store.RotateMasterKey(ctx, newKey)
+		if err != nil {
+			return err
+		}
+		l.Info("Rotated master-key, remember to update "+secrets.MasterKeyEnv+" and to remove the old key from "+secrets.PreviousMasterKeysEnv, slog.Int("secrets", n))
+	}
+	return nil
+}
+
+func newGenAPIOptions(l *logger.Logger, secretStore genapi.SecretStore, client genapi.HttpClient) genapi.GenAPIOptions {
 	cacheDir := "./.cache"
 	cacheDir, err := filepath.Abs(cacheDir)
 	if err != nil {
@@ -267,40 +430,61 @@ func initUntold(l *logger.Logger) (ChannelFinder, error) {
 		l.Fatal("Failed to initiate cache for cacheDir", slog.String("cacheDir", cacheDir), slog.Any("error", err))
 	}
 	cache := cache.NewCache(cacheDir)
-	genOptions := genapi.GenAPIOptions{
-		Logger: l.Logger,
-		Client: &client,
-		Cache:  cache,
+	return genapi.GenAPIOptions{
+		Logger:  l.Logger,
+		Client:  client,
+		Cache:   cache,
+		Secrets: secretStore,
 	}
-	untoldToken := os.Getenv("UNTOLD_TOKEN")
-	if untoldToken == "" {
-		l.Fatal("UNTOLD_TOKEN not set, quitting")
+}
+
+// deprecated only here temproarily during development until there is a database
+// The token is read from the secret untold.token, or UNTOLD_TOKEN
+func initUntold(l *logger.Logger, genOptions genapi.GenAPIOptions) (*untold.UntoldAPI, error) {
+	if _, err := genOptions.Secrets.GetSecret(context.TODO(), untold.TokenSecret); err != nil {
+		l.Fatal("untold-token is not set, quitting", slog.Any("error", err))
 	}
 	options := untold.UntoldAPIOptions{
 		GenAPIOptions: genOptions,
-		Token:         untoldToken,
 	}
 	untold, err := untold.NewUntoldAPI(options)
 	return untold, err
 }
 
-// deprecated will not be used to find episodes in the future.
-func FindChannels(ctx context.Context, l *logger.Logger, channelFinder ChannelFinder) ([]genapi.GenAPIChannelList, error) {
-	channelLists, err := channelFinder.FindAllChannels(ctx)
+func initFeeds(filePath string, genOptions genapi.GenAPIOptions) (*feed.FeedAPI, error) {
+	file, err := feed.LoadFeedsFile(filePath)
 	if err != nil {
-		return channelLists, err
-	}
-	for _, lists := range channelLists {
-		for i := range lists.Channels {
-			channel := &lists.Channels[i]
-			episodes, _, err := channelFinder.ListEpisodes(ctx, channel.Meta.ID)
-			if err != nil {
-				l.Fatal("failed?", slog.Any("error", err))
-			}
-			channel.Item = episodes.Items
-		}
+		return nil, err
+	}
+	return feed.NewFeedAPI(feed.FeedAPIOptions{
+		GenAPIOptions:   genOptions,
+		Feeds:           file.Feeds,
+		RefreshInterval: file.RefreshInterval,
+	})
+}
+
+// fetchChannels fetches every channel with its episodes, and logs the ones that failed, without stopping.
+func fetchChannels(ctx context.Context, l *logger.Logger, registry *genapi.Registry, opts genapi.FetchOptions) []genapi.GenAPIChannelList {
+	start := time.Now()
+	report := registry.FetchAll(ctx, opts)
+	for _, e := range report.Errors {
+		l.Warn("Failed to fetch",
+			slog.String("source", e.Source),
+			slog.String("channel", e.ChannelID),
+			slog.String("title", e.Title),
+			slog.Any("error", e.Err),
+		)
 	}
-	return channelLists, nil
+	channels := 0
+	for _, list := range report.Channels {
+		channels += len(list.Channels)
+	}
+	l.Info("Fetched channels",
+		slog.Int("channels", channels),
+		slog.Int("errors", len(report.Errors)),
+		slog.Duration("duration", time.Since(start)),
+	)
+	return report.Channels
 }
 
 func formatDuration(d time.Duration) string {
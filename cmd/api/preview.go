@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"connectrpc.com/connect"
+
+	apiv1 "github.com/runar-rkmedia/audio-mirror/gen/api/v1"
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+)
+
+// PreviewMapping implements apiv1connect.FeedServiceHandler.
+func (s *APIServer) PreviewMapping(
+	ctx context.Context,
+	req *connect.Request[apiv1.PreviewMappingRequest],
+) (*connect.Response[apiv1.PreviewMappingResponse], error) {
+	msg := req.Msg
+	if msg.Endpoint == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("endpoint is required"))
+	}
+	var api *genapi.GenAPI
+	if msg.Provider != "" {
+		api = s.Providers[msg.Provider]
+		if api == nil {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("no provider named %s", msg.Provider))
+		}
+	} else {
+		api = &genapi.GenAPI{Name: "preview", GenAPIOptions: genapi.GenAPIOptions{Logger: slog.Default()}}
+		if msg.BaseUrl != "" {
+			u, err := url.Parse(msg.BaseUrl)
+			if err != nil {
+				return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid base_url: %w", err))
+			}
+			api.URL = u
+		}
+	}
+	body, err := sampleBody(api, msg.Provider, msg.CacheKey, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := genapi.GenAPIEndpoint{
+		Path:        msg.Endpoint.Path,
+		Query:       msg.Endpoint.Query,
+		Method:      msg.Endpoint.Method,
+		DataType:    msg.Endpoint.DataType,
+		RootMapping: msg.Endpoint.RootMapping,
+		Mapping:     msg.Endpoint.Mapping,
+	}
+	preview, err := api.PreviewMapping(ctx, endpoint, previewKind(msg.Kind), body)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	decoded, err := json.MarshalIndent(preview.Decoded, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	res := &apiv1.PreviewMappingResponse{
+		DecodedJson:    string(decoded),
+		UnmappedFields: preview.UnmappedFields,
+		Rss:            preview.RSS,
+	}
+	for _, e := range preview.Errors {
+		res.Errors = append(res.Errors, &apiv1.MappingFieldError{Item: int32(e.Item), Field: e.Field, Error: e.Err})
+	}
+	return connect.NewResponse(res), nil
+}
+
+// SuggestMapping implements apiv1connect.FeedServiceHandler.
+func (s *APIServer) SuggestMapping(
+	ctx context.Context,
+	req *connect.Request[apiv1.SuggestMappingRequest],
+) (*connect.Response[apiv1.SuggestMappingResponse], error) {
+	msg := req.Msg
+	var api *genapi.GenAPI
+	if msg.Provider != "" {
+		api = s.Providers[msg.Provider]
+		if api == nil {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("no provider named %s", msg.Provider))
+		}
+	}
+	body, err := sampleBody(api, msg.Provider, msg.CacheKey, msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	suggestion, err := genapi.SuggestMapping(body, previewKind(msg.Kind))
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	res := &apiv1.SuggestMappingResponse{
+		RootMapping:    suggestion.RootMapping,
+		RootConfidence: suggestion.RootConfidence,
+	}
+	for _, f := range suggestion.Fields {
+		res.Suggestions = append(res.Suggestions, &apiv1.MappingSuggestion{Field: f.Field, Path: f.Path, Confidence: f.Confidence, Reason: f.Reason})
+	}
+	return connect.NewResponse(res), nil
+}
+
+// sampleBody returns either the body, or the cached response for the provider
+func sampleBody(api *genapi.GenAPI, provider, cacheKey, body string) ([]byte, error) {
+	if cacheKey == "" {
+		if body == "" {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("either body or cache_key is required"))
+		}
+		return []byte(body), nil
+	}
+	if provider == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("provider is required with cache_key"))
+	}
+	cached, ok := api.CachedResponse(cacheKey)
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("no cached response for %s", cacheKey))
+	}
+	return cached, nil
+}
+
+func previewKind(kind apiv1.PreviewKind) genapi.PreviewKind {
+	if kind == apiv1.PreviewKind_PREVIEW_KIND_CHANNELS {
+		return genapi.PreviewChannels
+	}
+	return genapi.PreviewItems
+}
@@ -48,6 +48,9 @@ func (db DB) createTables() error {
 	if _, err := db.DB.NewCreateTable().Model((*Episode)(nil)).IfNotExists().Exec(ctx); err != nil {
 		return fmt.Errorf("failed to create table episode: %w", err)
 	}
+	if _, err := db.DB.NewCreateTable().Model((*Secret)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return fmt.Errorf("failed to create table secrets: %w", err)
+	}
 
 	return nil
 }
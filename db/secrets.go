@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Secret is stored encrypted, see the secrets-package for encryption and decryption.
+type Secret struct {
+	bun.BaseModel `bun:"table:secrets,alias:s"`
+	CreatedAt     time.Time `bun:",nullzero,notnull,default:current_timestamp"`
+	UpdatedAt     time.Time `bun:",nullzero,notnull,default:current_timestamp"`
+	Name          string    `bun:",pk"`
+	// Identifies the master-key used to encrypt the value
+	KeyID      string `bun:",notnull"`
+	Nonce      []byte `bun:",notnull"`
+	Ciphertext []byte `bun:",notnull"`
+}
+
+func (db DB) GetSecret(ctx context.Context, name string) (Secret, error) {
+	var secret Secret
+	err := db.DB.NewSelect().Model(&secret).Where("name = ?", name).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return secret, fmt.Errorf("%w: secret %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return secret, fmt.Errorf("failed to retrieve secret %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+func (db DB) ListSecrets(ctx context.Context) ([]Secret, error) {
+	var secrets []Secret
+	err := db.DB.NewSelect().Model(&secrets).OrderExpr("name ASC").Scan(ctx)
+	if err != nil {
+		return secrets, fmt.Errorf("failed to retrieve secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// UpsertSecret creates the secret, or replaces the value of an existing secret with the same name
+func (db DB) UpsertSecret(ctx context.Context, secret Secret) error {
+	secret.UpdatedAt = time.Now()
+	_, err := db.DB.NewInsert().
+		Model(&secret).
+		On("CONFLICT (name) DO UPDATE").
+		Set("updated_at = EXCLUDED.updated_at").
+		Set("key_id = EXCLUDED.key_id").
+		Set("nonce = EXCLUDED.nonce").
+		Set("ciphertext = EXCLUDED.ciphertext").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to upsert secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+func (db DB) DeleteSecret(ctx context.Context, name string) error {
+	_, err := db.DB.NewDelete().Model((*Secret)(nil)).Where("name = ?", name).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret %s: %w", name, err)
+	}
+	return nil
+}
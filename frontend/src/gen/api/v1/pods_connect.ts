@@ -3,7 +3,7 @@
 /* eslint-disable */
 // @ts-nocheck
 
-import { GetChannelRequest, GetChannelResponse, GetChannelsRequest, GetChannelsResponse, GetEpisodesRequest, GetEpisodesResponse } from "./pods_pb.js";
+import { CheckFeedRequest, CheckFeedResponse, GetChannelRequest, GetChannelResponse, GetChannelsRequest, GetChannelsResponse, GetEpisodesRequest, GetEpisodesResponse, PreviewMappingRequest, PreviewMappingResponse, SuggestMappingRequest, SuggestMappingResponse } from "./pods_pb.js";
 import { MethodKind } from "@bufbuild/protobuf";
 
 /**
@@ -43,6 +43,39 @@ export const FeedService = {
       O: GetEpisodesResponse,
       kind: MethodKind.Unary,
     },
+    /**
+     * Decodes a sample with a mapping, without requesting the upstream api.
+     *
+     * @generated from rpc api.v1.FeedService.PreviewMapping
+     */
+    previewMapping: {
+      name: "PreviewMapping",
+      I: PreviewMappingRequest,
+      O: PreviewMappingResponse,
+      kind: MethodKind.Unary,
+    },
+    /**
+     * Suggests a mapping from a sample json-response.
+     *
+     * @generated from rpc api.v1.FeedService.SuggestMapping
+     */
+    suggestMapping: {
+      name: "SuggestMapping",
+      I: SuggestMappingRequest,
+      O: SuggestMappingResponse,
+      kind: MethodKind.Unary,
+    },
+    /**
+     * Checks a feed against the specifications of RSS, Apple Podcasts, Spotify and the podcast-namespace.
+     *
+     * @generated from rpc api.v1.FeedService.CheckFeed
+     */
+    checkFeed: {
+      name: "CheckFeed",
+      I: CheckFeedRequest,
+      O: CheckFeedResponse,
+      kind: MethodKind.Unary,
+    },
   }
 } as const;
 
@@ -36,6 +36,66 @@ proto3.util.setEnumType(ChannelType, "api.v1.ChannelType", [
   { no: 2, name: "CHANNEL_TYPE_AUDIO_BOOK" },
 ]);
 
+/**
+ * @generated from enum api.v1.PreviewKind
+ */
+export enum PreviewKind {
+  /**
+   * @generated from enum value: PREVIEW_KIND_UNSPECIFIED = 0;
+   */
+  UNSPECIFIED = 0,
+
+  /**
+   * Decode into episodes
+   *
+   * @generated from enum value: PREVIEW_KIND_ITEMS = 1;
+   */
+  ITEMS = 1,
+
+  /**
+   * Decode into channels
+   *
+   * @generated from enum value: PREVIEW_KIND_CHANNELS = 2;
+   */
+  CHANNELS = 2,
+}
+// Retrieve enum metadata with: proto3.getEnumType(PreviewKind)
+proto3.util.setEnumType(PreviewKind, "api.v1.PreviewKind", [
+  { no: 0, name: "PREVIEW_KIND_UNSPECIFIED" },
+  { no: 1, name: "PREVIEW_KIND_ITEMS" },
+  { no: 2, name: "PREVIEW_KIND_CHANNELS" },
+]);
+
+/**
+ * @generated from enum api.v1.Severity
+ */
+export enum Severity {
+  /**
+   * @generated from enum value: SEVERITY_UNSPECIFIED = 0;
+   */
+  UNSPECIFIED = 0,
+
+  /**
+   * The feed, or the episode, is rejected
+   *
+   * @generated from enum value: SEVERITY_ERROR = 1;
+   */
+  ERROR = 1,
+
+  /**
+   * The feed is accepted, but may be shown wrongly
+   *
+   * @generated from enum value: SEVERITY_WARNING = 2;
+   */
+  WARNING = 2,
+}
+// Retrieve enum metadata with: proto3.getEnumType(Severity)
+proto3.util.setEnumType(Severity, "api.v1.Severity", [
+  { no: 0, name: "SEVERITY_UNSPECIFIED" },
+  { no: 1, name: "SEVERITY_ERROR" },
+  { no: 2, name: "SEVERITY_WARNING" },
+]);
+
 /**
  * Like a podcast or an audio-book
  *
@@ -406,3 +466,635 @@ export class GetEpisodesResponse extends Message<GetEpisodesResponse> {
   }
 }
 
+/**
+ * The same as an endpoint in a provider-definition
+ *
+ * @generated from message api.v1.EndpointDefinition
+ */
+export class EndpointDefinition extends Message<EndpointDefinition> {
+  /**
+   * @generated from field: string path = 1;
+   */
+  path = "";
+
+  /**
+   * @generated from field: string query = 2;
+   */
+  query = "";
+
+  /**
+   * @generated from field: string method = 3;
+   */
+  method = "";
+
+  /**
+   * One of json, xml, rss or html. Defaults to json
+   *
+   * @generated from field: string data_type = 4;
+   */
+  dataType = "";
+
+  /**
+   * @generated from field: string root_mapping = 5;
+   */
+  rootMapping = "";
+
+  /**
+   * Field within the rss-type, like PubDate, to the path within the source
+   *
+   * @generated from field: map<string, string> mapping = 6;
+   */
+  mapping: { [key: string]: string } = {};
+
+  constructor(data?: PartialMessage<EndpointDefinition>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.EndpointDefinition";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "path", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 2, name: "query", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 3, name: "method", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 4, name: "data_type", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 5, name: "root_mapping", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 6, name: "mapping", kind: "map", K: 9 /* ScalarType.STRING */, V: {kind: "scalar", T: 9 /* ScalarType.STRING */} },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): EndpointDefinition {
+    return new EndpointDefinition().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): EndpointDefinition {
+    return new EndpointDefinition().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): EndpointDefinition {
+    return new EndpointDefinition().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: EndpointDefinition | PlainMessage<EndpointDefinition> | undefined, b: EndpointDefinition | PlainMessage<EndpointDefinition> | undefined): boolean {
+    return proto3.util.equals(EndpointDefinition, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.PreviewMappingRequest
+ */
+export class PreviewMappingRequest extends Message<PreviewMappingRequest> {
+  /**
+   * @generated from field: api.v1.EndpointDefinition endpoint = 1;
+   */
+  endpoint?: EndpointDefinition;
+
+  /**
+   * @generated from field: api.v1.PreviewKind kind = 2;
+   */
+  kind = PreviewKind.UNSPECIFIED;
+
+  /**
+   * A sample response-body to decode. Either this or cache_key is required.
+   *
+   * @generated from field: string body = 3;
+   */
+  body = "";
+
+  /**
+   * Key of a cached response within the provider, like episodes-podID=123.
+   * Only responses retrieved by the provider's endpoints since the server started are available
+   *
+   * @generated from field: string cache_key = 4;
+   */
+  cacheKey = "";
+
+  /**
+   * Name of the provider, like untold. Required for cache_key, and used to resolve relative urls
+   *
+   * @generated from field: string provider = 5;
+   */
+  provider = "";
+
+  /**
+   * Used to resolve relative urls, if there is no provider
+   *
+   * @generated from field: string base_url = 6;
+   */
+  baseUrl = "";
+
+  constructor(data?: PartialMessage<PreviewMappingRequest>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.PreviewMappingRequest";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "endpoint", kind: "message", T: EndpointDefinition },
+    { no: 2, name: "kind", kind: "enum", T: proto3.getEnumType(PreviewKind) },
+    { no: 3, name: "body", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 4, name: "cache_key", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 5, name: "provider", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 6, name: "base_url", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): PreviewMappingRequest {
+    return new PreviewMappingRequest().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): PreviewMappingRequest {
+    return new PreviewMappingRequest().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): PreviewMappingRequest {
+    return new PreviewMappingRequest().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: PreviewMappingRequest | PlainMessage<PreviewMappingRequest> | undefined, b: PreviewMappingRequest | PlainMessage<PreviewMappingRequest> | undefined): boolean {
+    return proto3.util.equals(PreviewMappingRequest, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.MappingFieldError
+ */
+export class MappingFieldError extends Message<MappingFieldError> {
+  /**
+   * Index of the item within the source
+   *
+   * @generated from field: int32 item = 1;
+   */
+  item = 0;
+
+  /**
+   * The mapping-key, like PubDate. Empty if the error is for the whole item
+   *
+   * @generated from field: string field = 2;
+   */
+  field = "";
+
+  /**
+   * @generated from field: string error = 3;
+   */
+  error = "";
+
+  constructor(data?: PartialMessage<MappingFieldError>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.MappingFieldError";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "item", kind: "scalar", T: 5 /* ScalarType.INT32 */ },
+    { no: 2, name: "field", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 3, name: "error", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): MappingFieldError {
+    return new MappingFieldError().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): MappingFieldError {
+    return new MappingFieldError().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): MappingFieldError {
+    return new MappingFieldError().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: MappingFieldError | PlainMessage<MappingFieldError> | undefined, b: MappingFieldError | PlainMessage<MappingFieldError> | undefined): boolean {
+    return proto3.util.equals(MappingFieldError, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.PreviewMappingResponse
+ */
+export class PreviewMappingResponse extends Message<PreviewMappingResponse> {
+  /**
+   * The decoded channels or items, as json
+   *
+   * @generated from field: string decoded_json = 1;
+   */
+  decodedJson = "";
+
+  /**
+   * @generated from field: repeated api.v1.MappingFieldError errors = 2;
+   */
+  errors: MappingFieldError[] = [];
+
+  /**
+   * Paths within the source that are not used by any mapping
+   *
+   * @generated from field: repeated string unmapped_fields = 3;
+   */
+  unmappedFields: string[] = [];
+
+  /**
+   * Rss-xml for the decoded result, limited to a few items
+   *
+   * @generated from field: string rss = 4;
+   */
+  rss = "";
+
+  constructor(data?: PartialMessage<PreviewMappingResponse>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.PreviewMappingResponse";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "decoded_json", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 2, name: "errors", kind: "message", T: MappingFieldError, repeated: true },
+    { no: 3, name: "unmapped_fields", kind: "scalar", T: 9 /* ScalarType.STRING */, repeated: true },
+    { no: 4, name: "rss", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): PreviewMappingResponse {
+    return new PreviewMappingResponse().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): PreviewMappingResponse {
+    return new PreviewMappingResponse().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): PreviewMappingResponse {
+    return new PreviewMappingResponse().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: PreviewMappingResponse | PlainMessage<PreviewMappingResponse> | undefined, b: PreviewMappingResponse | PlainMessage<PreviewMappingResponse> | undefined): boolean {
+    return proto3.util.equals(PreviewMappingResponse, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.SuggestMappingRequest
+ */
+export class SuggestMappingRequest extends Message<SuggestMappingRequest> {
+  /**
+   * @generated from field: api.v1.PreviewKind kind = 1;
+   */
+  kind = PreviewKind.UNSPECIFIED;
+
+  /**
+   * A sample json response-body. Either this or cache_key is required.
+   *
+   * @generated from field: string body = 2;
+   */
+  body = "";
+
+  /**
+   * Key of a cached response within the provider, like episodes-podID=123.
+   * Only responses retrieved by the provider's endpoints since the server started are available
+   *
+   * @generated from field: string cache_key = 3;
+   */
+  cacheKey = "";
+
+  /**
+   * Name of the provider, like untold. Required for cache_key
+   *
+   * @generated from field: string provider = 4;
+   */
+  provider = "";
+
+  constructor(data?: PartialMessage<SuggestMappingRequest>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.SuggestMappingRequest";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "kind", kind: "enum", T: proto3.getEnumType(PreviewKind) },
+    { no: 2, name: "body", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 3, name: "cache_key", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 4, name: "provider", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): SuggestMappingRequest {
+    return new SuggestMappingRequest().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): SuggestMappingRequest {
+    return new SuggestMappingRequest().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): SuggestMappingRequest {
+    return new SuggestMappingRequest().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: SuggestMappingRequest | PlainMessage<SuggestMappingRequest> | undefined, b: SuggestMappingRequest | PlainMessage<SuggestMappingRequest> | undefined): boolean {
+    return proto3.util.equals(SuggestMappingRequest, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.MappingSuggestion
+ */
+export class MappingSuggestion extends Message<MappingSuggestion> {
+  /**
+   * The mapping-key, like PubDate
+   *
+   * @generated from field: string field = 1;
+   */
+  field = "";
+
+  /**
+   * The mapping-value, like published |> date
+   *
+   * @generated from field: string path = 2;
+   */
+  path = "";
+
+  /**
+   * 0 to 1
+   *
+   * @generated from field: double confidence = 3;
+   */
+  confidence = 0;
+
+  /**
+   * @generated from field: string reason = 4;
+   */
+  reason = "";
+
+  constructor(data?: PartialMessage<MappingSuggestion>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.MappingSuggestion";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "field", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 2, name: "path", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 3, name: "confidence", kind: "scalar", T: 1 /* ScalarType.DOUBLE */ },
+    { no: 4, name: "reason", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): MappingSuggestion {
+    return new MappingSuggestion().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): MappingSuggestion {
+    return new MappingSuggestion().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): MappingSuggestion {
+    return new MappingSuggestion().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: MappingSuggestion | PlainMessage<MappingSuggestion> | undefined, b: MappingSuggestion | PlainMessage<MappingSuggestion> | undefined): boolean {
+    return proto3.util.equals(MappingSuggestion, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.SuggestMappingResponse
+ */
+export class SuggestMappingResponse extends Message<SuggestMappingResponse> {
+  /**
+   * @generated from field: string root_mapping = 1;
+   */
+  rootMapping = "";
+
+  /**
+   * 0 to 1
+   *
+   * @generated from field: double root_confidence = 2;
+   */
+  rootConfidence = 0;
+
+  /**
+   * @generated from field: repeated api.v1.MappingSuggestion suggestions = 3;
+   */
+  suggestions: MappingSuggestion[] = [];
+
+  constructor(data?: PartialMessage<SuggestMappingResponse>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.SuggestMappingResponse";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "root_mapping", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 2, name: "root_confidence", kind: "scalar", T: 1 /* ScalarType.DOUBLE */ },
+    { no: 3, name: "suggestions", kind: "message", T: MappingSuggestion, repeated: true },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): SuggestMappingResponse {
+    return new SuggestMappingResponse().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): SuggestMappingResponse {
+    return new SuggestMappingResponse().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): SuggestMappingResponse {
+    return new SuggestMappingResponse().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: SuggestMappingResponse | PlainMessage<SuggestMappingResponse> | undefined, b: SuggestMappingResponse | PlainMessage<SuggestMappingResponse> | undefined): boolean {
+    return proto3.util.equals(SuggestMappingResponse, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.CheckFeedRequest
+ */
+export class CheckFeedRequest extends Message<CheckFeedRequest> {
+  /**
+   * Id of a mirrored channel, which is checked as it is served. Either this, url or body is required.
+   *
+   * @generated from field: string id = 1;
+   */
+  id = "";
+
+  /**
+   * Url of a feed to fetch and check
+   *
+   * @generated from field: string url = 2;
+   */
+  url = "";
+
+  /**
+   * The xml of a feed to check
+   *
+   * @generated from field: string body = 3;
+   */
+  body = "";
+
+  /**
+   * One of rss, apple, spotify or podcast. Defaults to all of them
+   *
+   * @generated from field: repeated string specs = 4;
+   */
+  specs: string[] = [];
+
+  /**
+   * Download the artwork to check its dimensions
+   *
+   * @generated from field: bool probe_images = 5;
+   */
+  probeImages = false;
+
+  constructor(data?: PartialMessage<CheckFeedRequest>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.CheckFeedRequest";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "id", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 2, name: "url", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 3, name: "body", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 4, name: "specs", kind: "scalar", T: 9 /* ScalarType.STRING */, repeated: true },
+    { no: 5, name: "probe_images", kind: "scalar", T: 8 /* ScalarType.BOOL */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): CheckFeedRequest {
+    return new CheckFeedRequest().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): CheckFeedRequest {
+    return new CheckFeedRequest().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): CheckFeedRequest {
+    return new CheckFeedRequest().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: CheckFeedRequest | PlainMessage<CheckFeedRequest> | undefined, b: CheckFeedRequest | PlainMessage<CheckFeedRequest> | undefined): boolean {
+    return proto3.util.equals(CheckFeedRequest, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.ComplianceFinding
+ */
+export class ComplianceFinding extends Message<ComplianceFinding> {
+  /**
+   * @generated from field: api.v1.Severity severity = 1;
+   */
+  severity = Severity.UNSPECIFIED;
+
+  /**
+   * The specification, like apple
+   *
+   * @generated from field: string spec = 2;
+   */
+  spec = "";
+
+  /**
+   * The path to the field, like Item[2].Enclosure.Type. Empty for the whole channel
+   *
+   * @generated from field: string path = 3;
+   */
+  path = "";
+
+  /**
+   * Like enclosure-type
+   *
+   * @generated from field: string rule = 4;
+   */
+  rule = "";
+
+  /**
+   * @generated from field: string message = 5;
+   */
+  message = "";
+
+  /**
+   * Link to the specification of the rule
+   *
+   * @generated from field: string reference = 6;
+   */
+  reference = "";
+
+  constructor(data?: PartialMessage<ComplianceFinding>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.ComplianceFinding";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "severity", kind: "enum", T: proto3.getEnumType(Severity) },
+    { no: 2, name: "spec", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 3, name: "path", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 4, name: "rule", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 5, name: "message", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+    { no: 6, name: "reference", kind: "scalar", T: 9 /* ScalarType.STRING */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): ComplianceFinding {
+    return new ComplianceFinding().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): ComplianceFinding {
+    return new ComplianceFinding().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): ComplianceFinding {
+    return new ComplianceFinding().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: ComplianceFinding | PlainMessage<ComplianceFinding> | undefined, b: ComplianceFinding | PlainMessage<ComplianceFinding> | undefined): boolean {
+    return proto3.util.equals(ComplianceFinding, a, b);
+  }
+}
+
+/**
+ * @generated from message api.v1.CheckFeedResponse
+ */
+export class CheckFeedResponse extends Message<CheckFeedResponse> {
+  /**
+   * @generated from field: repeated api.v1.ComplianceFinding findings = 1;
+   */
+  findings: ComplianceFinding[] = [];
+
+  /**
+   * @generated from field: int32 errors = 2;
+   */
+  errors = 0;
+
+  /**
+   * @generated from field: int32 warnings = 3;
+   */
+  warnings = 0;
+
+  constructor(data?: PartialMessage<CheckFeedResponse>) {
+    super();
+    proto3.util.initPartial(data, this);
+  }
+
+  static readonly runtime: typeof proto3 = proto3;
+  static readonly typeName = "api.v1.CheckFeedResponse";
+  static readonly fields: FieldList = proto3.util.newFieldList(() => [
+    { no: 1, name: "findings", kind: "message", T: ComplianceFinding, repeated: true },
+    { no: 2, name: "errors", kind: "scalar", T: 5 /* ScalarType.INT32 */ },
+    { no: 3, name: "warnings", kind: "scalar", T: 5 /* ScalarType.INT32 */ },
+  ]);
+
+  static fromBinary(bytes: Uint8Array, options?: Partial<BinaryReadOptions>): CheckFeedResponse {
+    return new CheckFeedResponse().fromBinary(bytes, options);
+  }
+
+  static fromJson(jsonValue: JsonValue, options?: Partial<JsonReadOptions>): CheckFeedResponse {
+    return new CheckFeedResponse().fromJson(jsonValue, options);
+  }
+
+  static fromJsonString(jsonString: string, options?: Partial<JsonReadOptions>): CheckFeedResponse {
+    return new CheckFeedResponse().fromJsonString(jsonString, options);
+  }
+
+  static equals(a: CheckFeedResponse | PlainMessage<CheckFeedResponse> | undefined, b: CheckFeedResponse | PlainMessage<CheckFeedResponse> | undefined): boolean {
+    return proto3.util.equals(CheckFeedResponse, a, b);
+  }
+}
+
@@ -39,14 +39,25 @@ const (
 	FeedServiceGetChannelProcedure = "/api.v1.FeedService/GetChannel"
 	// FeedServiceGetEpisodesProcedure is the fully-qualified name of the FeedService's GetEpisodes RPC.
 	FeedServiceGetEpisodesProcedure = "/api.v1.FeedService/GetEpisodes"
+	// FeedServicePreviewMappingProcedure is the fully-qualified name of the FeedService's
+	// PreviewMapping RPC.
+	FeedServicePreviewMappingProcedure = "/api.v1.FeedService/PreviewMapping"
+	// FeedServiceSuggestMappingProcedure is the fully-qualified name of the FeedService's
+	// SuggestMapping RPC.
+	FeedServiceSuggestMappingProcedure = "/api.v1.FeedService/SuggestMapping"
+	// FeedServiceCheckFeedProcedure is the fully-qualified name of the FeedService's CheckFeed RPC.
+	FeedServiceCheckFeedProcedure = "/api.v1.FeedService/CheckFeed"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
 var (
-	feedServiceServiceDescriptor           = v1.File_api_v1_pods_proto.Services().ByName("FeedService")
-	feedServiceGetChannelsMethodDescriptor = feedServiceServiceDescriptor.Methods().ByName("GetChannels")
-	feedServiceGetChannelMethodDescriptor  = feedServiceServiceDescriptor.Methods().ByName("GetChannel")
-	feedServiceGetEpisodesMethodDescriptor = feedServiceServiceDescriptor.Methods().ByName("GetEpisodes")
+	feedServiceServiceDescriptor              = v1.File_api_v1_pods_proto.Services().ByName("FeedService")
+	feedServiceGetChannelsMethodDescriptor    = feedServiceServiceDescriptor.Methods().ByName("GetChannels")
+	feedServiceGetChannelMethodDescriptor     = feedServiceServiceDescriptor.Methods().ByName("GetChannel")
+	feedServiceGetEpisodesMethodDescriptor    = feedServiceServiceDescriptor.Methods().ByName("GetEpisodes")
+	feedServicePreviewMappingMethodDescriptor = feedServiceServiceDescriptor.Methods().ByName("PreviewMapping")
+	feedServiceSuggestMappingMethodDescriptor = feedServiceServiceDescriptor.Methods().ByName("SuggestMapping")
+	feedServiceCheckFeedMethodDescriptor      = feedServiceServiceDescriptor.Methods().ByName("CheckFeed")
 )
 
 // FeedServiceClient is a client for the api.v1.FeedService service.
@@ -56,6 +67,12 @@ type FeedServiceClient interface {
 	GetChannel(context.Context, *connect.Request[v1.GetChannelRequest]) (*connect.Response[v1.GetChannelResponse], error)
 	// Returns a list of episodes, like podcasts or audio-book.
 	GetEpisodes(context.Context, *connect.Request[v1.GetEpisodesRequest]) (*connect.Response[v1.GetEpisodesResponse], error)
+	// Decodes a sample with a mapping, without requesting the upstream api.
+	PreviewMapping(context.Context, *connect.Request[v1.PreviewMappingRequest]) (*connect.Response[v1.PreviewMappingResponse], error)
+	// Suggests a mapping from a sample json-response.
+	SuggestMapping(context.Context, *connect.Request[v1.SuggestMappingRequest]) (*connect.Response[v1.SuggestMappingResponse], error)
+	// Checks a feed against the specifications of RSS, Apple Podcasts, Spotify and the podcast-namespace.
+	CheckFeed(context.Context, *connect.Request[v1.CheckFeedRequest]) (*connect.Response[v1.CheckFeedResponse], error)
 }
 
 // NewFeedServiceClient constructs a client for the api.v1.FeedService service. By default, it uses
@@ -86,14 +103,35 @@ func NewFeedServiceClient(httpClient connect.HTTPClient, baseURL string, opts ..
 			connect.WithSchema(feedServiceGetEpisodesMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		previewMapping: connect.NewClient[v1.PreviewMappingRequest, v1.PreviewMappingResponse](
+			httpClient,
+			baseURL+FeedServicePreviewMappingProcedure,
+			connect.WithSchema(feedServicePreviewMappingMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		suggestMapping: connect.NewClient[v1.SuggestMappingRequest, v1.SuggestMappingResponse](
+			httpClient,
+			baseURL+FeedServiceSuggestMappingProcedure,
+			connect.WithSchema(feedServiceSuggestMappingMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		checkFeed: connect.NewClient[v1.CheckFeedRequest, v1.CheckFeedResponse](
+			httpClient,
+			baseURL+FeedServiceCheckFeedProcedure,
+			connect.WithSchema(feedServiceCheckFeedMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // feedServiceClient implements FeedServiceClient.
 type feedServiceClient struct {
-	getChannels *connect.Client[v1.GetChannelsRequest, v1.GetChannelsResponse]
-	getChannel  *connect.Client[v1.GetChannelRequest, v1.GetChannelResponse]
-	getEpisodes *connect.Client[v1.GetEpisodesRequest, v1.GetEpisodesResponse]
+	getChannels    *connect.Client[v1.GetChannelsRequest, v1.GetChannelsResponse]
+	getChannel     *connect.Client[v1.GetChannelRequest, v1.GetChannelResponse]
+	getEpisodes    *connect.Client[v1.GetEpisodesRequest, v1.GetEpisodesResponse]
+	previewMapping *connect.Client[v1.PreviewMappingRequest, v1.PreviewMappingResponse]
+	suggestMapping *connect.Client[v1.SuggestMappingRequest, v1.SuggestMappingResponse]
+	checkFeed      *connect.Client[v1.CheckFeedRequest, v1.CheckFeedResponse]
 }
 
 // GetChannels calls api.v1.FeedService.GetChannels.
@@ -111,6 +149,21 @@ func (c *feedServiceClient) GetEpisodes(ctx context.Context, req *connect.Reques
 	return c.getEpisodes.CallUnary(ctx, req)
 }
 
+// PreviewMapping calls api.v1.FeedService.PreviewMapping.
+func (c *feedServiceClient) PreviewMapping(ctx context.Context, req *connect.Request[v1.PreviewMappingRequest]) (*connect.Response[v1.PreviewMappingResponse], error) {
+	return c.previewMapping.CallUnary(ctx, req)
+}
+
+// SuggestMapping calls api.v1.FeedService.SuggestMapping.
+func (c *feedServiceClient) SuggestMapping(ctx context.Context, req *connect.Request[v1.SuggestMappingRequest]) (*connect.Response[v1.SuggestMappingResponse], error) {
+	return c.suggestMapping.CallUnary(ctx, req)
+}
+
+// CheckFeed calls api.v1.FeedService.CheckFeed.
+func (c *feedServiceClient) CheckFeed(ctx context.Context, req *connect.Request[v1.CheckFeedRequest]) (*connect.Response[v1.CheckFeedResponse], error) {
+	return c.checkFeed.CallUnary(ctx, req)
+}
+
 // FeedServiceHandler is an implementation of the api.v1.FeedService service.
 type FeedServiceHandler interface {
 	// Returns a list of channels, like podcasts or audio-book.
@@ -118,6 +171,12 @@ type FeedServiceHandler interface {
 	GetChannel(context.Context, *connect.Request[v1.GetChannelRequest]) (*connect.Response[v1.GetChannelResponse], error)
 	// Returns a list of episodes, like podcasts or audio-book.
 	GetEpisodes(context.Context, *connect.Request[v1.GetEpisodesRequest]) (*connect.Response[v1.GetEpisodesResponse], error)
+	// Decodes a sample with a mapping, without requesting the upstream api.
+	PreviewMapping(context.Context, *connect.Request[v1.PreviewMappingRequest]) (*connect.Response[v1.PreviewMappingResponse], error)
+	// Suggests a mapping from a sample json-response.
+	SuggestMapping(context.Context, *connect.Request[v1.SuggestMappingRequest]) (*connect.Response[v1.SuggestMappingResponse], error)
+	// Checks a feed against the specifications of RSS, Apple Podcasts, Spotify and the podcast-namespace.
+	CheckFeed(context.Context, *connect.Request[v1.CheckFeedRequest]) (*connect.Response[v1.CheckFeedResponse], error)
 }
 
 // NewFeedServiceHandler builds an HTTP handler from the service implementation. It returns the path
@@ -144,6 +203,24 @@ func NewFeedServiceHandler(svc FeedServiceHandler, opts ...connect.HandlerOption
 		connect.WithSchema(feedServiceGetEpisodesMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	feedServicePreviewMappingHandler := connect.NewUnaryHandler(
+		FeedServicePreviewMappingProcedure,
+		svc.PreviewMapping,
+		connect.WithSchema(feedServicePreviewMappingMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	feedServiceSuggestMappingHandler := connect.NewUnaryHandler(
+		FeedServiceSuggestMappingProcedure,
+		svc.SuggestMapping,
+		connect.WithSchema(feedServiceSuggestMappingMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	feedServiceCheckFeedHandler := connect.NewUnaryHandler(
+		FeedServiceCheckFeedProcedure,
+		svc.CheckFeed,
+		connect.WithSchema(feedServiceCheckFeedMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/api.v1.FeedService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case FeedServiceGetChannelsProcedure:
@@ -152,6 +229,12 @@ func NewFeedServiceHandler(svc FeedServiceHandler, opts ...connect.HandlerOption
 			feedServiceGetChannelHandler.ServeHTTP(w, r)
 		case FeedServiceGetEpisodesProcedure:
 			feedServiceGetEpisodesHandler.ServeHTTP(w, r)
+		case FeedServicePreviewMappingProcedure:
+			feedServicePreviewMappingHandler.ServeHTTP(w, r)
+		case FeedServiceSuggestMappingProcedure:
+			feedServiceSuggestMappingHandler.ServeHTTP(w, r)
+		case FeedServiceCheckFeedProcedure:
+			feedServiceCheckFeedHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -172,3 +255,15 @@ func (UnimplementedFeedServiceHandler) GetChannel(context.Context, *connect.Requ
 func (UnimplementedFeedServiceHandler) GetEpisodes(context.Context, *connect.Request[v1.GetEpisodesRequest]) (*connect.Response[v1.GetEpisodesResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("api.v1.FeedService.GetEpisodes is not implemented"))
 }
+
+func (UnimplementedFeedServiceHandler) PreviewMapping(context.Context, *connect.Request[v1.PreviewMappingRequest]) (*connect.Response[v1.PreviewMappingResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("api.v1.FeedService.PreviewMapping is not implemented"))
+}
+
+func (UnimplementedFeedServiceHandler) SuggestMapping(context.Context, *connect.Request[v1.SuggestMappingRequest]) (*connect.Response[v1.SuggestMappingResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("api.v1.FeedService.SuggestMapping is not implemented"))
+}
+
+func (UnimplementedFeedServiceHandler) CheckFeed(context.Context, *connect.Request[v1.CheckFeedRequest]) (*connect.Response[v1.CheckFeedResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("api.v1.FeedService.CheckFeed is not implemented"))
+}
@@ -0,0 +1,290 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+// How long a fetched feed is used before it is revalidated, for feeds that do not set it themselves
+const DefaultRefreshInterval = time.Hour
+
+var ErrUnknownFeed = errors.New("unknown feed")
+
+// FeedAPI mirrors ordinary, public podcast-feeds by their url.
+// Feeds are fetched through the cache, and revalidated with conditional requests when their refresh-interval has passed.
+type FeedAPI struct {
+	*genapi.GenAPI
+	// For feeds without their own RefreshInterval
+	RefreshInterval time.Duration
+
+	mu sync.RWMutex
+	// In the order they were subscribed
+	feeds []Feed
+}
+
+type (
+	Feed struct {
+		URL string `yaml:"url"`
+		// The id of the channel. Defaults to the podcast:guid of the url, see rss.PodcastGUID
+		ID string `yaml:"id"`
+		// How long the fetched feed is used before it is revalidated. Defaults to FeedAPI.RefreshInterval
+		RefreshInterval time.Duration `yaml:"refreshInterval"`
+	}
+	FeedAPIOptions struct {
+		genapi.GenAPIOptions
+		// Defaults to feeds
+		Name  string
+		Feeds []Feed
+		// Defaults to DefaultRefreshInterval
+		RefreshInterval time.Duration
+	}
+	// FeedsFile is the file with the subscribed feeds, in yaml or json
+	FeedsFile struct {
+		RefreshInterval time.Duration `yaml:"refreshInterval"`
+		Feeds           []Feed        `yaml:"feeds"`
+	}
+)
+
+func NewFeedAPI(options FeedAPIOptions) (*FeedAPI, error) {
+	if options.Name == "" {
+		options.Name = "feeds"
+	}
+	if options.RefreshInterval < 0 {
+		return nil, fmt.Errorf("the refresh-interval cannot be negative, was %s", options.RefreshInterval)
+	}
+	if options.RefreshInterval == 0 {
+		options.RefreshInterval = DefaultRefreshInterval
+	}
+	// Every feed has its own absolute url
+	endpoint, err := genapi.NewEndoint(&url.URL{}, map[string]string{
+		"accept":     "application/rss+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.5",
+		"user-agent": "audio-mirror",
+	})
+	if err != nil {
+		return nil, err
+	}
+	api, err := genapi.NewGeneralAPI(options.Name, endpoint, options.GenAPIOptions)
+	if err != nil {
+		return nil, err
+	}
+	retry := genapi.DefaultRetryPolicy
+	api.Retry = &retry
+	api.FetchConcurrency = 4
+	f := &FeedAPI{GenAPI: api, RefreshInterval: options.RefreshInterval}
+	var errs error
+	for _, feed := range options.Feeds {
+		if _, err := f.Subscribe(feed); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return f, nil
+}
+
+// LoadFeedsFile reads the subscribed feeds from a yaml- or json-file
+func LoadFeedsFile(filePath string) (FeedsFile, error) {
+	var file FeedsFile
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return file, fmt.Errorf("failed to read feeds-file: %w", err)
+	}
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	if err := d.Decode(&file); err != nil {
+		return file, fmt.Errorf("failed to parse feeds-file %s: %w", filePath, err)
+	}
+	return file, nil
+}
+
+// Subscribe adds the feed, and returns it with its defaults set. The feed is not fetched until it is used.
+func (f *FeedAPI) Subscribe(feed Feed) (Feed, error) {
+	u, err := url.Parse(feed.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return feed, fmt.Errorf("the feed-url must be an absolute http(s)-url, was %q", feed.URL)
+	}
+	if feed.RefreshInterval < 0 {
+		return feed, fmt.Errorf("the refresh-interval of the feed %s cannot be negative, was %s", feed.URL, feed.RefreshInterval)
+	}
+	if feed.ID == "" {
+		feed.ID = rss.PodcastGUID(feed.URL)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.feeds {
+		if existing.ID == feed.ID {
+			return feed, fmt.Errorf("the feed %s is already subscribed, as %s", feed.ID, existing.URL)
+		}
+	}
+	f.feeds = append(f.feeds, feed)
+	return feed, nil
+}
+
+// Unsubscribe removes the feed. Its cached responses are kept.
+func (f *FeedAPI) Unsubscribe(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := slices.IndexFunc(f.feeds, func(feed Feed) bool { return feed.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownFeed, id)
+	}
+	f.feeds = slices.Delete(f.feeds, i, i+1)
+	return nil
+}
+
+// Feeds returns the subscribed feeds
+func (f *FeedAPI) Feeds() []Feed {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return slices.Clone(f.feeds)
+}
+
+func (f *FeedAPI) feed(id string) (Feed, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, feed := range f.feeds {
+		if feed.ID == id {
+			return feed, nil
+		}
+	}
+	return Feed{}, fmt.Errorf("%w: %s", ErrUnknownFeed, id)
+}
+
+// FindAllChannels fetches every subscribed feed, concurrently. Feeds that fail are left out, and their errors joined.
+// The channels have no items, since they are listed by ListEpisodes.
+func (f *FeedAPI) FindAllChannels(ctx context.Context) ([]genapi.GenAPIChannelList, error) {
+	feeds := f.Feeds()
+	channels := make([]*genapi.GenApiChannel, len(feeds))
+	errs := make([]error, len(feeds))
+	concurrency := f.FetchConcurrency
+	if concurrency <= 0 {
+		concurrency = genapi.DefaultFetchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, feed := range feeds {
+		wg.Add(1)
+		go func(i int, feed Feed) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			channel, _, err := f.fetch(ctx, feed, f.refreshInterval(feed))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			channel.Item = nil
+			channels[i] = channel
+		}(i, feed)
+	}
+	wg.Wait()
+	var list genapi.GenAPIChannelList
+	for _, c := range channels {
+		if c != nil {
+			list.Channels = append(list.Channels, *c)
+		}
+	}
+	return []genapi.GenAPIChannelList{list}, errors.Join(errs...)
+}
+
+// ListEpisodes returns the items of the feed, which is revalidated if its refresh-interval has passed
+func (f *FeedAPI) ListEpisodes(ctx context.Context, id string) (*genapi.GenAPIEpisodeList, *http.Response, error) {
+	feed, err := f.feed(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	channel, res, err := f.fetch(ctx, feed, f.refreshInterval(feed))
+	if err != nil {
+		return nil, res, err
+	}
+	return &genapi.GenAPIEpisodeList{Items: channel.Item}, res, nil
+}
+
+// Refresh revalidates the feed now, regardless of its refresh-interval, and returns the channel with its items
+func (f *FeedAPI) Refresh(ctx context.Context, id string) (genapi.GenApiChannel, error) {
+	feed, err := f.feed(id)
+	if err != nil {
+		return genapi.GenApiChannel{}, err
+	}
+	channel, _, err := f.fetch(ctx, feed, 0)
+	if err != nil {
+		return genapi.GenApiChannel{}, err
+	}
+	return *channel, nil
+}
+
+// Returns some data about the file. Mostly, just the content-type is of direct value
+func (f *FeedAPI) GetMediaInfo(ctx context.Context, mediaURL string) (*http.Response, error) {
+	r, err := f.NewRequest(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Add("range", "bytes=0-1")
+	return f.Do(ctx, r)
+}
+
+func (f *FeedAPI) refreshInterval(feed Feed) time.Duration {
+	if feed.RefreshInterval > 0 {
+		return feed.RefreshInterval
+	}
+	return f.RefreshInterval
+}
+
+// fetch retrieves and parses the feed, using the cached feed if it is younger than maxAge
+func (f *FeedAPI) fetch(ctx context.Context, feed Feed, maxAge time.Duration) (*genapi.GenApiChannel, *http.Response, error) {
+	res, body, err := f.GetRevalidated(ctx, feed.URL, "feed-"+feed.ID+".xml", maxAge)
+	if err != nil {
+		return nil, res, fmt.Errorf("failed to fetch the feed %s: %w", feed.URL, err)
+	}
+	parsed, err := rss.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, res, fmt.Errorf("failed to parse the feed %s: %w", feed.URL, err)
+	}
+	if parsed.NewFeedURL != "" && parsed.NewFeedURL != feed.URL {
+		f.Logger.Warn("The feed has moved, update the subscription", slog.String("id", feed.ID), slog.String("url", feed.URL), slog.String("newFeedURL", parsed.NewFeedURL))
+	}
+	return newChannel(f.Name, feed, *parsed), res, nil
+}
+
+// newChannel fills in the fields that other providers map, so that the feed is served like any other channel
+func newChannel(source string, feed Feed, c rss.Channel) *genapi.GenApiChannel {
+	if c.Image.URL == "" && c.ItunesImage != nil {
+		c.Image.URL = c.ItunesImage.Href
+	}
+	channel := &genapi.GenApiChannel{
+		Channel: c,
+		Meta: genapi.GenApiChannelMeta{
+			Kind:      genapi.ChannelTypePodCast,
+			Source:    source,
+			SourceURL: feed.URL,
+			ID:        feed.ID,
+		},
+	}
+	for i := range channel.Item {
+		item := &channel.Item[i]
+		if item.Image.URL == "" && item.ItunesImage != nil {
+			item.Image.URL = item.ItunesImage.Href
+		}
+		if t, err := rss.ParseDate(item.PubDate); err == nil {
+			if channel.Meta.LastAired == nil || t.After(*channel.Meta.LastAired) {
+				channel.Meta.LastAired = &t
+			}
+		}
+	}
+	return channel
+}
@@ -0,0 +1,159 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+	"github.com/runar-rkmedia/audio-mirror/genapi/conformance"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+const testFeedURL = "https://example.com/podcast/feed.xml"
+
+type (
+	testCache      map[string]testCacheEntry
+	testHTTPFunc   func(r *http.Request) (*http.Response, error)
+	testCacheEntry struct {
+		value   []byte
+		written time.Time
+	}
+)
+
+func (c testCache) Retrieve(keyPaths []string, changedAfter time.Time) ([]byte, bool, error) {
+	e, ok := c[strings.Join(keyPaths, "/")]
+	if !ok || e.written.Before(changedAfter) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (c testCache) Write(keyPaths []string, value []byte) (string, error) {
+	key := strings.Join(keyPaths, "/")
+	c[key] = testCacheEntry{value, time.Now()}
+	return key, nil
+}
+
+func (f testHTTPFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Options{
+		Fixtures: "testdata/fixtures",
+		New: func(t *testing.T, client genapi.HttpClient) conformance.Provider {
+			f, err := NewFeedAPI(FeedAPIOptions{
+				GenAPIOptions: genapi.GenAPIOptions{Logger: slog.Default(), Client: client},
+				Feeds:         []Feed{{URL: testFeedURL}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Retry = nil
+			return f
+		},
+		// The server sets the itunes:image from the Image
+		Feed: func(channel genapi.GenApiChannel, episodes []rss.Item) rss.Channel {
+			c := channel.Channel
+			c.Item = episodes
+			return c.WithItunesDefaults()
+		},
+	})
+}
+
+func TestFeedAPI_ConditionalRequests(t *testing.T) {
+	const body = `<rss><channel><title>A</title><item><guid>1</guid><pubDate>Thu, 01 Aug 2024 08:00:00 GMT</pubDate></item></channel></rss>`
+	var requests []http.Header
+	cache := testCache{}
+	f, err := NewFeedAPI(FeedAPIOptions{
+		GenAPIOptions: genapi.GenAPIOptions{
+			Logger: slog.Default(),
+			Cache:  cache,
+			Client: testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+				requests = append(requests, r.Header)
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(""))}, nil
+				}
+				return &http.Response{StatusCode: 200, Header: http.Header{"Etag": {`"v1"`}}, Body: io.NopCloser(strings.NewReader(body))}, nil
+			}),
+		},
+		Feeds: []Feed{{URL: testFeedURL, ID: "a"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lists, err := f.FindAllChannels(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := lists[0].Channels[0]
+	if c.Meta.ID != "a" || c.Meta.Source != "feeds" || c.Title != "A" || len(c.Item) != 0 {
+		t.Errorf("unexpected channel %#v", c)
+	}
+	if want := time.Date(2024, 8, 1, 8, 0, 0, 0, time.UTC); c.Meta.LastAired == nil || !c.Meta.LastAired.Equal(want) {
+		t.Errorf("expected LastAired to be the latest episode, got %v", c.Meta.LastAired)
+	}
+	// Within the refresh-interval, the cached feed is used
+	episodes, _, err := f.ListEpisodes(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(episodes.Items) != 1 || len(requests) != 1 {
+		t.Errorf("expected the episodes from the cached feed, got %d items after %d requests", len(episodes.Items), len(requests))
+	}
+	// Refresh is routed by the registry, like for /feed/{id}/refresh
+	registry := genapi.NewRegistry()
+	if err := registry.Register(f.Name, f); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := registry.Refresh(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[1].Get("If-None-Match") != `"v1"` {
+		t.Fatalf("expected Refresh to revalidate the feed, got %d requests", len(requests))
+	}
+	if len(refreshed.Item) != 1 {
+		t.Errorf("expected the unmodified feed to be kept, got %d items", len(refreshed.Item))
+	}
+	if _, _, err := f.ListEpisodes(ctx, "b"); !errors.Is(err, ErrUnknownFeed) {
+		t.Errorf("expected ErrUnknownFeed, got %v", err)
+	}
+}
+
+func TestFeedAPI_Subscribe(t *testing.T) {
+	f, err := NewFeedAPI(FeedAPIOptions{GenAPIOptions: genapi.GenAPIOptions{Logger: slog.Default()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := f.Subscribe(Feed{URL: testFeedURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.ID != rss.PodcastGUID(testFeedURL) {
+		t.Errorf("expected the id to default to the podcast:guid, got %s", feed.ID)
+	}
+	for _, invalid := range []Feed{
+		{URL: testFeedURL},
+		{URL: "example.com/feed.xml"},
+		{URL: "ftp://example.com/feed.xml"},
+		{URL: "https://example.com/other.xml", RefreshInterval: -time.Minute},
+	} {
+		if _, err := f.Subscribe(invalid); err == nil {
+			t.Errorf("expected Subscribe(%#v) to fail", invalid)
+		}
+	}
+	if err := f.Unsubscribe(feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Feeds()) != 0 {
+		t.Errorf("expected no feeds after unsubscribing, got %v", f.Feeds())
+	}
+}
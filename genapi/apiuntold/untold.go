@@ -7,12 +7,10 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/runar-rkmedia/audio-mirror/genapi"
-	"github.com/runar-rkmedia/audio-mirror/rss"
 )
 
 type UntoldAPI struct {
@@ -20,8 +18,12 @@ type UntoldAPI struct {
 	EndpointOriginals, EndpointFollowing, EndpointRecommended, EndpointHero, EndpointCategoriesDiscover, EndpointPopularEpisodes, EndpointFollowedEpisodes *genapi.GenAPIEndpoint
 }
 
+// Name of the secret with the token, used when UntoldAPIOptions.Token is not set
+const TokenSecret = "untold.token"
+
 type UntoldAPIOptions struct {
 	genapi.GenAPIOptions
+	// Defaults to the secret untold.token, which is resolved on every request so that it can be rotated
 	Token string
 	Name  string
 }
@@ -29,7 +31,6 @@ type UntoldAPIOptions struct {
 func NewUntoldAPI(options UntoldAPIOptions) (*UntoldAPI, error) {
 	untoldHeaders := map[string]string{
 		"accept":          "*/*",
-		"authorization":   options.Token,
 		"x-app-os":        "ios",
 		"user-agent":      "Untold/2 CFNetwork/1496.0.7 Darwin/23.5.0",
 		"accept-language": "nb-NO,nb;q=0.9",
@@ -45,6 +46,9 @@ func NewUntoldAPI(options UntoldAPIOptions) (*UntoldAPI, error) {
 	if options.Name == "" {
 		options.Name = "untold"
 	}
+	if options.Token == "" {
+		options.Token = `{{secret "` + TokenSecret + `"}}`
+	}
 	api, err := genapi.NewGeneralAPI(
 		options.Name,
 		untoldEndpoint,
@@ -54,6 +58,13 @@ func NewUntoldAPI(options UntoldAPIOptions) (*UntoldAPI, error) {
 		return nil, err
 	}
 
+	api.Auth = genapi.StaticAuth{Headers: map[string]string{"authorization": options.Token}}
+	// Fetching episodes for every channel quickly gets us throttled
+	retry := genapi.DefaultRetryPolicy
+	api.Retry = &retry
+	api.RateLimit = &genapi.RateLimit{Rate: 4, Burst: 8}
+	api.FetchConcurrency = 4
+
 	untold := &UntoldAPI{
 		GenAPI: api,
 	}
@@ -78,7 +89,22 @@ func NewUntoldAPI(options UntoldAPIOptions) (*UntoldAPI, error) {
 	}
 	// Their search is a bit strange. Not all podcasts seems searchable, for instance Truecrimepodden, which does show up in categories/discover
 	untold.EndpointSearchTitles = &genapi.GenAPIEndpoint{Path: "/api/v1/podcasts/search", Query: "term={{.query}}", Mapping: ChannelMapping}
-	untold.EndpointListEpisodes = &genapi.GenAPIEndpoint{Path: "/api/v1/podcasts/{{.podID}}/episodes"}
+	EpisodeMapping := map[string]string{
+		"Title":             "title",
+		"Description":       "description",
+		"GUID":              "id",
+		"DurationInSeconds": "duration |> duration",
+		"PubDate":           "published |> date",
+		"Link":              "soundUrl",
+		"Image.URL":         "cover.lg",
+		"Enclosure.URL":     "soundUrl",
+		"Enclosure.Type":    `@literal:"audio/mpeg"`,
+		// The season is a string at untold, while itunes:season must be a number
+		"Season": `season |> extract '(\d+)'`,
+		// TODO: get this value
+		"Enclosure.LengthInBytes": "duration",
+	}
+	untold.EndpointListEpisodes = &genapi.GenAPIEndpoint{Path: "/api/v1/podcasts/{{.podID}}/episodes", Mapping: EpisodeMapping}
 	untold.EndpointOriginals = &genapi.GenAPIEndpoint{Path: "/api/v1/podcasts/original", Mapping: ChannelMapping}
 	untold.EndpointFollowing = &genapi.GenAPIEndpoint{Path: "/api/v1/podcasts/followed", Mapping: FollowingMapping}
 	untold.EndpointRecommended = &genapi.GenAPIEndpoint{Path: "/api/v1/podcasts/recommended", Mapping: ChannelMapping}
@@ -212,7 +238,7 @@ func (u *UntoldAPI) GetMediaInfo(ctx context.Context, soundURL string) (*http.Re
 		return nil, err
 	}
 	r.Header.Add("range", "bytes=0-1")
-	return u.Client.Do(r)
+	return u.Do(ctx, r)
 }
 
 // Returns a list of categories, even though they call their endpoint discover
@@ -223,43 +249,6 @@ func (u *UntoldAPI) GetCategories(ctx context.Context) ([]UntoldDiscoverElement,
 	return j, r, err
 }
 
-func (u *UntoldAPI) ListEpisodes(ctx context.Context, id string) (*genapi.GenAPIEpisodeList, *http.Response, error) {
-	epsiodes, body, res, err := u.ListUntoldEpisodes(ctx, id)
-	if err != nil {
-		return nil, res, err
-	}
-	list := genapi.GenAPIEpisodeList{
-		Items: make([]rss.Item, len(epsiodes)),
-		Raw:   body,
-	}
-	for i, epi := range epsiodes {
-
-		item := rss.Item{
-			Title:       epi.Title,
-			Description: epi.Description,
-			// Summary:     epi.,
-			// Subtitle:    "",
-			// Category:    rss.ItemCategory{},
-			// Enclosure:   rss.Enclosure{},
-			GUID:              epi.ID,
-			DurationInSeconds: strconv.FormatInt(epi.Duration, 10),
-			PubDate:           epi.Published.String(),
-			Link:              epi.SoundURL,
-			Enclosure: rss.Enclosure{
-				URL:  epi.SoundURL,
-				Type: "audio/mpeg",
-				// TODO: get this value
-				LengthInBytes: strconv.FormatInt(epi.Duration, 10),
-			},
-		}
-		if epi.Cover != nil {
-			item.Image.URL = epi.Cover.Lg
-		}
-		list.Items[i] = item
-	}
-	return &list, res, err
-}
-
 func (u *UntoldAPI) ListUntoldEpisodes(ctx context.Context, podCastID string) ([]UntoldEpisode, []byte, *http.Response, error) {
 	// TODO: fix me
 	var jx any
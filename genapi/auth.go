@@ -0,0 +1,379 @@
+package genapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+type (
+	// Authenticator adds credentials to requests.
+	Authenticator interface {
+		// Authenticate adds credentials to the request, logging in if required.
+		Authenticate(ctx context.Context, g *GenAPI, r *http.Request) error
+		// Invalidate is called when the api responds with 401 Unauthorized,
+		// so that the next call to Authenticate will re-authenticate.
+		Invalidate(ctx context.Context, g *GenAPI) error
+	}
+	// TokenStore persists tokens, so that they survive restarts. Tokens are credentials,
+	// so the store should encrypt them at rest, like secrets.Store.
+	TokenStore interface {
+		GetToken(ctx context.Context, key string) (*Token, error)
+		SetToken(ctx context.Context, key string, token *Token) error
+	}
+	Token struct {
+		AccessToken  string    `json:"accessToken"`
+		RefreshToken string    `json:"refreshToken,omitempty"`
+		Expiry       time.Time `json:"expiry,omitempty"`
+	}
+
+	// StaticAuth adds the same headers to every request. The values may reference secrets, like {{secret "untold.token"}}
+	StaticAuth struct {
+		Headers map[string]string
+	}
+	// LoginAuth retrieves a token by posting a username and password to a login-endpoint.
+	LoginAuth struct {
+		TokenAuth
+		// Url to the login-endpoint, relative to the api's base-url.
+		URL string
+		// Username and password may reference secrets, like {{secret "example.password"}}
+		Username, Password string
+		// Fields within the posted json. Defaults to username and password
+		UsernameField, PasswordField string
+		// gjson-path to the token in the response. Defaults to access_token
+		TokenPath string
+		// gjson-path to the lifetime of the token in seconds. If not found, the token is used until the api responds with 401
+		ExpiresInPath string
+	}
+	// OAuth2Auth retrieves tokens from an OAuth2 token-endpoint, with either the refresh_token or the client_credentials grant.
+	OAuth2Auth struct {
+		TokenAuth
+		TokenURL string
+		// One of refresh_token or client_credentials. Defaults to refresh_token if a RefreshToken is set
+		GrantType    string
+		ClientID     string
+		ClientSecret string
+		// The initial refresh-token. The latest issued refresh-token is persisted in the TokenStore.
+		RefreshToken string
+		Scopes       []string
+	}
+	// TokenAuth holds the token, and the options common for token-based authenticators.
+	TokenAuth struct {
+		// Used to persist tokens. Defaults to GenAPIOptions.Tokens.
+		// Without either, tokens are only kept in memory, and retrieved again after a restart.
+		Store TokenStore
+		// Header to set. Defaults to Authorization
+		Header string
+		// The header-value, where {{.token}} is replaced with the token. Defaults to "Bearer {{.token}}"
+		HeaderTemplate string
+
+		mu    sync.Mutex
+		token *Token
+	}
+)
+
+const (
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	// Tokens are refreshed this long before they expire
+	tokenExpiryDelta = 30 * time.Second
+)
+
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry)
+}
+
+func (s StaticAuth) Authenticate(ctx context.Context, g *GenAPI, r *http.Request) error {
+	for k, v := range s.Headers {
+		v, err := g.TemplateString(v, map[string]any{})
+		if err != nil {
+			return fmt.Errorf("failed to template header %s: %w", k, err)
+		}
+		r.Header.Set(k, v)
+	}
+	return nil
+}
+
+// Static headers cannot be renewed, but secrets may have been rotated, which is picked up on the next request.
+func (s StaticAuth) Invalidate(ctx context.Context, g *GenAPI) error {
+	return nil
+}
+
+// store returns the TokenStore, or nil if tokens are not persisted
+func (a *TokenAuth) store(g *GenAPI) TokenStore {
+	if a.Store != nil {
+		return a.Store
+	}
+	return g.Tokens
+}
+
+// authenticate sets the header from a valid token, retrieving a new one with fetch if needed.
+// The previous token is passed to fetch, so that its refresh-token can be used.
+func (a *TokenAuth) authenticate(ctx context.Context, g *GenAPI, r *http.Request, fetch func(ctx context.Context, previous *Token) (*Token, error)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Tokens are persisted per api
+	key := g.Name
+	store := a.store(g)
+	if a.token == nil && store != nil {
+		t, err := store.GetToken(ctx, key)
+		if err != nil {
+			g.Logger.Warn("Failed to retrieve persisted token", slog.Any("error", err))
+		}
+		a.token = t
+	}
+	if !a.token.Valid() {
+		g.Logger.Debug("Retrieving new token", slog.String("api", g.Name))
+		t, err := fetch(ctx, a.token)
+		if err != nil {
+			return fmt.Errorf("%w: failed to retrieve token: %w", ErrUnauthorized, err)
+		}
+		if t.RefreshToken == "" && a.token != nil {
+			t.RefreshToken = a.token.RefreshToken
+		}
+		a.token = t
+		if store != nil {
+			if err := store.SetToken(ctx, key, t); err != nil {
+				g.Logger.Warn("Failed to persist token", slog.Any("error", err))
+			}
+		}
+	}
+	header := a.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	tmpl := a.HeaderTemplate
+	if tmpl == "" {
+		tmpl = "Bearer {{.token}}"
+	}
+	// Not using TemplateString here, since it logs its variables
+	r.Header.Set(header, strings.ReplaceAll(tmpl, "{{.token}}", a.token.AccessToken))
+	return nil
+}
+
+func (a *TokenAuth) Invalidate(ctx context.Context, g *GenAPI) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != nil {
+		// The refresh-token is kept, so that it can be used to get a new access-token.
+		a.token = &Token{RefreshToken: a.token.RefreshToken}
+	}
+	return nil
+}
+
+func (a *LoginAuth) Authenticate(ctx context.Context, g *GenAPI, r *http.Request) error {
+	return a.authenticate(ctx, g, r, func(ctx context.Context, _ *Token) (*Token, error) {
+		return a.login(ctx, g)
+	})
+}
+
+func (a *LoginAuth) login(ctx context.Context, g *GenAPI) (*Token, error) {
+	username, err := g.TemplateString(a.Username, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	password, err := g.TemplateString(a.Password, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	usernameField, passwordField := a.UsernameField, a.PasswordField
+	if usernameField == "" {
+		usernameField = "username"
+	}
+	if passwordField == "" {
+		passwordField = "password"
+	}
+	payload, err := json.Marshal(map[string]string{usernameField: username, passwordField: password})
+	if err != nil {
+		return nil, err
+	}
+	u, err := g.URL.Parse(a.URL)
+	if err != nil {
+		return nil, err
+	}
+	r, err := g.NewRequest(ctx, http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	body, err := g.doTokenRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	tokenPath := a.TokenPath
+	if tokenPath == "" {
+		tokenPath = "access_token"
+	}
+	t := &Token{AccessToken: gjson.GetBytes(body, tokenPath).String()}
+	if t.AccessToken == "" {
+		return nil, fmt.Errorf("no token found at %s in the login-response", tokenPath)
+	}
+	if a.ExpiresInPath != "" {
+		if seconds := gjson.GetBytes(body, a.ExpiresInPath).Int(); seconds > 0 {
+			t.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	return t, nil
+}
+
+func (a *OAuth2Auth) Authenticate(ctx context.Context, g *GenAPI, r *http.Request) error {
+	return a.authenticate(ctx, g, r, func(ctx context.Context, previous *Token) (*Token, error) {
+		return a.fetchToken(ctx, g, previous)
+	})
+}
+
+func (a *OAuth2Auth) fetchToken(ctx context.Context, g *GenAPI, previous *Token) (*Token, error) {
+	form := url.Values{}
+	grantType := a.GrantType
+	refreshToken := a.RefreshToken
+	if previous != nil && previous.RefreshToken != "" {
+		refreshToken = previous.RefreshToken
+	}
+	if grantType == "" {
+		grantType = GrantTypeClientCredentials
+		if refreshToken != "" {
+			grantType = GrantTypeRefreshToken
+		}
+	}
+	form.Set("grant_type", grantType)
+	switch grantType {
+	case GrantTypeRefreshToken:
+		refreshToken, err := g.TemplateString(refreshToken, map[string]any{})
+		if err != nil {
+			return nil, err
+		}
+		if refreshToken == "" {
+			return nil, fmt.Errorf("a refresh-token is required for the grant-type %s", grantType)
+		}
+		form.Set("refresh_token", refreshToken)
+	case GrantTypeClientCredentials:
+	default:
+		return nil, fmt.Errorf("unsupported grant-type %s", grantType)
+	}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	clientID, err := g.TemplateString(a.ClientID, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := g.TemplateString(a.ClientSecret, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+	if clientID != "" {
+		r.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+	body, err := g.doTokenRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token-response: %w", err)
+	}
+	if res.AccessToken == "" {
+		return nil, fmt.Errorf("no access_token in the token-response")
+	}
+	t := &Token{AccessToken: res.AccessToken, RefreshToken: res.RefreshToken}
+	if res.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// doTokenRequest performs a request for a token, without authentication and retries.
+func (g *GenAPI) doTokenRequest(r *http.Request) ([]byte, error) {
+	res, err := Chain(g.transport(), WithTimeout(g.Timeout)).Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do token-request: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token-response: %w", err)
+	}
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("unsuccessful status-code for token-request: %d", res.StatusCode)
+	}
+	return body, nil
+}
+
+// authenticate is the middleware for authentication. If the api responds with 401 Unauthorized,
+// the credentials are invalidated and the request is retried once.
+func (g *GenAPI) authenticate(next HttpClient) HttpClient {
+	if g.Auth == nil {
+		return next
+	}
+	return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+		return g.doAuthenticated(r, next)
+	})
+}
+
+func (g *GenAPI) doAuthenticated(r *http.Request, next HttpClient) (*http.Response, error) {
+	ctx := r.Context()
+	if err := g.Auth.Authenticate(ctx, g, r); err != nil {
+		return nil, err
+	}
+	res, err := next.Do(r)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	retry, err := cloneRequest(ctx, r)
+	if err != nil {
+		// The request cannot be retried, so the original response is returned
+		return res, nil
+	}
+	res.Body.Close()
+	g.Logger.Info("Got 401 Unauthorized, re-authenticating", slog.String("api", g.Name))
+	if err := g.Auth.Invalidate(ctx, g); err != nil {
+		return nil, err
+	}
+	if err := g.Auth.Authenticate(ctx, g, retry); err != nil {
+		return nil, err
+	}
+	return next.Do(retry)
+}
+
+// cloneRequest clones the request, including a fresh copy of the body.
+func cloneRequest(ctx context.Context, r *http.Request) (*http.Request, error) {
+	clone := r.Clone(ctx)
+	if r.Body == nil || r.Body == http.NoBody {
+		return clone, nil
+	}
+	if r.GetBody == nil {
+		return nil, fmt.Errorf("request-body cannot be replayed")
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone.Body = body
+	return clone, nil
+}
@@ -0,0 +1,158 @@
+package genapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testHTTPFunc lets a function act as a HttpClient
+type testHTTPFunc func(r *http.Request) (*http.Response, error)
+
+func (f testHTTPFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type testTokenStore map[string]*Token
+
+func (s testTokenStore) GetToken(ctx context.Context, key string) (*Token, error) {
+	return s[key], nil
+}
+
+func (s testTokenStore) SetToken(ctx context.Context, key string, token *Token) error {
+	s[key] = token
+	return nil
+}
+
+func testResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestGenAPI_Do_OAuth2(t *testing.T) {
+	var tokenRequests []url.Values
+	var apiRequests []string
+	client := testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/oauth/token" {
+			b, _ := io.ReadAll(r.Body)
+			form, _ := url.ParseQuery(string(b))
+			tokenRequests = append(tokenRequests, form)
+			if form.Get("refresh_token") == "refresh-1" {
+				return testResponse(200, `{"access_token": "access-1", "refresh_token": "refresh-2", "expires_in": 3600}`), nil
+			}
+			if form.Get("refresh_token") == "refresh-2" {
+				return testResponse(200, `{"access_token": "access-2", "expires_in": 3600}`), nil
+			}
+			return testResponse(400, `{"error": "invalid_grant"}`), nil
+		}
+		auth := r.Header.Get("Authorization")
+		apiRequests = append(apiRequests, auth)
+		// The first token is revoked on the server after the first request
+		if auth == "Bearer access-1" && len(apiRequests) > 1 {
+			return testResponse(401, ``), nil
+		}
+		return testResponse(200, `[]`), nil
+	})
+	cache := testMapCache{}
+	tokens := testTokenStore{}
+	g := &GenAPI{
+		Name:     "test",
+		Endpoint: Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+		GenAPIOptions: GenAPIOptions{
+			Logger: slog.Default(),
+			Client: client,
+			Cache:  cache,
+			Tokens: tokens,
+		},
+		Auth: &OAuth2Auth{
+			TokenURL:     "https://example.com/oauth/token",
+			ClientID:     "client",
+			RefreshToken: "refresh-1",
+		},
+	}
+	for i := 0; i < 2; i++ {
+		r, err := g.NewRequest(context.TODO(), "", "https://example.com/api/items", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := g.Do(context.TODO(), r)
+		if err != nil {
+			t.Fatalf("GenAPI.Do() error = %v", err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatalf("GenAPI.Do() expected status 200 after re-authenticating, got %d", res.StatusCode)
+		}
+	}
+	want := []string{"Bearer access-1", "Bearer access-1", "Bearer access-2"}
+	if strings.Join(apiRequests, ",") != strings.Join(want, ",") {
+		t.Errorf("expected api-requests with %v, got %v", want, apiRequests)
+	}
+	if len(tokenRequests) != 2 || tokenRequests[1].Get("refresh_token") != "refresh-2" {
+		t.Errorf("expected the rotated refresh-token to be used, got %v", tokenRequests)
+	}
+	if tokens["test"] == nil || tokens["test"].AccessToken != "access-2" {
+		t.Errorf("expected the token to be persisted, got %#v", tokens["test"])
+	}
+	if len(cache) != 0 {
+		t.Errorf("expected no tokens in the cache, got %v", cache)
+	}
+}
+
+func TestGenAPI_Do_Login(t *testing.T) {
+	logins := 0
+	client := testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/api/login" {
+			logins++
+			b, _ := io.ReadAll(r.Body)
+			if string(b) != `{"email":"me@example.com","password":"hunter2"}` {
+				return testResponse(403, ``), nil
+			}
+			return testResponse(200, `{"data": {"jwt": "abc"}}`), nil
+		}
+		if r.Header.Get("x-token") != "abc" {
+			return testResponse(401, ``), nil
+		}
+		return testResponse(200, `[]`), nil
+	})
+	t.Setenv("TEST_PASSWORD", "hunter2")
+	g := &GenAPI{
+		Name:     "test",
+		Endpoint: Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/api/"}},
+		GenAPIOptions: GenAPIOptions{
+			Logger:  slog.Default(),
+			Client:  client,
+			Secrets: EnvSecrets{},
+		},
+		Auth: &LoginAuth{
+			TokenAuth:     TokenAuth{Header: "x-token", HeaderTemplate: "{{.token}}"},
+			URL:           "login",
+			Username:      "me@example.com",
+			Password:      `{{secret "test.password"}}`,
+			UsernameField: "email",
+			TokenPath:     "data.jwt",
+		},
+	}
+	for i := 0; i < 2; i++ {
+		r, err := g.NewRequest(context.TODO(), "", "https://example.com/api/items", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := g.Do(context.TODO(), r)
+		if err != nil {
+			t.Fatalf("GenAPI.Do() error = %v", err)
+		}
+		if res.StatusCode != 200 {
+			t.Fatalf("GenAPI.Do() expected status 200, got %d", res.StatusCode)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("expected a single login, got %d", logins)
+	}
+}
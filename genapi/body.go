@@ -0,0 +1,72 @@
+package genapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	BodyTypeJSON = "json"
+	BodyTypeForm = "form"
+)
+
+// requestBody is the rendered body of an endpoint
+type requestBody struct {
+	data        []byte
+	contentType string
+}
+
+// renderBody templates the endpoints Body, or its GraphQL-request, with the same data as the path and query.
+// Returns nil if the endpoint has no body.
+//
+// Values in json-bodies should be encoded with toJson, like {"term": {{toJson .query}}}.
+// Form-bodies are written like queries, like term={{.query}}&limit=10, and are re-encoded.
+func (g *GenAPI) renderBody(endpoint GenAPIEndpoint, data map[string]any) (*requestBody, error) {
+	if endpoint.Body == "" && endpoint.GraphQL == nil {
+		return nil, nil
+	}
+	if data == nil {
+		data = map[string]any{}
+	}
+	if endpoint.GraphQL != nil {
+		return g.renderGraphQL(*endpoint.GraphQL, data)
+	}
+	data["endpoint"] = endpoint
+	s, err := g.TemplateString(endpoint.Body, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to template body: %w", err)
+	}
+	switch endpoint.BodyType {
+	case "", BodyTypeJSON:
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("the templated body is not valid json: %s", s)
+		}
+		return &requestBody{data: []byte(s), contentType: "application/json"}, nil
+	case BodyTypeForm:
+		values, err := url.ParseQuery(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("the templated body is not a valid form: %w", err)
+		}
+		return &requestBody{data: []byte(values.Encode()), contentType: "application/x-www-form-urlencoded"}, nil
+	}
+	return nil, fmt.Errorf("unsupported bodyType %q, expected one of %s, %s", endpoint.BodyType, BodyTypeJSON, BodyTypeForm)
+}
+
+// cacheKey makes the cache-key unique for the body, since requests to the same url with different bodies
+// typically have different responses.
+func (b *requestBody) cacheKey(cacheKey string) string {
+	if b == nil {
+		return cacheKey
+	}
+	return cacheKey + "-body=" + shortHash(b.data)
+}
+
+// shortHash is used to keep cache-keys short, while still being unique
+func shortHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,82 @@
+package genapi
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestGenAPI_renderBody(t *testing.T) {
+	tests := []struct {
+		name            string
+		endpoint        GenAPIEndpoint
+		wantBody        string
+		wantContentType string
+		wantErr         bool
+	}{
+		{
+			"No body",
+			GenAPIEndpoint{},
+			"",
+			"",
+			false,
+		},
+		{
+			"Json with encoded values",
+			GenAPIEndpoint{Body: `{"term": {{toJson .query}}, "limit": 10}`},
+			`{"term": "say \"hi\"", "limit": 10}`,
+			"application/json",
+			false,
+		},
+		{
+			"Form is re-encoded",
+			GenAPIEndpoint{Body: "term={{.query}}&limit=10", BodyType: BodyTypeForm},
+			"limit=10&term=say+%22hi%22",
+			"application/x-www-form-urlencoded",
+			false,
+		},
+		{
+			"Invalid json",
+			GenAPIEndpoint{Body: `{"term": {{.query}}}`},
+			"",
+			"",
+			true,
+		},
+		{
+			"Unknown body-type",
+			GenAPIEndpoint{Body: "x", BodyType: "xml"},
+			"",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GenAPI{GenAPIOptions: GenAPIOptions{Logger: slog.Default()}}
+			got, err := g.renderBody(tt.endpoint, map[string]any{"query": `say "hi"`})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenAPI.renderBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantBody == "" {
+				if got != nil {
+					t.Errorf("GenAPI.renderBody() expected no body, got %s", got.data)
+				}
+				return
+			}
+			if string(got.data) != tt.wantBody || got.contentType != tt.wantContentType {
+				t.Errorf("GenAPI.renderBody() = %s (%s), want %s (%s)", got.data, got.contentType, tt.wantBody, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func Test_requestBody_cacheKey(t *testing.T) {
+	var none *requestBody
+	if got := none.cacheKey("search-"); got != "search-" {
+		t.Errorf("expected the cache-key to be unchanged without a body, got %s", got)
+	}
+	a := (&requestBody{data: []byte(`{"q": "a"}`)}).cacheKey("search-")
+	b := (&requestBody{data: []byte(`{"q": "b"}`)}).cacheKey("search-")
+	if a == b || a == "search-" {
+		t.Errorf("expected different bodies to have different cache-keys, got %s and %s", a, b)
+	}
+}
@@ -0,0 +1,217 @@
+// Package conformance is a test-kit that every provider can be run against, with recorded fixtures.
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, conformance.Options{
+//			Fixtures: "testdata/fixtures",
+//			New: func(t *testing.T, client genapi.HttpClient) conformance.Provider {
+//				p, err := NewProvider(genapi.GenAPIOptions{Logger: slog.Default(), Client: client})
+//				if err != nil {
+//					t.Fatal(err)
+//				}
+//				return p
+//			},
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+	"github.com/runar-rkmedia/audio-mirror/genapi/fixture"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+// Limits the number of channels whose episodes are listed, if not set in Options
+const DefaultMaxChannels = 5
+
+// ErrTransport is returned by the client for every request when checking that errors are wrapped.
+var ErrTransport = errors.New("conformance: the transport failed")
+
+// Provider is what is tested, the same as is registered with the server
+type Provider = genapi.Provider
+
+type (
+	Options struct {
+		// Creates a new provider with the client, without a cache.
+		// The client either replays the fixtures, or fails every request with ErrTransport.
+		New func(t *testing.T, client genapi.HttpClient) Provider
+		// Directory with fixtures, as written by fixture.Recorder
+		Fixtures string
+		// Defaults to fixture.MatchLenient, so that fixtures can be written by hand
+		Match fixture.MatchMode
+		// Builds the feed for a channel, like the server does, before it is checked with rss.Channel.Validate.
+		// Defaults to the channel with the episodes as items.
+		Feed func(channel genapi.GenApiChannel, episodes []rss.Item) rss.Channel
+		// Defaults to DefaultMaxChannels
+		MaxChannels int
+	}
+	// The result of a single run against the fixtures
+	snapshot struct {
+		channels []genapi.GenApiChannel
+		episodes map[string][]rss.Item
+	}
+)
+
+type failingClient struct{}
+
+func (failingClient) Do(r *http.Request) (*http.Response, error) {
+	return nil, ErrTransport
+}
+
+// Run checks the provider against the fixtures:
+//   - Channels have IDs and titles, and the IDs are unique
+//   - Episodes have GUIDs and enclosures
+//   - Feeds pass rss.Channel.Validate
+//   - IDs and GUIDs are the same every time the provider is created
+//   - Errors from the client are returned, and wrapped so that errors.Is works
+func Run(t *testing.T, opts Options) {
+	t.Helper()
+	if opts.New == nil {
+		t.Fatal("conformance: Options.New is required")
+	}
+	if opts.Match == "" {
+		opts.Match = fixture.MatchLenient
+	}
+	if opts.MaxChannels <= 0 {
+		opts.MaxChannels = DefaultMaxChannels
+	}
+	if opts.Feed == nil {
+		opts.Feed = func(channel genapi.GenApiChannel, episodes []rss.Item) rss.Channel {
+			c := channel.Channel
+			c.Item = episodes
+			return c
+		}
+	}
+	first := run(t, opts)
+	t.Run("channels", func(t *testing.T) {
+		if len(first.channels) == 0 {
+			t.Fatal("expected at least one channel from the fixtures")
+		}
+		seen := map[string]bool{}
+		for i, c := range first.channels {
+			if c.Meta.ID == "" {
+				t.Errorf("channel %d (%q) has no Meta.ID", i, c.Title)
+			}
+			if c.Title == "" {
+				t.Errorf("channel %d (%s) has no Title", i, c.Meta.ID)
+			}
+			if seen[c.Meta.ID] {
+				t.Errorf("channel %d has a duplicate Meta.ID %s", i, c.Meta.ID)
+			}
+			seen[c.Meta.ID] = true
+		}
+	})
+	t.Run("episodes", func(t *testing.T) {
+		for _, id := range first.episodeIDs() {
+			episodes := first.episodes[id]
+			if len(episodes) == 0 {
+				t.Errorf("channel %s has no episodes", id)
+			}
+			for i, e := range episodes {
+				if e.GUID == "" {
+					t.Errorf("episode %d (%q) of channel %s has no GUID", i, e.Title, id)
+				}
+				if e.Enclosure.URL == "" || e.Enclosure.Type == "" {
+					t.Errorf("episode %d (%s) of channel %s has no enclosure-url and -type", i, e.GUID, id)
+				}
+			}
+		}
+	})
+	t.Run("feeds", func(t *testing.T) {
+		for _, c := range first.channels {
+			episodes, ok := first.episodes[c.Meta.ID]
+			if !ok {
+				continue
+			}
+			if err := opts.Feed(c, episodes).Validate(); err != nil {
+				t.Errorf("the feed for channel %s is invalid: %v", c.Meta.ID, err)
+			}
+		}
+	})
+	t.Run("stable ids", func(t *testing.T) {
+		second := run(t, opts)
+		if a, b := first.channelIDs(), second.channelIDs(); !slices.Equal(a, b) {
+			t.Errorf("the channel-ids changed between runs: %v != %v", a, b)
+		}
+		for _, id := range first.episodeIDs() {
+			if a, b := guids(first.episodes[id]), guids(second.episodes[id]); !slices.Equal(a, b) {
+				t.Errorf("the episode-guids of channel %s changed between runs: %v != %v", id, a, b)
+			}
+		}
+	})
+	t.Run("errors are wrapped", func(t *testing.T) {
+		p := opts.New(t, failingClient{})
+		ctx := context.Background()
+		if _, err := p.FindAllChannels(ctx); !errors.Is(err, ErrTransport) {
+			t.Errorf("FindAllChannels() error = %v, want it to wrap ErrTransport", err)
+		}
+		id := "conformance"
+		if len(first.channels) > 0 {
+			id = first.channels[0].Meta.ID
+		}
+		if _, _, err := p.ListEpisodes(ctx, id); !errors.Is(err, ErrTransport) {
+			t.Errorf("ListEpisodes() error = %v, want it to wrap ErrTransport", err)
+		}
+	})
+}
+
+// run lists the channels, and the episodes of the first channels, with a new provider
+func run(t *testing.T, opts Options) snapshot {
+	t.Helper()
+	p := opts.New(t, fixture.NewReplayer(opts.Fixtures, opts.Match))
+	ctx := context.Background()
+	lists, err := p.FindAllChannels(ctx)
+	if err != nil {
+		t.Fatalf("FindAllChannels() error = %v", err)
+	}
+	s := snapshot{
+		channels: genapi.FlattenAndDeduplicate(nil, lists).Channels,
+		episodes: map[string][]rss.Item{},
+	}
+	for i, c := range s.channels {
+		if i >= opts.MaxChannels {
+			break
+		}
+		list, _, err := p.ListEpisodes(ctx, c.Meta.ID)
+		if err != nil {
+			t.Fatalf("ListEpisodes(%s) error = %v", c.Meta.ID, err)
+		}
+		if list == nil {
+			t.Fatalf("ListEpisodes(%s) returned no list", c.Meta.ID)
+		}
+		s.episodes[c.Meta.ID] = list.Items
+	}
+	return s
+}
+
+func (s snapshot) channelIDs() []string {
+	ids := make([]string, len(s.channels))
+	for i, c := range s.channels {
+		ids[i] = c.Meta.ID
+	}
+	return ids
+}
+
+// episodeIDs returns the ids of the channels with listed episodes, in the order of the channels
+func (s snapshot) episodeIDs() []string {
+	var ids []string
+	for _, c := range s.channels {
+		if _, ok := s.episodes[c.Meta.ID]; ok {
+			ids = append(ids, c.Meta.ID)
+		}
+	}
+	return ids
+}
+
+func guids(items []rss.Item) []string {
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.GUID
+	}
+	return ids
+}
@@ -0,0 +1,552 @@
+package genapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidDefinition = errors.New("invalid definition")
+
+const (
+	AuthTypeStatic = "static"
+	AuthTypeLogin  = "login"
+	AuthTypeOAuth2 = "oauth2"
+
+	EndpointNameSearchTitles = "searchTitles"
+	EndpointNameListTitles   = "listTitles"
+	EndpointNameCategories   = "categories"
+	EndpointNameListEpisodes = "listEpisodes"
+)
+
+type (
+	// Definition is the declarative form of a GenAPI, as read from a yaml- or json-file.
+	//
+	//	name: untold
+	//	baseUrl: https://api.fole.app.iterate.no
+	//	cacheTime: 24h
+	//	headers:
+	//	  authorization: '{{secret "untold.token"}}'
+	//	endpoints:
+	//	  listEpisodes:
+	//	    path: /api/v1/podcasts/{{.podID}}/episodes
+	Definition struct {
+		Name      string                     `yaml:"name" schema:"required"`
+		BaseURL   string                     `yaml:"baseUrl" schema:"required"`
+		Headers   map[string]string          `yaml:"headers"`
+		CacheTime time.Duration              `yaml:"cacheTime"`
+		Endpoints map[string]*GenAPIEndpoint `yaml:"endpoints"`
+		Auth      *AuthDefinition            `yaml:"auth"`
+		Retry     *RetryPolicy               `yaml:"retry"`
+		RateLimit *RateLimit                 `yaml:"rateLimit"`
+		// The number of channels whose episodes are fetched concurrently. Defaults to DefaultFetchConcurrency
+		FetchConcurrency int `yaml:"fetchConcurrency"`
+		// Limits each request, including retries
+		Timeout time.Duration `yaml:"timeout"`
+		// The maximum size of a response-body, in bytes
+		MaxResponseSize int64 `yaml:"maxResponseSize"`
+	}
+	// AuthDefinition declares how to authenticate. Values may reference secrets, like {{secret "example.password"}}
+	//
+	//	auth:
+	//	  type: oauth2
+	//	  url: https://example.com/oauth/token
+	//	  clientId: '{{secret "example.clientId"}}'
+	//	  refreshToken: '{{secret "example.refreshToken"}}'
+	AuthDefinition struct {
+		// One of static, login or oauth2
+		Type string `yaml:"type" schema:"required"`
+		// Headers for the static type
+		Headers map[string]string `yaml:"headers"`
+		// The login-url for the login-type, or the token-url for the oauth2-type
+		URL           string   `yaml:"url"`
+		Username      string   `yaml:"username"`
+		Password      string   `yaml:"password"`
+		UsernameField string   `yaml:"usernameField"`
+		PasswordField string   `yaml:"passwordField"`
+		TokenPath     string   `yaml:"tokenPath"`
+		ExpiresInPath string   `yaml:"expiresInPath"`
+		GrantType     string   `yaml:"grantType"`
+		ClientID      string   `yaml:"clientId"`
+		ClientSecret  string   `yaml:"clientSecret"`
+		RefreshToken  string   `yaml:"refreshToken"`
+		Scopes        []string `yaml:"scopes"`
+		// Header to set the token in. Defaults to Authorization
+		Header string `yaml:"header"`
+		// Defaults to "Bearer {{.token}}"
+		HeaderTemplate string `yaml:"headerTemplate"`
+	}
+	// DefinitionError points to the exact position within the definition-file that is invalid.
+	DefinitionError struct {
+		File   string
+		Line   int
+		Column int
+		// Path to the invalid field, like endpoints.listEpisodes.method
+		Path string
+		Msg  string
+	}
+	definitionParser struct {
+		file  string
+		nodes map[string]*yaml.Node
+		errs  []error
+	}
+)
+
+func (e DefinitionError) Error() string {
+	s := e.File
+	if e.Line > 0 {
+		s += fmt.Sprintf(":%d:%d", e.Line, e.Column)
+	}
+	if e.Path != "" {
+		s += ": " + e.Path
+	}
+	return s + ": " + e.Msg
+}
+
+func (e DefinitionError) Unwrap() error {
+	return ErrInvalidDefinition
+}
+
+var (
+	durationType   = reflect.TypeOf(time.Duration(0))
+	allowedMethods = []string{"", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+)
+
+// LoadDefinitionFile reads a yaml- or json-file and returns a ready GenAPI.
+func LoadDefinitionFile(filePath string, options GenAPIOptions) (*GenAPI, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read definition-file: %w", err)
+	}
+	def, err := ParseDefinition(filePath, b)
+	if err != nil {
+		return nil, err
+	}
+	return def.NewGenAPI(options)
+}
+
+// LoadDefinitionDir loads every yaml- or json-file within the directory, sorted by filename.
+func LoadDefinitionDir(dir string, options GenAPIOptions) ([]*GenAPI, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read definition-directory: %w", err)
+	}
+	var errs error
+	var apis []*GenAPI
+	names := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		filePath := filepath.Join(dir, entry.Name())
+		api, err := LoadDefinitionFile(filePath, options)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if existing, ok := names[api.Name]; ok {
+			errs = errors.Join(errs, DefinitionError{File: filePath, Path: "name", Msg: fmt.Sprintf("name %q is already used by %s", api.Name, existing)})
+			continue
+		}
+		names[api.Name] = filePath
+		apis = append(apis, api)
+	}
+	return apis, errs
+}
+
+// ParseDefinition parses and validates a definition. Json is a subset of yaml, so both are accepted.
+// The fileName is only used for error-messages
+func ParseDefinition(fileName string, data []byte) (Definition, error) {
+	var def Definition
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return def, DefinitionError{File: fileName, Msg: err.Error()}
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return def, DefinitionError{File: fileName, Msg: "definition is empty"}
+	}
+	p := definitionParser{file: fileName, nodes: map[string]*yaml.Node{}}
+	root := doc.Content[0]
+	p.checkSchema(root, reflect.TypeOf(def), "")
+	if len(p.errs) > 0 {
+		return def, errors.Join(p.errs...)
+	}
+	if err := root.Decode(&def); err != nil {
+		return def, DefinitionError{File: fileName, Msg: err.Error()}
+	}
+	p.validate(def)
+	return def, errors.Join(p.errs...)
+}
+
+// NewGenAPI creates a GenAPI from the definition. The definition is expected to be validated.
+func (d Definition) NewGenAPI(options GenAPIOptions) (*GenAPI, error) {
+	u, err := url.Parse(d.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse baseUrl: %w", err)
+	}
+	headers := make(map[string]string, len(d.Headers))
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	endpoint, err := NewEndoint(u, headers)
+	if err != nil {
+		return nil, err
+	}
+	api, err := NewGeneralAPI(d.Name, endpoint, options)
+	if err != nil {
+		return nil, err
+	}
+	if d.CacheTime > 0 {
+		api.CacheTime = d.CacheTime
+	}
+	api.Retry = d.Retry
+	api.RateLimit = d.RateLimit
+	api.FetchConcurrency = d.FetchConcurrency
+	api.Timeout = d.Timeout
+	api.MaxResponseSize = d.MaxResponseSize
+	if d.Auth != nil {
+		api.Auth = d.Auth.Authenticator()
+	}
+	api.Endpoints = make(map[string]*GenAPIEndpoint, len(d.Endpoints))
+	for name, e := range d.Endpoints {
+		if e == nil {
+			e = &GenAPIEndpoint{}
+		}
+		e.Method = strings.ToUpper(e.Method)
+		if e.Enrich != nil {
+			e.Enrich.Endpoint.Method = strings.ToUpper(e.Enrich.Endpoint.Method)
+		}
+		api.Endpoints[name] = e
+		switch name {
+		case EndpointNameSearchTitles:
+			api.EndpointSearchTitles = e
+		case EndpointNameListTitles:
+			api.EndpointListTitles = e
+		case EndpointNameCategories:
+			api.EndpointCategories = e
+		case EndpointNameListEpisodes:
+			api.EndpointListEpisodes = e
+		}
+	}
+	return api, nil
+}
+
+func (a AuthDefinition) Authenticator() Authenticator {
+	switch a.Type {
+	case AuthTypeLogin:
+		return &LoginAuth{
+			TokenAuth:     TokenAuth{Header: a.Header, HeaderTemplate: a.HeaderTemplate},
+			URL:           a.URL,
+			Username:      a.Username,
+			Password:      a.Password,
+			UsernameField: a.UsernameField,
+			PasswordField: a.PasswordField,
+			TokenPath:     a.TokenPath,
+			ExpiresInPath: a.ExpiresInPath,
+		}
+	case AuthTypeOAuth2:
+		return &OAuth2Auth{
+			TokenAuth:    TokenAuth{Header: a.Header, HeaderTemplate: a.HeaderTemplate},
+			TokenURL:     a.URL,
+			GrantType:    a.GrantType,
+			ClientID:     a.ClientID,
+			ClientSecret: a.ClientSecret,
+			RefreshToken: a.RefreshToken,
+			Scopes:       a.Scopes,
+		}
+	}
+	return StaticAuth{Headers: a.Headers}
+}
+
+func (p *definitionParser) errAt(node *yaml.Node, path string, format string, args ...any) {
+	e := DefinitionError{File: p.file, Path: path, Msg: fmt.Sprintf(format, args...)}
+	if node != nil {
+		e.Line, e.Column = node.Line, node.Column
+	}
+	p.errs = append(p.errs, e)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// checkSchema validates the shape of the yaml-node against the go-type it will be decoded into,
+// so that every error can point to a line and column.
+func (p *definitionParser) checkSchema(node *yaml.Node, t reflect.Type, path string) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	p.nodes[path] = node
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if t == durationType {
+		if node.Kind != yaml.ScalarNode {
+			p.errAt(node, path, "expected a duration, like 24h")
+			return
+		}
+		// yaml would read bare numbers as nanoseconds
+		if node.Tag == "!!int" {
+			p.errAt(node, path, "expected a duration, like 24h")
+			return
+		}
+		if _, err := time.ParseDuration(node.Value); err != nil {
+			p.errAt(node, path, "invalid duration %q, expected something like 24h", node.Value)
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			p.errAt(node, path, "expected an object")
+			return
+		}
+		fields := map[string]reflect.StructField{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			fields[name] = f
+		}
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			f, ok := fields[key.Value]
+			if !ok {
+				known := make([]string, 0, len(fields))
+				for k := range fields {
+					known = append(known, k)
+				}
+				sort.Strings(known)
+				p.errAt(key, joinPath(path, key.Value), "unknown field %q, expected one of %s", key.Value, strings.Join(known, ", "))
+				continue
+			}
+			seen[key.Value] = true
+			p.checkSchema(value, f.Type, joinPath(path, key.Value))
+		}
+		for name, f := range fields {
+			if f.Tag.Get("schema") == "required" && !seen[name] {
+				p.errAt(node, joinPath(path, name), "missing required field")
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			p.errAt(node, path, "expected an object")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode || key.Value == "" {
+				p.errAt(key, path, "expected a non-empty key")
+				continue
+			}
+			p.checkSchema(value, t.Elem(), joinPath(path, key.Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			p.errAt(node, path, "expected a list")
+			return
+		}
+		for i, value := range node.Content {
+			p.checkSchema(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Interface:
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			p.errAt(node, path, "expected a string")
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			p.errAt(node, path, "expected a boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			p.errAt(node, path, "expected an integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!float" && node.Tag != "!!int") {
+			p.errAt(node, path, "expected a number")
+		}
+	default:
+		p.errAt(node, path, "unsupported type %s", t.Kind())
+	}
+}
+
+func (p *definitionParser) checkTemplate(path string, s string) {
+	if !strings.Contains(s, "{{") {
+		return
+	}
+	// The functions are only used for parsing, and are never called.
+	g := &GenAPI{}
+	if _, err := template.New("_").Funcs(g.templateFuncs()).Parse(s); err != nil {
+		p.errAt(p.nodes[path], path, "invalid template: %s", err)
+	}
+}
+
+// validate performs the semantic validation, after the schema is known to be correct.
+func (p *definitionParser) validate(d Definition) {
+	if strings.TrimSpace(d.Name) == "" {
+		p.errAt(p.nodes["name"], "name", "must not be empty")
+	}
+	if u, err := url.Parse(d.BaseURL); err != nil {
+		p.errAt(p.nodes["baseUrl"], "baseUrl", "invalid url: %s", err)
+	} else if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		p.errAt(p.nodes["baseUrl"], "baseUrl", "expected an absolute http(s)-url, like https://example.com, got %q", d.BaseURL)
+	}
+	if d.CacheTime < 0 {
+		p.errAt(p.nodes["cacheTime"], "cacheTime", "must not be negative")
+	}
+	for k, v := range d.Headers {
+		p.checkTemplate(joinPath("headers", k), v)
+	}
+	if a := d.Auth; a != nil {
+		switch a.Type {
+		case AuthTypeStatic:
+			if len(a.Headers) == 0 {
+				p.errAt(p.nodes["auth"], "auth.headers", "headers are required for the auth-type %s", a.Type)
+			}
+			for k, v := range a.Headers {
+				p.checkTemplate(joinPath("auth.headers", k), v)
+			}
+		case AuthTypeLogin:
+			if a.URL == "" || a.Username == "" || a.Password == "" {
+				p.errAt(p.nodes["auth"], "auth", "url, username and password are required for the auth-type %s", a.Type)
+			}
+		case AuthTypeOAuth2:
+			if a.URL == "" {
+				p.errAt(p.nodes["auth"], "auth.url", "the token-url is required for the auth-type %s", a.Type)
+			}
+			switch a.GrantType {
+			case "", GrantTypeClientCredentials:
+			case GrantTypeRefreshToken:
+				if a.RefreshToken == "" {
+					p.errAt(p.nodes["auth"], "auth.refreshToken", "a refreshToken is required for the grantType %s", a.GrantType)
+				}
+			default:
+				p.errAt(p.nodes["auth.grantType"], "auth.grantType", "unsupported grantType %q, expected one of %s, %s", a.GrantType, GrantTypeRefreshToken, GrantTypeClientCredentials)
+			}
+		default:
+			p.errAt(p.nodes["auth.type"], "auth.type", "unsupported auth-type %q, expected one of %s, %s, %s", a.Type, AuthTypeStatic, AuthTypeLogin, AuthTypeOAuth2)
+		}
+		for _, k := range []string{"url", "username", "password", "clientId", "clientSecret", "refreshToken"} {
+			if n, ok := p.nodes["auth."+k]; ok {
+				p.checkTemplate("auth."+k, n.Value)
+			}
+		}
+	}
+	if d.Retry != nil {
+		if err := d.Retry.Validate(); err != nil {
+			p.errAt(p.nodes["retry"], "retry", "%s", err)
+		}
+	}
+	if d.RateLimit != nil {
+		if err := d.RateLimit.Validate(); err != nil {
+			p.errAt(p.nodes["rateLimit"], "rateLimit", "%s", err)
+		}
+	}
+	if d.FetchConcurrency < 0 {
+		p.errAt(p.nodes["fetchConcurrency"], "fetchConcurrency", "fetchConcurrency must not be negative")
+	}
+	if d.Timeout < 0 {
+		p.errAt(p.nodes["timeout"], "timeout", "timeout must not be negative")
+	}
+	if d.MaxResponseSize < 0 {
+		p.errAt(p.nodes["maxResponseSize"], "maxResponseSize", "maxResponseSize must not be negative")
+	}
+	names := make([]string, 0, len(d.Endpoints))
+	for name := range d.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e := d.Endpoints[name]
+		path := joinPath("endpoints", name)
+		if e == nil {
+			p.errAt(p.nodes[path], path, "endpoint must not be empty")
+			continue
+		}
+		p.validateEndpoint(path, e)
+	}
+}
+
+func (p *definitionParser) validateEndpoint(path string, e *GenAPIEndpoint) {
+	if !slices.Contains(allowedMethods, strings.ToUpper(e.Method)) {
+		p.errAt(p.nodes[joinPath(path, "method")], joinPath(path, "method"), "unsupported method %q, expected one of %s", e.Method, strings.Join(allowedMethods[1:], ", "))
+	}
+	switch e.BodyType {
+	case "", BodyTypeJSON, BodyTypeForm:
+	default:
+		p.errAt(p.nodes[joinPath(path, "bodyType")], joinPath(path, "bodyType"), "unsupported bodyType %q, expected one of %s, %s", e.BodyType, BodyTypeJSON, BodyTypeForm)
+	}
+	if e.Body != "" || e.GraphQL != nil {
+		switch strings.ToUpper(e.Method) {
+		case http.MethodGet, http.MethodHead:
+			p.errAt(p.nodes[joinPath(path, "method")], joinPath(path, "method"), "a body cannot be sent with %s", strings.ToUpper(e.Method))
+		}
+	}
+	if e.GraphQL != nil {
+		if e.Body != "" {
+			p.errAt(p.nodes[joinPath(path, "body")], joinPath(path, "body"), "body cannot be combined with graphql")
+		}
+		p.checkTemplate(joinPath(path, "graphql.variables"), e.GraphQL.Variables)
+	}
+	switch e.DataType {
+	case "", DataTypeJSON, DataTypeXML, DataTypeRSS:
+	case DataTypeHTML:
+		if err := validateHTMLSelector(e.RootMapping); err != nil {
+			p.errAt(p.nodes[joinPath(path, "rootMapping")], joinPath(path, "rootMapping"), "invalid css-selector: %s", err)
+		}
+		for key, value := range e.Mapping {
+			source, _, _ := parseMapping(value)
+			if err := validateHTMLSelector(source); err != nil {
+				p.errAt(p.nodes[joinPath(path, "mapping."+key)], joinPath(path, "mapping."+key), "invalid css-selector: %s", err)
+			}
+		}
+	default:
+		p.errAt(p.nodes[joinPath(path, "dataType")], joinPath(path, "dataType"), "unsupported dataType %q, expected one of json, xml, rss, html", e.DataType)
+	}
+	for key, value := range e.Mapping {
+		if _, _, err := parseMapping(value); err != nil {
+			p.errAt(p.nodes[joinPath(path, "mapping."+key)], joinPath(path, "mapping."+key), "%s", err)
+		}
+	}
+	p.checkTemplate(joinPath(path, "path"), e.Path)
+	p.checkTemplate(joinPath(path, "query"), e.Query)
+	p.checkTemplate(joinPath(path, "body"), e.Body)
+	if e.Pagination != nil {
+		if err := e.Pagination.Validate(); err != nil {
+			p.errAt(p.nodes[joinPath(path, "pagination")], joinPath(path, "pagination"), "%s", err)
+		}
+	}
+	if e.Enrich != nil {
+		if e.Enrich.Concurrency < 0 {
+			p.errAt(p.nodes[joinPath(path, "enrich.concurrency")], joinPath(path, "enrich.concurrency"), "concurrency must not be negative")
+		}
+		p.validateEndpoint(joinPath(path, "enrich.endpoint"), &e.Enrich.Endpoint)
+	}
+}
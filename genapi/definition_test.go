@@ -0,0 +1,188 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDefinitionYAML = `
+name: example
+baseUrl: https://example.com/api
+cacheTime: 24h
+headers:
+  accept: application/json
+  authorization: 'Bearer {{secret "example.token"}}'
+endpoints:
+  listEpisodes:
+    path: /podcasts/{{.podID}}/episodes
+    rootMapping: items
+    mapping:
+      Title: name
+      GUID: id
+  popular:
+    path: /popular
+    method: get
+`
+
+func TestParseDefinition(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		// Each of these must be contained in the error
+		wantErrs []string
+	}{
+		{
+			"Should parse a valid yaml-definition",
+			testDefinitionYAML,
+			nil,
+		},
+		{
+			"Should parse a valid json-definition",
+			`{"name": "example", "baseUrl": "https://example.com", "endpoints": {"listEpisodes": {"path": "/episodes"}}}`,
+			nil,
+		},
+		{
+			"Should report missing required fields",
+			"cacheTime: 1h\n",
+			[]string{"test.yaml:1:1: name: missing required field", "test.yaml:1:1: baseUrl: missing required field"},
+		},
+		{
+			"Should report unknown fields with position",
+			"name: x\nbaseUrl: https://example.com\nendpoints:\n  foo:\n    pth: /bar\n",
+			[]string{`test.yaml:5:5: endpoints.foo.pth: unknown field "pth"`},
+		},
+		{
+			"Should report wrong types with position",
+			"name: x\nbaseUrl: https://example.com\nheaders: [a, b]\n",
+			[]string{"test.yaml:3:10: headers: expected an object"},
+		},
+		{
+			"Should report invalid durations",
+			"name: x\nbaseUrl: https://example.com\ncacheTime: forever\n",
+			[]string{`test.yaml:3:12: cacheTime: invalid duration "forever"`},
+		},
+		{
+			"Should report durations without a unit",
+			"name: x\nbaseUrl: https://example.com\ncacheTime: 3600\ntimeout: 30\n",
+			[]string{
+				"test.yaml:3:12: cacheTime: expected a duration, like 24h",
+				"test.yaml:4:10: timeout: expected a duration, like 24h",
+			},
+		},
+		{
+			"Should report semantic errors with position",
+			"name: x\nbaseUrl: example.com\nendpoints:\n  foo:\n    method: FETCH\n    path: /{{.id\n",
+			[]string{
+				"test.yaml:2:10: baseUrl: expected an absolute http(s)-url",
+				`test.yaml:5:13: endpoints.foo.method: unsupported method "FETCH"`,
+				"test.yaml:6:11: endpoints.foo.path: invalid template",
+			},
+		},
+		{
+			"Should report invalid bodies",
+			"name: x\nbaseUrl: https://example.com\nendpoints:\n  foo:\n    body: '{\"q\": {{toJson .query}'\n    bodyType: xml\n  bar:\n    method: get\n    body: q=1\n    bodyType: form\n",
+			[]string{
+				"test.yaml:5:11: endpoints.foo.body: invalid template",
+				`test.yaml:6:15: endpoints.foo.bodyType: unsupported bodyType "xml"`,
+				"test.yaml:8:13: endpoints.bar.method: a body cannot be sent with GET",
+			},
+		},
+		{
+			"Should report invalid graphql-endpoints",
+			"name: x\nbaseUrl: https://example.com\nendpoints:\n  foo:\n    body: '{}'\n    graphql:\n      query: '{ podcasts { id } }'\n      variables: '{{.id'\n",
+			[]string{
+				"test.yaml:5:11: endpoints.foo.body: body cannot be combined with graphql",
+				"test.yaml:8:18: endpoints.foo.graphql.variables: invalid template",
+			},
+		},
+		{
+			"Should validate enrichment-endpoints",
+			"name: x\nbaseUrl: https://example.com\nendpoints:\n  foo:\n    enrich:\n      concurrency: -1\n      endpoint:\n        method: FETCH\n",
+			[]string{
+				"test.yaml:6:20: endpoints.foo.enrich.concurrency: concurrency must not be negative",
+				`test.yaml:8:17: endpoints.foo.enrich.endpoint.method: unsupported method "FETCH"`,
+			},
+		},
+		{
+			"Should report a negative fetchConcurrency, timeout and maxResponseSize",
+			"name: x\nbaseUrl: https://example.com\nfetchConcurrency: -2\ntimeout: -1s\nmaxResponseSize: -1\n",
+			[]string{
+				"test.yaml:3:19: fetchConcurrency: fetchConcurrency must not be negative",
+				"test.yaml:4:10: timeout: timeout must not be negative",
+				"test.yaml:5:18: maxResponseSize: maxResponseSize must not be negative",
+			},
+		},
+		{
+			"Should require a graphql-query",
+			"name: x\nbaseUrl: https://example.com\nendpoints:\n  foo:\n    graphql:\n      operationName: x\n",
+			[]string{"test.yaml:6:7: endpoints.foo.graphql.query: missing required field"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDefinition("test.yaml", []byte(tt.data))
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("ParseDefinition() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ParseDefinition() expected error")
+			}
+			if !errors.Is(err, ErrInvalidDefinition) {
+				t.Errorf("ParseDefinition() expected error to wrap ErrInvalidDefinition, got %v", err)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ParseDefinition() error = \n%v\nwant it to contain \n%s", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefinition_NewGenAPI(t *testing.T) {
+	def, err := ParseDefinition("test.yaml", []byte(testDefinitionYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXAMPLE_TOKEN", "s3cret")
+	g, err := def.NewGenAPI(GenAPIOptions{Logger: slog.Default(), Secrets: EnvSecrets{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Name != "example" {
+		t.Errorf("expected name to be example, got %s", g.Name)
+	}
+	if g.CacheTime != 24*time.Hour {
+		t.Errorf("expected cacheTime to be 24h, got %s", g.CacheTime)
+	}
+	if g.EndpointListEpisodes == nil || g.EndpointListEpisodes.RootMapping != "items" || g.EndpointListEpisodes.Mapping["GUID"] != "id" {
+		t.Errorf("expected listEpisodes to be set from definition, got %#v", g.EndpointListEpisodes)
+	}
+	if e, ok := g.Endpoints["popular"]; !ok || e.Method != "GET" {
+		t.Errorf("expected popular-endpoint to be set with method GET, got %#v", e)
+	}
+	// The headers are set by the middlewares, when the request is performed
+	var header http.Header
+	g.Client = testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+		header = r.Header
+		return testResponse(200, "{}"), nil
+	})
+	r, err := g.NewRequest(context.TODO(), "", g.URL.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Do(context.TODO(), r); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("authorization"); got != "Bearer s3cret" {
+		t.Errorf("expected the secret to be resolved in the header, got %q", got)
+	}
+}
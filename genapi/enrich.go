@@ -0,0 +1,127 @@
+package genapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/tidwall/sjson"
+)
+
+// The number of concurrent enrichment-requests, if not set on the Enrichment
+const DefaultEnrichConcurrency = 4
+
+// Enrichment is a follow-up request for every decoded item, typically to a detail-endpoint,
+// whose mapped fields are merged into the item.
+//
+//	enrich:
+//	  endpoint:
+//	    path: /episodes/{{.item.GUID}}
+//	    mapping:
+//	      Description: description |> stripHTML
+//	      Enclosure.URL: soundUrl
+type Enrichment struct {
+	// The templates of the endpoint receive the decoded item as .item, in addition to the data of the list.
+	// The RootMapping should point to a single object, and defaults to the whole response.
+	// Only the fields in its Mapping are changed on the item.
+	Endpoint GenAPIEndpoint `yaml:"endpoint" schema:"required"`
+	// The maximum number of concurrent requests. Defaults to DefaultEnrichConcurrency
+	Concurrency int `yaml:"concurrency"`
+	// If set, a failed enrichment fails the whole list. Otherwise, the item is kept as it is.
+	Required bool `yaml:"required"`
+}
+
+// enrich runs the enrichment-endpoint for every item in responseData, which must be a pointer to a slice.
+func (g *GenAPI) enrich(ctx context.Context, enrichment Enrichment, data map[string]any, responseData any) error {
+	out := reflect.ValueOf(responseData)
+	if out.Kind() != reflect.Pointer || out.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("enriched endpoints must decode into a pointer to a slice, got %T", responseData)
+	}
+	items := out.Elem()
+	concurrency := enrichment.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultEnrichConcurrency
+	}
+	endpoint := enrichment.Endpoint
+	root := endpoint.RootMapping
+	if root == "" {
+		root = "@this"
+	}
+	// Detail-endpoints typically return a single object, which is wrapped so that it is decoded like a list.
+	endpoint.RootMapping = "[" + root + "]"
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	for i := 0; i < items.Len(); i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(item reflect.Value) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := g.enrichItem(ctx, endpoint, data, item.Addr().Interface())
+			if err == nil {
+				return
+			}
+			if !enrichment.Required {
+				g.Logger.Warn("Failed to enrich item, keeping it as is",
+					slog.String("endpoint", endpoint.CompositeKey()),
+					slog.Any("error", err),
+				)
+				return
+			}
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}(items.Index(i))
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (g *GenAPI) enrichItem(ctx context.Context, endpoint GenAPIEndpoint, listData map[string]any, item any) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	var itemData map[string]any
+	if err := json.Unmarshal(b, &itemData); err != nil {
+		return err
+	}
+	// Every request has its own data, since the templates write to it.
+	data := make(map[string]any, len(listData)+1)
+	for k, v := range listData {
+		data[k] = v
+	}
+	data["item"] = itemData
+	u, err := g.CreateSubURL(g.URL, endpoint, data)
+	if err != nil {
+		return err
+	}
+	// Keyed by the url, so that items in several lists share the cache
+	cacheKey := "enrich-" + shortHash([]byte(u.String()))
+	// The mapped json is kept as is, so that only the mapped fields are merged into the item.
+	var mapped []json.RawMessage
+	if _, _, _, err := g.runEndpointPage(ctx, endpoint, u, data, cacheKey, &mapped); err != nil {
+		return fmt.Errorf("failed to enrich item from %s: %w", u.Redacted(), err)
+	}
+	if len(mapped) == 0 {
+		return fmt.Errorf("the enrichment from %s returned no item", u.Redacted())
+	}
+	patch := string(mapped[0])
+	// The source of the item is the list, not the detail-endpoint
+	for _, key := range []string{"_Meta.source", "_Meta.sourceUrl"} {
+		if _, ok := endpoint.Mapping[key]; !ok {
+			patch, _ = sjson.Delete(patch, key)
+		}
+	}
+	return json.Unmarshal([]byte(patch), item)
+}
@@ -0,0 +1,103 @@
+package genapi
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+func TestGenAPI_RunEndpoint_Enrich(t *testing.T) {
+	detailKey := func(u string) string {
+		return "test/enrich-" + shortHash([]byte(u)) + ".json"
+	}
+	tests := []struct {
+		name     string
+		required bool
+		cache    testMapCache
+		want     []rss.Item
+		wantErr  bool
+	}{
+		{
+			"Should merge the mapped fields into every item",
+			false,
+			testMapCache{
+				detailKey("https://example.com/episodes/a?full=1"): []byte(`{"episode": {"description": "Full a", "soundUrl": "https://example.com/a.mp3"}}`),
+				detailKey("https://example.com/episodes/b?full=1"): []byte(`{"episode": {"description": "Full b", "soundUrl": "https://example.com/b.mp3"}}`),
+			},
+			[]rss.Item{
+				{GUID: "a", Title: "A", Description: "Full a", Enclosure: rss.Enclosure{URL: "https://example.com/a.mp3", Type: "audio/mpeg"}},
+				{GUID: "b", Title: "B", Description: "Full b", Enclosure: rss.Enclosure{URL: "https://example.com/b.mp3", Type: "audio/mpeg"}},
+			},
+			false,
+		},
+		{
+			"Should keep items that fail to be enriched",
+			false,
+			testMapCache{
+				detailKey("https://example.com/episodes/a?full=1"): []byte(`{"episode": {"description": "Full a", "soundUrl": "https://example.com/a.mp3"}}`),
+				detailKey("https://example.com/episodes/b?full=1"): []byte(`not json`),
+			},
+			[]rss.Item{
+				{GUID: "a", Title: "A", Description: "Full a", Enclosure: rss.Enclosure{URL: "https://example.com/a.mp3", Type: "audio/mpeg"}},
+				{GUID: "b", Title: "B", Description: "Short b", Enclosure: rss.Enclosure{Type: "audio/mpeg"}},
+			},
+			false,
+		},
+		{
+			"Should fail if the enrichment is required",
+			true,
+			testMapCache{
+				detailKey("https://example.com/episodes/a?full=1"): []byte(`{"episode": {"description": "Full a", "soundUrl": "https://example.com/a.mp3"}}`),
+				detailKey("https://example.com/episodes/b?full=1"): []byte(`{"other": 1}`),
+			},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cache["test/episodes-.json"] = []byte(`[{"id": "a", "title": "A", "summary": "Short a"}, {"id": "b", "title": "B", "summary": "Short b"}]`)
+			g := &GenAPI{
+				Name:          "test",
+				CacheTime:     time.Hour,
+				Endpoint:      Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+				GenAPIOptions: GenAPIOptions{Logger: slog.Default(), Cache: tt.cache},
+			}
+			endpoint := GenAPIEndpoint{
+				Path: "/episodes",
+				Mapping: map[string]string{
+					"GUID":           "id",
+					"Title":          "title",
+					"Description":    "summary",
+					"Enclosure.Type": `@literal:"audio/mpeg"`,
+				},
+				Enrich: &Enrichment{
+					Endpoint: GenAPIEndpoint{
+						Path:        "/episodes/{{.item.GUID}}",
+						Query:       "full=1",
+						RootMapping: "episode",
+						Mapping:     map[string]string{"Description": "description", "Enclosure.URL": "soundUrl"},
+					},
+					Concurrency: 1,
+					Required:    tt.required,
+				},
+			}
+			var items []rss.Item
+			_, _, err := g.RunEndpoint(context.TODO(), endpoint, nil, "episodes-", &items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenAPI.RunEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := deep.Equal(items, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
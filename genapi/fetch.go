@@ -0,0 +1,162 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// The number of channels whose episodes are fetched concurrently for each provider, if not set in FetchOptions
+const DefaultFetchConcurrency = 4
+
+type (
+	FetchOptions struct {
+		// The number of channels whose episodes are fetched concurrently for each provider.
+		// Defaults to DefaultFetchConcurrency
+		Concurrency int
+		// Overrides Concurrency for the providers by name
+		ProviderConcurrency map[string]int
+	}
+	// FetchReport holds every channel that was found, and the errors for the ones that failed.
+	// Channels whose episodes failed are kept, without items.
+	FetchReport struct {
+		Channels []GenAPIChannelList
+		// Sorted by source and channel-id
+		Errors []FetchError
+	}
+	// FetchError is a failure to find the channels of a provider, or to list the episodes of a single channel.
+	FetchError struct {
+		Source string
+		// Empty if finding the channels failed
+		ChannelID string
+		Title     string
+		Err       error
+	}
+)
+
+func (e FetchError) Error() string {
+	if e.ChannelID == "" {
+		return fmt.Sprintf("%s: failed to find channels: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s: failed to list episodes for channel %s (%s): %v", e.Source, e.ChannelID, e.Title, e.Err)
+}
+
+func (e FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Err joins every error, or returns nil if everything was fetched
+func (r FetchReport) Err() error {
+	errs := make([]error, len(r.Errors))
+	for i, e := range r.Errors {
+		errs[i] = e
+	}
+	return errors.Join(errs...)
+}
+
+func (o FetchOptions) concurrency(name string) int {
+	if n := o.ProviderConcurrency[name]; n > 0 {
+		return n
+	}
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	return DefaultFetchConcurrency
+}
+
+// FetchAll finds the channels of every provider, and lists their episodes.
+// The providers are fetched concurrently, and the episodes of each provider with its own limit of concurrency.
+// Failures do not stop the fetch, they are collected in the report together with the channels that did succeed.
+// If the context is cancelled, channels that were not yet started are reported with the error of the context.
+func (r *Registry) FetchAll(ctx context.Context, opts FetchOptions) FetchReport {
+	names := r.Names()
+	reports := make([]FetchReport, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			reports[i] = r.fetchProvider(ctx, name, opts.concurrency(name))
+		}(i, name)
+	}
+	wg.Wait()
+	var report FetchReport
+	for _, rep := range reports {
+		report.Channels = append(report.Channels, rep.Channels...)
+		report.Errors = append(report.Errors, rep.Errors...)
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		a, b := report.Errors[i], report.Errors[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.ChannelID < b.ChannelID
+	})
+	return report
+}
+
+func (r *Registry) fetchProvider(ctx context.Context, name string, concurrency int) FetchReport {
+	var report FetchReport
+	lists, err := r.FindAllChannels(ctx, name)
+	if err != nil {
+		// Providers may return the channels they did find, together with the error
+		report.Errors = append(report.Errors, FetchError{Source: name, Err: err})
+	}
+	report.Channels = lists
+
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		sem = make(chan struct{}, concurrency)
+		// The first copy of each channel, by id, whose episodes are listed
+		first = map[string]*GenApiChannel{}
+		// The later copies of a channel, which get the episodes of the first copy
+		duplicates = map[*GenApiChannel][]*GenApiChannel{}
+	)
+	fail := func(channel *GenApiChannel, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		report.Errors = append(report.Errors, FetchError{Source: name, ChannelID: channel.Meta.ID, Title: channel.Title, Err: err})
+	}
+	for l := range lists {
+		for i := range lists[l].Channels {
+			channel := &lists[l].Channels[i]
+			// Channels are often listed several times, like both as original and followed
+			if f, ok := first[channel.Meta.ID]; ok && channel.Meta.ID != "" {
+				duplicates[f] = append(duplicates[f], channel)
+				continue
+			}
+			first[channel.Meta.ID] = channel
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				fail(channel, ctx.Err())
+				continue
+			}
+			wg.Add(1)
+			go func(channel *GenApiChannel) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := ctx.Err(); err != nil {
+					fail(channel, err)
+					return
+				}
+				episodes, _, err := r.ListEpisodes(ctx, *channel)
+				if err != nil {
+					fail(channel, err)
+					return
+				}
+				channel.Item = episodes.Items
+			}(channel)
+		}
+	}
+	wg.Wait()
+	for channel, copies := range duplicates {
+		for _, c := range copies {
+			c.Item = channel.Item
+		}
+	}
+	return report
+}
@@ -0,0 +1,126 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+var errTestEpisodes = errors.New("test: failed to list episodes")
+
+// fetchTestProvider fails to list the episodes of the channels in failing, and tracks the concurrency.
+type fetchTestProvider struct {
+	testProvider
+	failing  map[string]bool
+	findErr  error
+	delay    time.Duration
+	running  *atomic.Int32
+	maxSeen  *atomic.Int32
+	requests *atomic.Int32
+}
+
+func (p fetchTestProvider) FindAllChannels(ctx context.Context) ([]GenAPIChannelList, error) {
+	lists, _ := p.testProvider.FindAllChannels(ctx)
+	return lists, p.findErr
+}
+
+func (p fetchTestProvider) ListEpisodes(ctx context.Context, id string) (*GenAPIEpisodeList, *http.Response, error) {
+	p.requests.Add(1)
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		m := p.maxSeen.Load()
+		if n <= m || p.maxSeen.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(p.delay)
+	if p.failing[id] {
+		return nil, nil, errTestEpisodes
+	}
+	return p.testProvider.ListEpisodes(ctx, id)
+}
+
+func newFetchTestProvider(ids ...string) fetchTestProvider {
+	p := fetchTestProvider{failing: map[string]bool{}, running: &atomic.Int32{}, maxSeen: &atomic.Int32{}, requests: &atomic.Int32{}}
+	for _, id := range ids {
+		p.channels = append(p.channels, GenApiChannel{Channel: rss.Channel{Title: "title " + id}, Meta: GenApiChannelMeta{ID: id}})
+	}
+	return p
+}
+
+func TestRegistry_FetchAll(t *testing.T) {
+	a := newFetchTestProvider("1", "2", "3", "2", "4", "5", "6")
+	a.failing["3"] = true
+	a.delay = 10 * time.Millisecond
+	b := newFetchTestProvider("x")
+	b.findErr = errors.New("test: partially failed to find channels")
+	r := NewRegistry()
+	if err := r.Register("a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("b", b); err != nil {
+		t.Fatal(err)
+	}
+	report := r.FetchAll(context.TODO(), FetchOptions{Concurrency: 1, ProviderConcurrency: map[string]int{"a": 2}})
+
+	if got := a.maxSeen.Load(); got != 2 {
+		t.Errorf("expected at most 2 concurrent requests for a, got %d", got)
+	}
+	if got := a.requests.Load(); got != 6 {
+		t.Errorf("expected the duplicate channel to be fetched once, got %d requests", got)
+	}
+	var items []string
+	for _, list := range report.Channels {
+		for _, c := range list.Channels {
+			guid := ""
+			if len(c.Item) > 0 {
+				guid = c.Item[0].GUID
+			}
+			items = append(items, c.Meta.Source+":"+c.Meta.ID+"="+guid)
+		}
+	}
+	// The duplicate of channel 2 gets the same episodes
+	want := []string{"a:1=1", "a:2=2", "a:3=", "a:2=2", "a:4=4", "a:5=5", "a:6=6", "b:x=x"}
+	if diff := deep.Equal(items, want); diff != nil {
+		t.Errorf("expected the partial results, in order: %v", diff)
+	}
+	var errs []string
+	for _, e := range report.Errors {
+		errs = append(errs, e.Source+":"+e.ChannelID)
+	}
+	if diff := deep.Equal(errs, []string{"a:3", "b:"}); diff != nil {
+		t.Errorf("expected an error for every failing channel: %v", diff)
+	}
+	if !errors.Is(report.Err(), errTestEpisodes) {
+		t.Errorf("expected the joined error to wrap the errors of the channels, got %v", report.Err())
+	}
+}
+
+func TestRegistry_FetchAll_Cancelled(t *testing.T) {
+	a := newFetchTestProvider("1", "2", "3")
+	r := NewRegistry()
+	if err := r.Register("a", a); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := r.FetchAll(ctx, FetchOptions{})
+	if len(report.Errors) != 3 {
+		t.Fatalf("expected every channel to be reported, got %v", report.Errors)
+	}
+	for _, e := range report.Errors {
+		if !errors.Is(e, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", e)
+		}
+	}
+	if got := a.requests.Load(); got != 0 {
+		t.Errorf("expected no requests after the context was cancelled, got %d", got)
+	}
+}
@@ -0,0 +1,426 @@
+// Package fixture records http-requests to files, and replays them, so that providers can be developed and tested offline.
+//
+// Both the Recorder and the Replayer implement genapi.HttpClient.
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+)
+
+type MatchMode string
+
+const (
+	// Requests must have the same method, url, including the query, and body as the recording
+	MatchStrict MatchMode = "strict"
+	// Requests must have the same method, host and path as the recording.
+	// If several recordings match, the one with the most equal query-parameters is used
+	MatchLenient MatchMode = "lenient"
+
+	Redacted = "REDACTED"
+)
+
+var (
+	ErrNoFixture = errors.New("no fixture for request")
+	// Fields of form- and json-bodies that are redacted if not configured otherwise
+	DefaultRedactFields = []string{"password", "client_secret", "refresh_token", "access_token", "id_token", "api_key", "apikey", "token", "secret"}
+)
+
+type (
+	HttpClient interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+	// Fixture is a recorded request and its response, as stored in a file
+	Fixture struct {
+		Request  Request  `json:"request"`
+		Response Response `json:"response"`
+	}
+	Request struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   Body        `json:"body,omitempty"`
+	}
+	Response struct {
+		StatusCode int         `json:"statusCode"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       Body        `json:"body,omitempty"`
+	}
+	// Body is stored as a string if it is valid utf-8, and as base64 otherwise
+	Body []byte
+	// Redaction replaces secrets in the recorded fixtures
+	Redaction struct {
+		// Header-names to redact. Defaults to genapi.DefaultRedactHeaders
+		Headers []string
+		// Query-parameters to redact, like api_key. Defaults to genapi.DefaultRedactQuery
+		Query []string
+		// Fields of form- and json-bodies to redact, like password. Defaults to DefaultRedactFields.
+		// Fields are matched without case, at any depth of json-bodies
+		Fields []string
+	}
+	// Recorder performs requests with the Client, and writes every request and response to a file in Dir.
+	Recorder struct {
+		Client HttpClient
+		Dir    string
+		Redact Redaction
+		mu     sync.Mutex
+	}
+	// Replayer serves responses from the fixtures in Dir, without performing any requests.
+	//
+	// Requests are redacted before they are matched, like they were when recorded, so the Redaction
+	// should be the same as the Recorder's. Secrets that are not redacted, like a secret in the
+	// path of the url, must be the same as when recorded for a strict match.
+	Replayer struct {
+		Dir    string
+		Mode   MatchMode
+		Redact Redaction
+		once   sync.Once
+		loaded []Fixture
+		err    error
+	}
+)
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal("base64:" + base64.StdEncoding.EncodeToString(b))
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if encoded, ok := strings.CutPrefix(s, "base64:"); ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return err
+		}
+		*b = decoded
+		return nil
+	}
+	*b = []byte(s)
+	return nil
+}
+
+func ParseMatchMode(s string) (MatchMode, error) {
+	switch MatchMode(s) {
+	case "", MatchStrict:
+		return MatchStrict, nil
+	case MatchLenient:
+		return MatchLenient, nil
+	}
+	return "", fmt.Errorf("unknown match-mode %q, expected one of %s, %s", s, MatchStrict, MatchLenient)
+}
+
+func NewRecorder(client HttpClient, dir string) *Recorder {
+	return &Recorder{Client: client, Dir: dir}
+}
+
+func NewReplayer(dir string, mode MatchMode) *Replayer {
+	return &Replayer{Dir: dir, Mode: mode}
+}
+
+// Do performs the request, and records it. Failed requests, without a response, are not recorded.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body for recording: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	f := Fixture{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.Redact.url(req.URL),
+			Header: r.Redact.header(req.Header),
+			Body:   r.Redact.body(req.Header, reqBody),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     r.Redact.header(res.Header),
+			Body:       r.Redact.body(res.Header, resBody),
+		},
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return res, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return res, fmt.Errorf("failed to create fixture-dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.Dir, fileName(f.Request)), b, 0o644); err != nil {
+		return res, fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return res, nil
+}
+
+// Do returns the recorded response for the request, or ErrNoFixture.
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	want := Request{Method: req.Method, URL: r.Redact.url(req.URL), Body: r.Redact.body(req.Header, body)}
+	var f *Fixture
+	switch r.Mode {
+	case MatchLenient:
+		f, err = r.lenient(want)
+	default:
+		f, err = r.strict(want)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Replayer) strict(want Request) (*Fixture, error) {
+	b, err := os.ReadFile(filepath.Join(r.Dir, fileName(want)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoFixture, want.Method, want.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture for %s %s: %w", want.Method, want.URL, err)
+	}
+	return &f, nil
+}
+
+func (r *Replayer) lenient(want Request) (*Fixture, error) {
+	r.once.Do(r.load)
+	if r.err != nil {
+		return nil, r.err
+	}
+	wantURL, err := url.Parse(want.URL)
+	if err != nil {
+		return nil, err
+	}
+	var best *Fixture
+	bestScore := -1
+	for i, f := range r.loaded {
+		u, err := url.Parse(f.Request.URL)
+		if err != nil || f.Request.Method != want.Method || u.Host != wantURL.Host || u.Path != wantURL.Path {
+			continue
+		}
+		score := 0
+		q := u.Query()
+		for k, v := range wantURL.Query() {
+			if slices.Equal(q[k], v) {
+				score++
+			}
+		}
+		if bytes.Equal(f.Request.Body, want.Body) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = &r.loaded[i], score
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoFixture, want.Method, want.URL)
+	}
+	return best, nil
+}
+
+// load reads every fixture in Dir, sorted by file-name, for lenient matching
+func (r *Replayer) load() {
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		r.err = fmt.Errorf("failed to read fixture-dir: %w", err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(r.Dir, e.Name()))
+		if err != nil {
+			r.err = err
+			return
+		}
+		var f Fixture
+		if err := json.Unmarshal(b, &f); err != nil {
+			r.err = fmt.Errorf("failed to decode fixture %s: %w", e.Name(), err)
+			return
+		}
+		r.loaded = append(r.loaded, f)
+	}
+}
+
+func (red Redaction) header(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	h = h.Clone()
+	names := red.Headers
+	if names == nil {
+		names = genapi.DefaultRedactHeaders
+	}
+	for _, name := range names {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, Redacted)
+		}
+	}
+	return h
+}
+
+// url returns the url with redacted and sorted query-parameters
+func (red Redaction) url(u *url.URL) string {
+	c := *u
+	c.User = nil
+	q := c.Query()
+	names := red.Query
+	if names == nil {
+		names = genapi.DefaultRedactQuery
+	}
+	for _, name := range names {
+		if q.Has(name) {
+			q.Set(name, Redacted)
+		}
+	}
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// body returns the form- or json-body with its secret fields redacted.
+// The body is only re-encoded if a field was redacted, other bodies are returned as they are
+func (red Redaction) body(h http.Header, b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	fields := red.Fields
+	if fields == nil {
+		fields = DefaultRedactFields
+	}
+	secret := func(name string) bool {
+		return slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(f, name) })
+	}
+	switch contentType := h.Get("Content-Type"); {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		form, err := url.ParseQuery(string(b))
+		if err != nil {
+			return b
+		}
+		redacted := false
+		for name := range form {
+			if secret(name) {
+				form.Set(name, Redacted)
+				redacted = true
+			}
+		}
+		if redacted {
+			return []byte(form.Encode())
+		}
+	case strings.Contains(contentType, "json") || json.Valid(b):
+		// Numbers are kept as they are written
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber()
+		var v any
+		if err := d.Decode(&v); err != nil {
+			return b
+		}
+		if redactJSON(v, secret) {
+			if redacted, err := json.Marshal(v); err == nil {
+				return redacted
+			}
+		}
+	}
+	return b
+}
+
+// redactJSON replaces the values of secret fields at any depth, and reports whether any was replaced
+func redactJSON(v any, secret func(name string) bool) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if secret(k) {
+				v[k] = Redacted
+				redacted = true
+				continue
+			}
+			redacted = redactJSON(child, secret) || redacted
+		}
+	case []any:
+		for _, child := range v {
+			redacted = redactJSON(child, secret) || redacted
+		}
+	}
+	return redacted
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// fileName is unique for the method, url and body, and readable enough to find a fixture by hand
+func fileName(r Request) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL + "\n"))
+	sum.Write(r.Body)
+	name := r.Method
+	if u, err := url.Parse(r.URL); err == nil {
+		name += "-" + u.Host + u.Path
+	}
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name + "-" + hex.EncodeToString(sum.Sum(nil)[:6]) + ".json"
+}
+
+// readRequestBody reads the body, and replaces it so that the request can still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request-body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
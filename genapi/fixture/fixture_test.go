@@ -0,0 +1,193 @@
+package fixture
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testHTTPFunc func(r *http.Request) (*http.Response, error)
+
+func (f testHTTPFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func record(t *testing.T, dir string, requests ...*http.Request) {
+	t.Helper()
+	rec := NewRecorder(testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(r.Body)
+		}
+		res := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"session=secret"}},
+			Body:       io.NopCloser(strings.NewReader(r.URL.Query().Get("page") + "|" + string(body))),
+		}
+		return res, nil
+	}), dir)
+	rec.Redact.Query = []string{"api_key"}
+	for _, r := range requests {
+		res, err := rec.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The response must still be readable by the caller
+		if b, _ := io.ReadAll(res.Body); len(b) == 0 {
+			t.Errorf("expected the recorded response to have a body")
+		}
+	}
+}
+
+func newRequest(t *testing.T, method, url, body string) *http.Request {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	return req
+}
+
+func TestRecorder_Redaction(t *testing.T) {
+	dir := t.TempDir()
+	record(t, dir, newRequest(t, "GET", "https://example.com/episodes?api_key=secret&page=1", ""))
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected a single fixture, got %v %v", files, err)
+	}
+	b, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "secret") {
+		t.Errorf("expected secrets to be redacted from the fixture:\n%s", b)
+	}
+	if !strings.HasPrefix(filepath.Base(files[0]), "GET-example.com_episodes-") {
+		t.Errorf("expected a readable file-name, got %s", filepath.Base(files[0]))
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	dir := t.TempDir()
+	record(t, dir,
+		newRequest(t, "GET", "https://example.com/episodes?page=1&api_key=secret", ""),
+		newRequest(t, "GET", "https://example.com/episodes?page=2&api_key=secret", ""),
+		newRequest(t, "POST", "https://example.com/search", `{"q": "a"}`),
+	)
+	tests := []struct {
+		name     string
+		mode     MatchMode
+		req      *http.Request
+		wantBody string
+		wantErr  error
+	}{
+		{"Strict match, with a different redacted value and query-order", MatchStrict, newRequest(t, "GET", "https://example.com/episodes?api_key=other&page=2", ""), "2|", nil},
+		{"Strict match on the body", MatchStrict, newRequest(t, "POST", "https://example.com/search", `{"q": "a"}`), `|{"q": "a"}`, nil},
+		{"Strict does not match another body", MatchStrict, newRequest(t, "POST", "https://example.com/search", `{"q": "b"}`), "", ErrNoFixture},
+		{"Strict does not match another query", MatchStrict, newRequest(t, "GET", "https://example.com/episodes?page=3", ""), "", ErrNoFixture},
+		{"Lenient picks the closest query", MatchLenient, newRequest(t, "GET", "https://example.com/episodes?page=2&extra=1", ""), "2|", nil},
+		{"Lenient ignores the body", MatchLenient, newRequest(t, "POST", "https://example.com/search", `{"q": "b"}`), `|{"q": "a"}`, nil},
+		{"Lenient requires the same path", MatchLenient, newRequest(t, "GET", "https://example.com/podcasts", ""), "", ErrNoFixture},
+		{"Lenient requires the same method", MatchLenient, newRequest(t, "DELETE", "https://example.com/search", ""), "", ErrNoFixture},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer := NewReplayer(dir, tt.mode)
+			replayer.Redact.Query = []string{"api_key"}
+			res, err := replayer.Do(tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Replayer.Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			b, _ := io.ReadAll(res.Body)
+			if string(b) != tt.wantBody {
+				t.Errorf("Replayer.Do() body = %q, want %q", b, tt.wantBody)
+			}
+			if res.Header.Get("Set-Cookie") != Redacted || res.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("unexpected headers %v", res.Header)
+			}
+		})
+	}
+}
+
+func TestBody_JSON(t *testing.T) {
+	for _, b := range []Body{Body(`{"a": "æøå"}`), Body{0xff, 0x00, 0xfe}} {
+		data, err := b.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Body
+		if err := got.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(b) {
+			t.Errorf("expected %v to survive a round-trip, got %v (%s)", []byte(b), []byte(got), data)
+		}
+	}
+}
+
+func TestRecorder_RedactBody(t *testing.T) {
+	dir := t.TempDir()
+	rec := NewRecorder(testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"access_token": "secret-access", "expires_in": 3600}`)),
+		}, nil
+	}), dir)
+	form := newRequest(t, "POST", "https://example.com/token?client=a&key=secret-key", "grant_type=password&username=jane&password=secret-password")
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	jsonBody := newRequest(t, "POST", "https://example.com/login", `{"user": {"name": "jane", "Password": "secret-password"}, "refresh_token": "secret-refresh", "n": 12345678901234567890}`)
+	for _, r := range []*http.Request{form, jsonBody} {
+		res, err := rec.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The caller gets the response that is not redacted
+		if b, _ := io.ReadAll(res.Body); !strings.Contains(string(b), "secret-access") {
+			t.Errorf("expected the response to be returned as it is, got %s", b)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected two fixtures, got %v", files)
+	}
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "secret") {
+			t.Errorf("expected secrets to be redacted from the fixture:\n%s", b)
+		}
+		if strings.Contains(file, "login") && !strings.Contains(string(b), "12345678901234567890") {
+			t.Errorf("expected numbers to be kept:\n%s", b)
+		}
+	}
+
+	// When replaying, secrets are resolved to Redacted, or may have other values
+	replayer := NewReplayer(dir, MatchStrict)
+	for _, r := range []*http.Request{
+		newRequest(t, "POST", "https://example.com/token?client=a&key=REDACTED", "grant_type=password&username=jane&password=REDACTED"),
+		newRequest(t, "POST", "https://example.com/token?key=other&client=a", "password=other&grant_type=password&username=jane"),
+		newRequest(t, "POST", "https://example.com/login", `{"refresh_token": "other", "user": {"name": "jane", "Password": "REDACTED"}, "n": 12345678901234567890}`),
+	} {
+		if strings.Contains(r.URL.Path, "token") {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if _, err := replayer.Do(r); err != nil {
+			t.Errorf("expected a strict match for %s: %v", r.URL, err)
+		}
+	}
+}
@@ -11,11 +11,12 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
-	"github.com/Masterminds/sprig/v3"
 	"github.com/runar-rkmedia/audio-mirror/rss"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -41,11 +42,23 @@ type (
 	}
 
 	GenAPIEndpoint struct {
-		Path        string
-		Query       string
-		Method      string
-		RootMapping string
-		Mapping     map[string]string
+		Path   string `yaml:"path"`
+		Query  string `yaml:"query"`
+		Method string `yaml:"method"`
+		// The format of the response, one of json, xml, rss or html. Defaults to json
+		DataType    string            `yaml:"dataType"`
+		RootMapping string            `yaml:"rootMapping"`
+		Mapping     map[string]string `yaml:"mapping"`
+		// Set to retrieve every page of the result, not just the first one.
+		Pagination *Pagination `yaml:"pagination"`
+		// Optional request-body, templated with the same data as Path and Query. Part of the cache-key.
+		Body string `yaml:"body"`
+		// The format of the Body, one of json or form. Defaults to json
+		BodyType string `yaml:"bodyType"`
+		// Turns the endpoint into a graphql-request. Cannot be combined with Body
+		GraphQL *GraphQL `yaml:"graphql"`
+		// Optional follow-up request for every item, like to a detail-endpoint
+		Enrich *Enrichment `yaml:"enrich"`
 	}
 	GenAPI struct {
 		Name      string
@@ -53,13 +66,46 @@ type (
 		Endpoint
 		GenAPIOptions
 		EndpointSearchTitles *GenAPIEndpoint
+		// Lists every channel at the api, used by FindAllChannels
+		EndpointListTitles   *GenAPIEndpoint
 		EndpointCategories   *GenAPIEndpoint
 		EndpointListEpisodes *GenAPIEndpoint
+		// All named endpoints, including the ones above, as declared in a definition-file.
+		Endpoints map[string]*GenAPIEndpoint
+		// Optional authentication for every request
+		Auth Authenticator
+		// Retries failed requests. Without it, every request is attempted once
+		Retry *RetryPolicy
+		// Limits the request-rate for every GenAPI with the same name
+		RateLimit *RateLimit
+		// The number of channels whose episodes are fetched concurrently by Registry.FetchAll
+		FetchConcurrency int
+		// Limits each request, including retries. No limit if zero
+		Timeout time.Duration
+		// Responses larger than this fail with ErrResponseTooLarge. No limit if zero
+		MaxResponseSize int64
+
+		// The cache-keys of responses retrieved by the endpoints, see CachedResponse
+		responseKeys sync.Map
+		// The parsed mapping-values of the endpoints, see mapping
+		mappings sync.Map
+		// The middlewares of Do, see client
+		chainMu sync.Mutex
+		chain   HttpClient
 	}
 	GenAPIOptions struct {
 		Logger *slog.Logger
 		Client HttpClient
 		Cache  Cache
+		// Used to resolve secret-references in headers and templates, like {{secret "untold.token"}}
+		Secrets SecretStore
+		// Persists the tokens of authenticators, for those that do not set their own TokenStore.
+		// Tokens are never written to the Cache
+		Tokens TokenStore
+		// Wraps every request, outside of retries and authentication. The first middleware is the outermost
+		Middlewares []Middleware
+		// Records every attempt of every request, if set
+		Metrics MetricsRecorder
 	}
 	GenAPIChannelList struct {
 		Channels []GenApiChannel
@@ -162,40 +208,36 @@ func (g *GenAPI) NewRequest(ctx context.Context, method string, url string, body
 			method = "POST"
 		}
 	}
-	r, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return r, err
-	}
-	for k, v := range g.Headers {
-		r.Header.Add(k, v)
-	}
-	return r, nil
+	// The headers of the api are set by the middlewares, when the request is performed
+	return http.NewRequestWithContext(ctx, method, url, body)
 }
 
-func (g *GenAPI) NewJSONRequest(ctx context.Context, r *http.Request, v any, cacheKey string) (*http.Response, error) {
+// doRead performs the request, and reads the body of the response
+func (g *GenAPI) doRead(ctx context.Context, r *http.Request) (*http.Response, []byte, *slog.Logger, error) {
 	l := g.Logger.With(
-		slog.String("uri", r.URL.String()),
+		slog.String("uri", redactURL(r.URL, DefaultRedactQuery)),
 		slog.String("method", r.Method),
 	)
-	l.Debug("Performing request")
-	res, err := g.Client.Do(r)
+	res, err := g.Do(ctx, r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to do request: %w", err)
+		return nil, nil, l, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer res.Body.Close()
-	contentType := res.Header.Get("Content-Type")
-	l = l.With(
-		slog.String("contentType", contentType),
-		slog.Int("status", res.StatusCode),
-		slog.Int64("contentLength", res.ContentLength),
-	)
-	l.Debug("Got response")
+	l = l.With(slog.Int("status", res.StatusCode))
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read body: %w", err)
+		return nil, nil, l, fmt.Errorf("failed to read body: %w", err)
 	}
 	if l.Enabled(ctx, -10) {
-		l.Debug("Raw body", slog.String("body", string(body)), slog.Any("headers", res.Header))
+		l.Debug("Raw body", slog.String("body", string(body)))
+	}
+	return res, body, l, nil
+}
+
+func (g *GenAPI) NewJSONRequest(ctx context.Context, r *http.Request, v any, cacheKey string) (*http.Response, error) {
+	res, body, l, err := g.doRead(ctx, r)
+	if err != nil {
+		return nil, err
 	}
 	if res.StatusCode >= 400 {
 		l.Error("unsuccessful statuscode", slog.String("body", string(body)))
@@ -217,7 +259,34 @@ func (g *GenAPI) NewJSONRequest(ctx context.Context, r *http.Request, v any, cac
 }
 
 func (g *GenAPI) ListTitles(ctx context.Context) (GenAPIChannelList, error) {
-	return GenAPIChannelList{}, fmt.Errorf("%w: ListTitles", ErrNotImplemented)
+	if g.EndpointListTitles == nil {
+		return GenAPIChannelList{}, fmt.Errorf("%w: ListTitlesEndpoint", ErrMissingEndpoint)
+	}
+	list, _, err := g.runChannelEndpoint(ctx, *g.EndpointListTitles, nil, "titles-")
+	return list, err
+}
+
+// runChannelEndpoint runs an endpoint that is mapped to channels, and fills in the Meta that the mapping did not.
+func (g *GenAPI) runChannelEndpoint(ctx context.Context, endpoint GenAPIEndpoint, data map[string]any, cacheKeyPrefix string) (GenAPIChannelList, *http.Response, error) {
+	var list GenAPIChannelList
+	r, body, err := g.RunEndpoint(ctx, endpoint, data, cacheKeyPrefix, &list.Channels)
+	list.Raw = body
+	if err != nil {
+		return list, r, err
+	}
+	for i := range list.Channels {
+		meta := &list.Channels[i].Meta
+		if meta.Kind == "" {
+			meta.Kind = ChannelTypePodCast
+		}
+		if meta.Source == "" {
+			meta.Source = g.Name
+		}
+		if meta.SourceURL == "" {
+			meta.SourceURL = endpoint.CompositeKey()
+		}
+	}
+	return list, r, nil
 }
 
 func (g *GenAPI) CreateSubURL(u *url.URL, endpoint GenAPIEndpoint, data map[string]any) (*url.URL, error) {
@@ -264,7 +333,7 @@ func (g *GenAPI) CreateSubURL(u *url.URL, endpoint GenAPIEndpoint, data map[stri
 }
 
 func (g *GenAPI) TemplateString(templateString string, vars map[string]any) (string, error) {
-	tmpl, err := template.New("_").Funcs(sprig.FuncMap()).Parse(templateString)
+	tmpl, err := template.New("_").Funcs(g.templateFuncs()).Parse(templateString)
 	if err != nil {
 		return templateString, fmt.Errorf("failed to create template from string '%s': %s", templateString, err)
 	}
@@ -287,6 +356,15 @@ func (g *GenAPI) TemplateString(templateString string, vars map[string]any) (str
 }
 
 func (g *GenAPI) getCache(keyPath string) ([]byte, bool) {
+	return g.retrieveCache(keyPath, time.Now().Add(-1*g.CacheTime))
+}
+
+// getStaleCache returns the cached item, even if it has expired
+func (g *GenAPI) getStaleCache(keyPath string) ([]byte, bool) {
+	return g.retrieveCache(keyPath, time.Time{})
+}
+
+func (g *GenAPI) retrieveCache(keyPath string, changedAfter time.Time) ([]byte, bool) {
 	if g.Cache == nil {
 		return nil, false
 	}
@@ -297,8 +375,7 @@ func (g *GenAPI) getCache(keyPath string) ([]byte, bool) {
 		g.Name,
 		keyPath,
 	}
-	fmt.Println("cache?", g.CacheTime, time.Now().Add(-1*g.CacheTime), g.Cache)
-	data, ok, err := g.Cache.Retrieve(keyPaths, time.Now().Add(-1*g.CacheTime))
+	data, ok, err := g.Cache.Retrieve(keyPaths, changedAfter)
 	if err != nil {
 		g.Logger.Error("Failed to get cached item", slog.Any("error", err))
 		return nil, false
@@ -317,10 +394,15 @@ func (g *GenAPI) writeCache(keyPath string, value any) (string, error) {
 		g.Name,
 		keyPath,
 	}
-	b, err := json.Marshal(value)
-	if err != nil {
-		g.Logger.Error("Failed to marshall cached item", slog.Any("error", err))
-		return "", err
+	// Raw responses are written as is.
+	b, ok := value.([]byte)
+	if !ok {
+		var err error
+		b, err = json.Marshal(value)
+		if err != nil {
+			g.Logger.Error("Failed to marshall cached item", slog.Any("error", err))
+			return "", err
+		}
 	}
 	cacheID, err := g.Cache.Write(keyPaths, b)
 	if err != nil {
@@ -378,92 +460,52 @@ func (u *GenAPI) FindAllChannels(ctx context.Context) ([]GenAPIChannelList, erro
 	return []GenAPIChannelList{channelList}, nil
 }
 
-func (g *GenAPI) SearchTitles(ctx context.Context, query string) ([]struct{ Name, ID string }, *http.Response, error) {
-	panic("not implemented")
+func (g *GenAPI) SearchTitles(ctx context.Context, query string) (GenAPIChannelList, *http.Response, error) {
 	if g.EndpointSearchTitles == nil {
-		return nil, nil, fmt.Errorf("%w: SearchTitlesEndpoint", ErrMissingEndpoint)
+		return GenAPIChannelList{}, nil, fmt.Errorf("%w: SearchTitlesEndpoint", ErrMissingEndpoint)
 	}
 	data := map[string]any{
 		"query": query,
 	}
-	endpoint := g.EndpointSearchTitles
-	var j []struct {
-		Name, ID string
-	}
-	r, _, err := g.RunEndpoint(ctx, *endpoint, data, "search-", &j)
+	list, r, err := g.runChannelEndpoint(ctx, *g.EndpointSearchTitles, data, "search-")
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to run endpoint: %w", err)
+		return list, r, fmt.Errorf("failed to run endpoint: %w", err)
 	}
-	return j, r, err
+	return list, r, nil
 }
 
 func (g *GenAPI) DecodeEndpointData(ctx context.Context, endpoint GenAPIEndpoint, dateType string, data []byte, out any) error {
-	// var v any
-	switch dateType {
-	case "", "json":
-
-	default:
-		return fmt.Errorf("unknown format: '%s' for deserialization", dateType)
+	if dateType == "" {
+		dateType = endpoint.DataType
 	}
-	root := endpoint.RootMapping
-	if root == "" {
-		root = "@this"
+	if dateType == "" {
+		dateType = DataTypeJSON
 	}
-	result := gjson.GetBytes(data, root)
-	if !result.IsArray() {
-		return fmt.Errorf("expected result to be an array, but was %s from RootMapping %s", result.Type, root)
+	if dateType == DataTypeRSS {
+		endpoint = rssDefaults(endpoint, out)
 	}
-	outjson := "[]"
-	i := 0
-	arr := result.Array()
-	for _, value := range arr {
-
-		thisJSON := "{}"
-		if resy, err := sjson.Set(thisJSON, "_Meta.source", g.Name); err == nil {
-			thisJSON = resy
-		} else {
+	var items []fieldResolver
+	switch dateType {
+	case DataTypeJSON, DataTypeXML, DataTypeRSS:
+		var err error
+		items, err = g.jsonItems(endpoint, dateType, data)
+		if err != nil {
 			return err
 		}
-		endpointCompositeKey := endpoint.CompositeKey()
-		if endpointCompositeKey != "" {
-			if resy, err := sjson.Set(thisJSON, "_Meta.sourceUrl", endpointCompositeKey); err == nil {
-				thisJSON = resy
-			} else {
-				return err
-			}
+	case DataTypeHTML:
+		var err error
+		items, err = g.htmlItems(endpoint, data)
+		if err != nil {
+			return err
 		}
-		for key, path := range endpoint.Mapping {
-			var v any
-			// Escape-hatches
-			if strings.HasPrefix(path, "@@template ") {
-				out, err := g.TemplateString(strings.TrimPrefix(path, "@template "), map[string]any{"data": data})
-				if err != nil {
-					return err
-				}
-				v = out
-			} else {
-				// treat as gjson-path
-				r := value.Get(path)
-				switch r.Type {
-				case gjson.String:
-					v = r.String()
-				case gjson.JSON:
-					err := json.Unmarshal([]byte(r.Raw), &v)
-					if err != nil {
-						return err
-					}
-				case gjson.Null:
-					continue
-				default:
-					return fmt.Errorf("unhandled type %s", r.Type)
-				}
-			}
-			resy, err := sjson.Set(thisJSON, key, v)
-			if err != nil {
-				return err
-			}
-			thisJSON = resy
-
+	default:
+		return fmt.Errorf("unknown format: '%s' for deserialization", dateType)
+	}
+	outjson := "[]"
+	for _, resolve := range items {
+		thisJSON, err := g.mapItem(endpoint, data, resolve)
+		if err != nil {
+			return err
 		}
 		if thisJSON != "{}" {
 			var j any
@@ -477,7 +519,6 @@ func (g *GenAPI) DecodeEndpointData(ctx context.Context, endpoint GenAPIEndpoint
 				return err
 			}
 			outjson = updated
-			i++
 		}
 	}
 	err := json.Unmarshal([]byte(outjson), out)
@@ -491,6 +532,143 @@ func (g *GenAPI) DecodeEndpointData(ctx context.Context, endpoint GenAPIEndpoint
 	return nil
 }
 
+// fieldResolver returns the value for a path within a single item.
+// If the value is not found, ok is false.
+type fieldResolver func(path string) (v any, ok bool, err error)
+
+// jsonItems returns a resolver for every item within the json-document, found at RootMapping.
+// xml is converted to json first.
+func (g *GenAPI) jsonItems(endpoint GenAPIEndpoint, dateType string, data []byte) ([]fieldResolver, error) {
+	arr, err := jsonItemResults(endpoint, dateType, data)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]fieldResolver, len(arr))
+	for i, value := range arr {
+		items[i] = gjsonResolver(value)
+	}
+	return items, nil
+}
+
+// jsonItemResults returns the items at the RootMapping. Xml is converted to json first.
+func jsonItemResults(endpoint GenAPIEndpoint, dateType string, data []byte) ([]gjson.Result, error) {
+	root := endpoint.RootMapping
+	if dateType == DataTypeXML || dateType == DataTypeRSS {
+		b, err := XMLToJSON(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+	if endpoint.GraphQL != nil && dateType == DataTypeJSON {
+		data = graphQLData(data)
+	}
+	if root == "" {
+		root = "@this"
+	}
+	result := gjson.GetBytes(data, root)
+	var arr []gjson.Result
+	switch {
+	case result.IsArray():
+		arr = result.Array()
+	case result.IsObject() && dateType != DataTypeJSON:
+		// With xml, there is no way to tell a list of a single element from a single element.
+		arr = []gjson.Result{result}
+	case !result.Exists() && dateType != DataTypeJSON:
+	default:
+		return nil, fmt.Errorf("expected result to be an array, but was %s from RootMapping %s", result.Type, root)
+	}
+	return arr, nil
+}
+
+func gjsonResolver(value gjson.Result) fieldResolver {
+	return func(path string) (any, bool, error) {
+		var v any
+		r := value.Get(path)
+		switch r.Type {
+		case gjson.String:
+			v = r.String()
+		case gjson.JSON:
+			err := json.Unmarshal([]byte(r.Raw), &v)
+			if err != nil {
+				return nil, false, err
+			}
+		case gjson.Number, gjson.True, gjson.False:
+			// Kept as strings, since that is what the rss-types expects
+			v = r.String()
+		default:
+			return nil, false, nil
+		}
+		return v, true, nil
+	}
+}
+
+// mapField resolves a single mapping-value within an item. Returns nil if the value was not found.
+// The parsed mapping-value is cached unless it is a preview, see mapping.
+func (g *GenAPI) mapField(key, path string, data []byte, resolve fieldResolver, preview bool) (any, error) {
+	// Escape-hatches
+	if strings.HasPrefix(path, "@@template ") {
+		// Templates can read secrets, and previews echo the result back to the caller
+		if preview {
+			return nil, fmt.Errorf("invalid mapping for %s: templates are not supported in previews", key)
+		}
+		return g.TemplateString(strings.TrimPrefix(path, "@@template "), map[string]any{"data": data})
+	}
+	parse := g.mapping
+	if preview {
+		parse = parseMapping
+	}
+	source, pipeline, err := parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping for %s: %w", key, err)
+	}
+	v, ok, err := resolve(source)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		v = nil
+	}
+	v, err = pipeline.apply(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %s: %w", key, err)
+	}
+	return v, nil
+}
+
+// mapItem maps a single item with the endpoints Mapping, and returns the json-result
+func (g *GenAPI) mapItem(endpoint GenAPIEndpoint, data []byte, resolve fieldResolver) (string, error) {
+	thisJSON := "{}"
+	if resy, err := sjson.Set(thisJSON, "_Meta.source", g.Name); err == nil {
+		thisJSON = resy
+	} else {
+		return thisJSON, err
+	}
+	endpointCompositeKey := endpoint.CompositeKey()
+	if endpointCompositeKey != "" {
+		if resy, err := sjson.Set(thisJSON, "_Meta.sourceUrl", endpointCompositeKey); err == nil {
+			thisJSON = resy
+		} else {
+			return thisJSON, err
+		}
+	}
+	for key, path := range endpoint.Mapping {
+		v, err := g.mapField(key, path, data, resolve, false)
+		if err != nil {
+			return thisJSON, err
+		}
+		if v == nil {
+			continue
+		}
+		resy, err := sjson.Set(thisJSON, key, v)
+		if err != nil {
+			return thisJSON, err
+		}
+		thisJSON = resy
+	}
+	return thisJSON, nil
+}
+
 func (g *GenAPI) RunEndpoint(
 	ctx context.Context,
 	endpoint GenAPIEndpoint,
@@ -498,77 +676,141 @@ func (g *GenAPI) RunEndpoint(
 	cacheKeyPrefix string,
 	responseData any,
 ) (*http.Response, []byte, error) {
-	cacheKey := cacheKeyPrefix
 	if data == nil {
 		data = map[string]any{}
 	}
-	for k, v := range data {
-		cacheKey += k + "=" + fmt.Sprintf("%v", v)
+	cacheKey := createCacheKey(cacheKeyPrefix, data)
+	if endpoint.Pagination == nil && endpoint.GraphQL != nil {
+		endpoint.Pagination = endpoint.GraphQL.pagination()
 	}
-	url, err := g.CreateSubURL(g.URL, endpoint, data)
+	var (
+		res  *http.Response
+		body []byte
+		err  error
+	)
+	if endpoint.Pagination != nil {
+		res, body, err = g.runPaginatedEndpoint(ctx, endpoint, data, cacheKey, responseData)
+	} else {
+		var url *url.URL
+		url, err = g.CreateSubURL(g.URL, endpoint, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		res, body, _, err = g.runEndpointPage(ctx, endpoint, url, data, cacheKey, responseData)
+	}
+	if err != nil || endpoint.Enrich == nil {
+		return res, body, err
+	}
+	return res, body, g.enrich(ctx, *endpoint.Enrich, data, responseData)
+}
+
+func createCacheKey(prefix string, data map[string]any) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	cacheKey := prefix
+	for _, k := range keys {
+		cacheKey += k + "=" + fmt.Sprintf("%v", data[k])
+	}
+	return cacheKey
+}
+
+// runEndpointPage retrieves a single response, either from cache or from the api, and decodes it into responseData.
+func (g *GenAPI) runEndpointPage(
+	ctx context.Context,
+	endpoint GenAPIEndpoint,
+	url *url.URL,
+	data map[string]any,
+	cacheKey string,
+	responseData any,
+) (*http.Response, []byte, cacheMeta, error) {
+	var meta cacheMeta
+	reqBody, err := g.renderBody(endpoint, data)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, meta, err
 	}
+	cacheKey = reqBody.cacheKey(cacheKey)
 	cached, found := g.getCache(cacheKey)
 	if found && len(cached) > 0 {
 		g.Logger.Debug("using cache",
 			slog.String("cacheKey", cacheKey),
 		)
+		err := g.DecodeEndpointData(ctx, endpoint, "", cached, responseData)
 		if err == nil {
-			// TODO: add the datatype
-			err = g.DecodeEndpointData(ctx, endpoint, "", cached, responseData)
-			if err == nil {
-				return nil, cached, nil
-			}
+			g.responseKeys.Store(cacheKey, struct{}{})
+			meta, _ = g.getCacheMeta(cacheKey)
+			return nil, cached, meta, nil
 		}
 	}
-	panic("TEMP DISABLE RUNNER")
+	// An expired response can still be used if the api confirms that it has not changed
+	stale, staleFound := g.getStaleCache(cacheKey)
+	staleFound = staleFound && len(stale) > 0
+	// The meta is overwritten if it exists, so that the validators and next-link of a replaced response are not used
+	meta, metaFound := g.getCacheMeta(cacheKey)
 	g.Logger.Debug("not using cache",
 		slog.Bool("found", found),
+		slog.Bool("stale", staleFound),
 		slog.String("cacheKey", cacheKey),
 	)
-	r, err := g.NewRequest(ctx, endpoint.Method, url.String(), nil)
-	if err != nil {
-		return nil, nil, err
+	var bodyReader io.Reader
+	if reqBody != nil {
+		bodyReader = bytes.NewReader(reqBody.data)
 	}
-	l := g.Logger.With(
-		slog.String("uri", r.URL.String()),
-		slog.String("method", r.Method),
-	)
-	l.Debug("Performing request")
-	res, err := g.Client.Do(r)
+	r, err := g.NewRequest(ctx, endpoint.Method, url.String(), bodyReader)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to do request: %w", err)
+		return nil, nil, meta, err
 	}
-	defer res.Body.Close()
-	contentType := res.Header.Get("Content-Type")
-	l = l.With(
-		slog.String("contentType", contentType),
-		slog.Int("status", res.StatusCode),
-		slog.Int64("contentLength", res.ContentLength),
-	)
-	l.Debug("Got response")
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read body: %w", err)
+	if reqBody != nil {
+		r.Header.Set("Content-Type", reqBody.contentType)
 	}
-	if l.Enabled(ctx, -10) {
-		l.Debug("Raw body", slog.String("body", string(body)), slog.Any("headers", res.Header))
+	if staleFound {
+		meta.setConditionalHeaders(r)
 	}
-	if res.StatusCode >= 400 {
-		l.Error("unsuccessful statuscode", slog.String("body", string(body)))
-		return nil, nil, fmt.Errorf("unsuccessful status-code: %d", res.StatusCode)
+	res, body, l, err := g.doRead(ctx, r)
+	if err != nil {
+		return nil, nil, meta, err
+	}
+	if res.StatusCode == http.StatusNotModified {
+		if !staleFound {
+			return nil, nil, meta, fmt.Errorf("%w: %s", ErrNotModifiedWithoutCache, url.String())
+		}
+		l.Debug("Not modified, refreshing cache")
+		body = stale
+		meta.setValidators(res.Header)
+	} else {
+		if endpoint.GraphQL != nil {
+			if err := graphQLErrors(body); err != nil {
+				l.Error("graphql-errors in response", slog.Any("error", err))
+				return nil, nil, meta, err
+			}
+		}
+		if res.StatusCode >= 400 {
+			l.Error("unsuccessful statuscode", slog.String("body", string(body)))
+			return nil, nil, meta, fmt.Errorf("unsuccessful status-code: %d", res.StatusCode)
+		}
+		meta = cacheMeta{Next: nextLink(url, res.Header.Values("Link"))}
+		meta.setValidators(res.Header)
 	}
 	if cacheKey != "" {
+		// Rewriting an unmodified body marks it as fresh
 		_, err = g.writeCache(cacheKey, body)
 		if err != nil {
-			return res, body, err
+			return res, body, meta, err
+		}
+		g.responseKeys.Store(cacheKey, struct{}{})
+		if meta != (cacheMeta{}) || metaFound {
+			_, err = g.writeCache(cacheMetaKey(cacheKey), meta)
+			if err != nil {
+				return res, body, meta, err
+			}
 		}
 	}
 
 	err = g.DecodeEndpointData(ctx, endpoint, "", body, responseData)
 	if err != nil {
-		return res, body, err
+		return res, body, meta, err
 	}
-	return res, body, err
+	return res, body, meta, err
 }
@@ -2,10 +2,12 @@ package genapi
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 	"github.com/runar-rkmedia/audio-mirror/rss"
@@ -85,6 +87,27 @@ func TestGenAPI_DecodeEndpointData(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Should render template-mappings",
+			GenAPIEndpoint{
+				Mapping: map[string]string{
+					"Title": "@@template Fixed title",
+				},
+			},
+			"json",
+			[]byte(`[{"foo": "bar"}]`),
+			GenAPIChannelList{},
+			false,
+			GenAPIChannelList{
+				Channels: []GenApiChannel{
+					{
+						Channel: rss.Channel{
+							Title: "Fixed title",
+						},
+					},
+				},
+			},
+		},
 		{
 			"Should map fields correctlly for single item, multiple keys",
 			GenAPIEndpoint{
@@ -184,10 +207,67 @@ func TestGenAPI_DecodeEndpointData(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Should map xml-elements, attributes and namespaced elements",
+			GenAPIEndpoint{
+				RootMapping: "shows.show",
+				Mapping: map[string]string{
+					"Title":     "name",
+					"Image.URL": "cover._src",
+					"Author":    "itunes:author|@text",
+				},
+			},
+			"xml",
+			[]byte(`<?xml version="1.0"?>
+<shows xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <show><name>bar</name><cover src="https://example.com/a.png"/><itunes:author role="host">Jane</itunes:author></show>
+  <show><name><![CDATA[baz & co]]></name></show>
+</shows>`),
+			GenAPIChannelList{},
+			false,
+			GenAPIChannelList{
+				Channels: []GenApiChannel{
+					{
+						Channel: rss.Channel{
+							Title:  "bar",
+							Author: "Jane",
+							Image:  rss.Image{URL: "https://example.com/a.png"},
+						},
+					},
+					{
+						Channel: rss.Channel{
+							Title: "baz & co",
+						},
+					},
+				},
+			},
+		},
+		{
+			"Should use the channel of an rss-feed",
+			GenAPIEndpoint{},
+			"rss",
+			[]byte(testRSSFeed),
+			GenAPIChannelList{},
+			false,
+			GenAPIChannelList{
+				Channels: []GenApiChannel{
+					{
+						Channel: rss.Channel{
+							Title:       "Example podcast",
+							Description: "All about examples",
+							Link:        rss.Link{Href: "https://example.com"},
+							Author:      "Example Author",
+							Image:       rss.Image{Href: "https://example.com/cover.jpg"},
+							Owner:       &rss.Owner{Name: "Example Owner"},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			g := &GenAPI{}
+			g := &GenAPI{GenAPIOptions: GenAPIOptions{Logger: slog.Default()}}
 			ctx := context.TODO()
 			if err := g.DecodeEndpointData(ctx, tt.endpoint, tt.dataType, tt.data, &tt.out.Channels); (err != nil) != tt.wantErr {
 				t.Fatalf("GenAPI.DecodeEndpointData() error = %v, wantErr %v", err, tt.wantErr)
@@ -198,3 +278,104 @@ func TestGenAPI_DecodeEndpointData(t *testing.T) {
 		})
 	}
 }
+
+var testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Example podcast</title>
+    <description>All about examples</description>
+    <link>https://example.com</link>
+    <itunes:author>Example Author</itunes:author>
+    <itunes:image href="https://example.com/cover.jpg"/>
+    <itunes:owner><itunes:name>Example Owner</itunes:name></itunes:owner>
+    <item>
+      <title>Episode 1</title>
+      <guid isPermaLink="false">ep-1</guid>
+      <pubDate>Mon, 01 Jul 2024 08:00:00 +0000</pubDate>
+      <itunes:duration>1234</itunes:duration>
+      <itunes:season>2</itunes:season>
+      <itunes:episode>1</itunes:episode>
+      <itunes:episodeType>full</itunes:episodeType>
+      <enclosure url="https://example.com/ep1.mp3" type="audio/mpeg" length="5678"/>
+    </item>
+    <item>
+      <title>Episode 2</title>
+      <guid>ep-2</guid>
+    </item>
+  </channel>
+</rss>`
+
+func TestGenAPI_DecodeEndpointData_RSSItems(t *testing.T) {
+	want := []rss.Item{
+		{
+			Title:             "Episode 1",
+			GUID:              "ep-1",
+			PubDate:           "Mon, 01 Jul 2024 08:00:00 +0000",
+			DurationInSeconds: "1234",
+			Season:            "2",
+			Episode:           "1",
+			EpisodeType:       "full",
+			Enclosure: rss.Enclosure{
+				URL:           "https://example.com/ep1.mp3",
+				Type:          "audio/mpeg",
+				LengthInBytes: "5678",
+			},
+		},
+		{
+			Title: "Episode 2",
+			GUID:  "ep-2",
+		},
+	}
+	g := &GenAPI{}
+	var got []rss.Item
+	if err := g.DecodeEndpointData(context.TODO(), GenAPIEndpoint{DataType: "rss"}, "", []byte(testRSSFeed), &got); err != nil {
+		t.Fatalf("GenAPI.DecodeEndpointData() error = %v", err)
+	}
+	if diff := deep.Equal(want, got); len(diff) != 0 {
+		t.Fatalf("not equal %v", diff)
+	}
+}
+
+func TestGenAPI_ListTitles_SearchTitles(t *testing.T) {
+	g := &GenAPI{
+		Name:      "test",
+		CacheTime: time.Hour,
+		Endpoint:  Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+		GenAPIOptions: GenAPIOptions{
+			Logger: slog.Default(),
+			Cache: testMapCache{
+				"test/titles-.json":           []byte(`{"podcasts": [{"id": 1, "name": "Alpha"}, {"id": 2, "name": "Beta", "type": "book"}]}`),
+				"test/search-query=beta.json": []byte(`[{"id": 2, "name": "Beta", "type": "book"}]`),
+			},
+		},
+	}
+	mapping := map[string]string{"Title": "name", "_Meta.ID": "id", "_Meta.Kind": "type"}
+	if _, err := g.ListTitles(context.TODO()); !errors.Is(err, ErrMissingEndpoint) {
+		t.Errorf("expected ErrMissingEndpoint without a listTitles-endpoint, got %v", err)
+	}
+	g.EndpointListTitles = &GenAPIEndpoint{Path: "/podcasts", RootMapping: "podcasts", Mapping: mapping}
+	g.EndpointSearchTitles = &GenAPIEndpoint{Path: "/search", Query: "q={{.query}}", Mapping: mapping}
+
+	lists, err := g.FindAllChannels(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GenApiChannel{
+		{Channel: rss.Channel{Title: "Alpha"}, Meta: GenApiChannelMeta{ID: "1", Kind: ChannelTypePodCast, Source: "test", SourceURL: "/podcasts"}},
+		{Channel: rss.Channel{Title: "Beta"}, Meta: GenApiChannelMeta{ID: "2", Kind: ChannelTypeAudioBook, Source: "test", SourceURL: "/podcasts"}},
+	}
+	if len(lists) != 1 {
+		t.Fatalf("expected a single list, got %d", len(lists))
+	}
+	if diff := deep.Equal(lists[0].Channels, want); diff != nil {
+		t.Error(diff)
+	}
+	search, _, err := g.SearchTitles(context.TODO(), "beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want[1].Meta.SourceURL = "/search?q={{.query}}"
+	if diff := deep.Equal(search.Channels, want[1:]); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,135 @@
+package genapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// The variable that receives the cursor for the next page, if not set on the endpoint
+const DefaultGraphQLCursorVariable = "after"
+
+type (
+	// GraphQL turns the endpoint into a graphql-request, which is always sent as a POST with a json-body.
+	// RootMapping and PageInfoPath are relative to the data-field of the response.
+	//
+	//	graphql:
+	//	  query: |
+	//	    query Episodes($id: ID!, $after: String) {
+	//	      podcast(id: $id) { episodes(first: 50, after: $after) { nodes { id title } pageInfo { hasNextPage endCursor } } }
+	//	    }
+	//	  variables: '{"id": {{toJson .podID}}}'
+	//	  pageInfoPath: podcast.episodes.pageInfo
+	//	rootMapping: podcast.episodes.nodes
+	GraphQL struct {
+		// The query-document
+		Query         string `yaml:"query" schema:"required"`
+		OperationName string `yaml:"operationName"`
+		// A json-object, templated with the same data as Path and Query, like {"id": {{toJson .podID}}}
+		Variables string `yaml:"variables"`
+		// gjson-path to a relay-style pageInfo-object with hasNextPage and endCursor.
+		// If set, every page is retrieved, by passing the endCursor as the CursorVariable.
+		PageInfoPath string `yaml:"pageInfoPath"`
+		// Defaults to DefaultGraphQLCursorVariable
+		CursorVariable string `yaml:"cursorVariable"`
+	}
+	GraphQLError struct {
+		Message    string         `json:"message"`
+		Path       []any          `json:"path"`
+		Extensions map[string]any `json:"extensions"`
+	}
+	// GraphQLErrors is the errors-array of a graphql-response
+	GraphQLErrors []GraphQLError
+)
+
+var ErrGraphQL = errors.New("graphql error")
+
+func (e GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(path, "."), e.Message)
+}
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrGraphQL, strings.Join(messages, "; "))
+}
+
+func (e GraphQLErrors) Is(target error) bool {
+	return target == ErrGraphQL
+}
+
+func (q GraphQL) cursorVariable() string {
+	if q.CursorVariable == "" {
+		return DefaultGraphQLCursorVariable
+	}
+	return q.CursorVariable
+}
+
+// pagination returns cursor-pagination that follows the pageInfo, or nil if there is no PageInfoPath.
+func (q GraphQL) pagination() *Pagination {
+	if q.PageInfoPath == "" {
+		return nil
+	}
+	return &Pagination{
+		Strategy:    PaginationCursor,
+		CursorPath:  joinPath(q.PageInfoPath, "endCursor"),
+		HasMorePath: joinPath(q.PageInfoPath, "hasNextPage"),
+	}
+}
+
+// renderGraphQL creates the json-body for a graphql-request. A non-empty .cursor in data is passed as the cursor-variable.
+func (g *GenAPI) renderGraphQL(q GraphQL, data map[string]any) (*requestBody, error) {
+	variables := map[string]any{}
+	if strings.TrimSpace(q.Variables) != "" {
+		s, err := g.TemplateString(q.Variables, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to template graphql-variables: %w", err)
+		}
+		if err := json.Unmarshal([]byte(s), &variables); err != nil {
+			return nil, fmt.Errorf("the templated graphql-variables are not a json-object: %w", err)
+		}
+	}
+	if cursor, _ := data["cursor"].(string); cursor != "" {
+		variables[q.cursorVariable()] = cursor
+	}
+	b, err := json.Marshal(struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName,omitempty"`
+		Variables     map[string]any `json:"variables"`
+	}{q.Query, q.OperationName, variables})
+	if err != nil {
+		return nil, err
+	}
+	return &requestBody{data: b, contentType: "application/json"}, nil
+}
+
+// graphQLData returns the data-field of a graphql-response, which mappings are relative to
+func graphQLData(body []byte) []byte {
+	return []byte(gjson.GetBytes(body, "data").Raw)
+}
+
+// graphQLErrors returns the errors-array of the response as GraphQLErrors, or nil if there are none.
+// Responses with partial data and errors are treated as failures.
+func graphQLErrors(body []byte) error {
+	result := gjson.GetBytes(body, "errors")
+	if !result.IsArray() || len(result.Array()) == 0 {
+		return nil
+	}
+	var errs GraphQLErrors
+	if err := json.Unmarshal([]byte(result.Raw), &errs); err != nil {
+		return fmt.Errorf("%w: failed to decode errors: %s", ErrGraphQL, result.Raw)
+	}
+	return errs
+}
@@ -0,0 +1,101 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+func TestGenAPI_renderGraphQL(t *testing.T) {
+	g := &GenAPI{GenAPIOptions: GenAPIOptions{Logger: slog.Default()}}
+	q := GraphQL{Query: "query Q($id: ID!) { podcast(id: $id) { id } }", OperationName: "Q", Variables: `{"id": {{toJson .podID}}}`}
+	tests := []struct {
+		name string
+		q    GraphQL
+		data map[string]any
+		want string
+	}{
+		{"Templated variables", q, map[string]any{"podID": "123"}, `{"query":"query Q($id: ID!) { podcast(id: $id) { id } }","operationName":"Q","variables":{"id":"123"}}`},
+		{"Cursor", q, map[string]any{"podID": "123", "cursor": "abc"}, `{"query":"query Q($id: ID!) { podcast(id: $id) { id } }","operationName":"Q","variables":{"after":"abc","id":"123"}}`},
+		{"Custom cursor-variable", GraphQL{Query: "{ x }", CursorVariable: "next"}, map[string]any{"cursor": "abc"}, `{"query":"{ x }","variables":{"next":"abc"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.renderGraphQL(tt.q, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got.data) != tt.want {
+				t.Errorf("GenAPI.renderGraphQL() = \n%s\nwant \n%s", got.data, tt.want)
+			}
+		})
+	}
+	if _, err := g.renderGraphQL(GraphQL{Query: "{ x }", Variables: `[1]`}, map[string]any{}); err == nil {
+		t.Errorf("expected an error for variables that are not an object")
+	}
+}
+
+func Test_graphQLErrors(t *testing.T) {
+	if err := graphQLErrors([]byte(`{"data": {"x": 1}, "errors": []}`)); err != nil {
+		t.Errorf("expected no error for an empty errors-array, got %v", err)
+	}
+	err := graphQLErrors([]byte(`{"data": null, "errors": [{"message": "Not found", "path": ["podcast", 0]}, {"message": "Forbidden"}]}`))
+	if !errors.Is(err, ErrGraphQL) {
+		t.Fatalf("expected ErrGraphQL, got %v", err)
+	}
+	if err.Error() != "graphql error: podcast.0: Not found; Forbidden" {
+		t.Errorf("unexpected error-message %q", err.Error())
+	}
+}
+
+func TestGenAPI_RunEndpoint_GraphQL(t *testing.T) {
+	g := &GenAPI{
+		Name:          "test",
+		CacheTime:     time.Hour,
+		Endpoint:      Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+		GenAPIOptions: GenAPIOptions{Logger: slog.Default()},
+	}
+	endpoint := GenAPIEndpoint{
+		Path: "/graphql",
+		GraphQL: &GraphQL{
+			Query:        "query($id: ID!, $after: String) { podcast(id: $id) { episodes(after: $after) { nodes { title } pageInfo { hasNextPage endCursor } } } }",
+			Variables:    `{"id": {{toJson .podID}}}`,
+			PageInfoPath: "podcast.episodes.pageInfo",
+		},
+		RootMapping: "podcast.episodes.nodes",
+		Mapping:     map[string]string{"Title": "title"},
+	}
+	// The cache-keys depend on the cursor, and on the body, which includes the cursor
+	cacheKey := func(page string, cursor string) string {
+		data := map[string]any{"podID": "1"}
+		prefix := "episodes-podID=1-page=" + page
+		if cursor != "" {
+			data["cursor"] = cursor
+			prefix += "-cursor=" + shortHash([]byte(cursor))
+		}
+		body, err := g.renderGraphQL(*endpoint.GraphQL, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "test/" + body.cacheKey(prefix) + ".json"
+	}
+	g.Cache = testMapCache{
+		cacheKey("1", ""):   []byte(`{"data": {"podcast": {"episodes": {"nodes": [{"title": "a"}, {"title": "b"}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}}`),
+		cacheKey("2", "c1"): []byte(`{"data": {"podcast": {"episodes": {"nodes": [{"title": "c"}], "pageInfo": {"hasNextPage": false, "endCursor": "c2"}}}}}`),
+	}
+	var items []rss.Item
+	_, _, err := g.RunEndpoint(context.TODO(), endpoint, map[string]any{"podID": "1"}, "episodes-", &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rss.Item{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+	if diff := deep.Equal(items, want); diff != nil {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,118 @@
+package genapi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/tidwall/gjson"
+)
+
+// For scraping web-pages. RootMapping is a css-selector for the items, and
+// every value in Mapping is a css-selector within the item, optionally followed by @attribute.
+//
+//	a.title        -> the text of the first matching element
+//	a.title@href   -> the href-attribute of the first matching element
+//	@data-id       -> the data-id-attribute of the item itself
+const DataTypeHTML = "html"
+
+// Attributes that contain urls, which are resolved against the base-url of the api
+var htmlURLAttributes = map[string]bool{"href": true, "src": true, "poster": true}
+
+func (g *GenAPI) htmlItems(endpoint GenAPIEndpoint, data []byte) ([]fieldResolver, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+	sel := doc.Selection
+	if endpoint.RootMapping != "" {
+		m, err := cascadia.Compile(endpoint.RootMapping)
+		if err != nil {
+			return nil, fmt.Errorf("invalid css-selector in RootMapping %s: %w", endpoint.RootMapping, err)
+		}
+		sel = doc.FindMatcher(m)
+	}
+	items := make([]fieldResolver, 0, sel.Length())
+	sel.Each(func(_ int, s *goquery.Selection) {
+		items = append(items, g.htmlResolver(s))
+	})
+	return items, nil
+}
+
+func (g *GenAPI) htmlResolver(item *goquery.Selection) fieldResolver {
+	return func(path string) (any, bool, error) {
+		if strings.HasPrefix(path, "@literal") {
+			return gjsonResolver(gjson.Parse("{}"))(path)
+		}
+		selector, attr := splitHTMLSelector(path)
+		sel := item
+		if selector != "" {
+			m, err := cascadia.Compile(selector)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid css-selector %s: %w", selector, err)
+			}
+			sel = item.FindMatcher(m).First()
+		}
+		if sel.Length() == 0 {
+			return nil, false, nil
+		}
+		if attr == "" {
+			text := strings.Join(strings.Fields(sel.Text()), " ")
+			return text, text != "", nil
+		}
+		v, ok := sel.Attr(attr)
+		if !ok {
+			return nil, false, nil
+		}
+		v = strings.TrimSpace(v)
+		if htmlURLAttributes[strings.ToLower(attr)] && g.URL != nil {
+			if u, err := g.URL.Parse(v); err == nil {
+				v = u.String()
+			}
+		}
+		return v, true, nil
+	}
+}
+
+// splitHTMLSelector splits "a.title@href" into the selector and the attribute.
+// An @ within attribute-selectors or quotes, like a[title="a@b"], is part of the selector
+func splitHTMLSelector(path string) (selector string, attr string) {
+	depth := 0
+	var quote rune
+	at := -1
+	for i, r := range path {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			depth--
+		case r == '@' && depth == 0:
+			at = i
+		}
+	}
+	if at < 0 {
+		return strings.TrimSpace(path), ""
+	}
+	return strings.TrimSpace(path[:at]), strings.TrimSpace(path[at+1:])
+}
+
+// validateHTMLSelector is used to validate the selectors within definitions
+func validateHTMLSelector(path string) error {
+	if strings.HasPrefix(path, "@literal") {
+		return nil
+	}
+	selector, _ := splitHTMLSelector(path)
+	if selector == "" {
+		return nil
+	}
+	_, err := cascadia.Compile(selector)
+	return err
+}
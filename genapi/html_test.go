@@ -0,0 +1,84 @@
+package genapi
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+var testHTMLPage = `<!doctype html>
+<html>
+<body>
+  <ul class="episodes">
+    <li class="episode" data-id="ep-1">
+      <a class="title" href="/episodes/1">  Episode
+        one </a>
+      <time datetime="2024-07-01T08:00:00Z">1. juli</time>
+      <audio src="https://cdn.example.com/1.mp3"></audio>
+    </li>
+    <li class="episode" data-id="ep-2">
+      <a class="title" href="https://example.com/episodes/2">Episode two</a>
+    </li>
+  </ul>
+</body>
+</html>`
+
+func TestGenAPI_DecodeEndpointData_HTML(t *testing.T) {
+	want := []rss.Item{
+		{
+			Title:     "Episode one",
+			GUID:      "ep-1",
+			Link:      "https://example.com/episodes/1",
+			PubDate:   "2024-07-01T08:00:00Z",
+			Enclosure: rss.Enclosure{URL: "https://cdn.example.com/1.mp3"},
+		},
+		{
+			Title: "Episode two",
+			GUID:  "ep-2",
+			Link:  "https://example.com/episodes/2",
+		},
+	}
+	g := &GenAPI{Endpoint: Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}}}
+	endpoint := GenAPIEndpoint{
+		DataType:    DataTypeHTML,
+		RootMapping: "ul.episodes li.episode",
+		Mapping: map[string]string{
+			"Title":         "a.title",
+			"GUID":          "@data-id",
+			"Link":          "a.title@href",
+			"PubDate":       "time@datetime",
+			"Enclosure.URL": "audio@src",
+		},
+	}
+	var got []rss.Item
+	if err := g.DecodeEndpointData(context.TODO(), endpoint, "", []byte(testHTMLPage), &got); err != nil {
+		t.Fatalf("GenAPI.DecodeEndpointData() error = %v", err)
+	}
+	if diff := deep.Equal(want, got); len(diff) != 0 {
+		t.Fatalf("not equal %v", diff)
+	}
+}
+
+func Test_splitHTMLSelector(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantSelector string
+		wantAttr     string
+	}{
+		{"a.title", "a.title", ""},
+		{"a.title@href", "a.title", "href"},
+		{"@data-id", "", "data-id"},
+		{`a[title="a@b"]@href`, `a[title="a@b"]`, "href"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			selector, attr := splitHTMLSelector(tt.path)
+			if selector != tt.wantSelector || attr != tt.wantAttr {
+				t.Errorf("splitHTMLSelector() = %q, %q, want %q, %q", selector, attr, tt.wantSelector, tt.wantAttr)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrResponseTooLarge = errors.New("response is too large")
+
+var (
+	// Query-parameters that are redacted when requests are logged
+	DefaultRedactQuery = []string{"api_key", "apikey", "key", "token", "access_token", "password", "secret", "client_secret"}
+	// Headers that are redacted when requests are logged
+	DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"}
+)
+
+type (
+	// Middleware wraps a HttpClient, and is typically used to change the request, or observe the response.
+	Middleware func(next HttpClient) HttpClient
+	// HttpClientFunc is a HttpClient as a function
+	HttpClientFunc func(r *http.Request) (*http.Response, error)
+	// RequestMetric is recorded for every attempt of a request
+	RequestMetric struct {
+		API    string
+		Method string
+		Host   string
+		// Zero if the request failed without a response
+		Status   int
+		Duration time.Duration
+		Err      error
+	}
+	MetricsRecorder interface {
+		RecordRequest(m RequestMetric)
+	}
+	// Cancels the context of the request when the body is closed
+	cancelBody struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+	limitedBody struct {
+		io.ReadCloser
+		limit, remaining int64
+	}
+)
+
+func (f HttpClientFunc) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// Chain wraps the client with the middlewares. The first middleware is the outermost, and sees the request first.
+func Chain(client HttpClient, middlewares ...Middleware) HttpClient {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client = middlewares[i](client)
+	}
+	return client
+}
+
+// WithTimeout limits the time of each request, until its body is closed. A zero duration disables it.
+func WithTimeout(d time.Duration) Middleware {
+	return func(next HttpClient) HttpClient {
+		if d <= 0 {
+			return next
+		}
+		return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			res, err := next.Do(r.WithContext(ctx))
+			if err != nil || res == nil {
+				cancel()
+				return res, err
+			}
+			res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+			return res, nil
+		})
+	}
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// WithHeaders sets the headers on every request, unless the request already has them.
+func WithHeaders(headers map[string]string) Middleware {
+	return func(next HttpClient) HttpClient {
+		return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+			for k, v := range headers {
+				if r.Header.Get(k) == "" {
+					r.Header.Set(k, v)
+				}
+			}
+			return next.Do(r)
+		})
+	}
+}
+
+// WithMaxResponseSize fails responses with bodies larger than n bytes with ErrResponseTooLarge. Zero disables it.
+func WithMaxResponseSize(n int64) Middleware {
+	return func(next HttpClient) HttpClient {
+		if n <= 0 {
+			return next
+		}
+		return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+			res, err := next.Do(r)
+			if err != nil || res == nil {
+				return res, err
+			}
+			if res.ContentLength > n {
+				res.Body.Close()
+				return nil, fmt.Errorf("%w: the content-length %d is more than %d bytes", ErrResponseTooLarge, res.ContentLength, n)
+			}
+			res.Body = &limitedBody{ReadCloser: res.Body, limit: n, remaining: n}
+			return res, nil
+		})
+	}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, b.limit)
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = -1
+		return n, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, b.limit)
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// WithLogging logs every request and its response at debug-level, with secrets redacted from the url and headers.
+// redactQuery defaults to DefaultRedactQuery.
+func WithLogging(l *slog.Logger, redactQuery ...string) Middleware {
+	if redactQuery == nil {
+		redactQuery = DefaultRedactQuery
+	}
+	return func(next HttpClient) HttpClient {
+		return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+			ctx := r.Context()
+			l := l.With(
+				slog.String("uri", redactURL(r.URL, redactQuery)),
+				slog.String("method", r.Method),
+			)
+			if l.Enabled(ctx, -10) {
+				l.Debug("Request headers", slog.Any("headers", redactHeaders(r.Header)))
+			}
+			l.Debug("Performing request")
+			start := time.Now()
+			res, err := next.Do(r)
+			if err != nil {
+				l.Debug("Request failed", slog.Any("error", err), slog.Duration("duration", time.Since(start)))
+				return res, err
+			}
+			l.Debug("Got response",
+				slog.String("contentType", res.Header.Get("Content-Type")),
+				slog.Int("status", res.StatusCode),
+				slog.Int64("contentLength", res.ContentLength),
+				slog.Duration("duration", time.Since(start)),
+			)
+			if l.Enabled(ctx, -10) {
+				l.Debug("Response headers", slog.Any("headers", redactHeaders(res.Header)))
+			}
+			return res, nil
+		})
+	}
+}
+
+// WithMetrics records every request, labelled with the api
+func WithMetrics(recorder MetricsRecorder, api string) Middleware {
+	return func(next HttpClient) HttpClient {
+		if recorder == nil {
+			return next
+		}
+		return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.Do(r)
+			m := RequestMetric{API: api, Method: r.Method, Host: r.URL.Host, Duration: time.Since(start), Err: err}
+			if res != nil {
+				m.Status = res.StatusCode
+			}
+			recorder.RecordRequest(m)
+			return res, err
+		})
+	}
+}
+
+func redactURL(u *url.URL, params []string) string {
+	c := *u
+	q := c.Query()
+	changed := false
+	for k := range q {
+		for _, p := range params {
+			if strings.EqualFold(k, p) {
+				q.Set(k, "REDACTED")
+				changed = true
+			}
+		}
+	}
+	if changed {
+		c.RawQuery = q.Encode()
+	}
+	return c.Redacted()
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range DefaultRedactHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, "REDACTED")
+		}
+	}
+	return h
+}
+
+// transport is the client with the middlewares for every request to the api, including token-requests.
+// Each attempt is logged and recorded separately.
+func (g *GenAPI) transport() HttpClient {
+	return Chain(g.Client,
+		g.injectHeaders,
+		WithLogging(g.Logger.With(slog.String("api", g.Name))),
+		WithMetrics(g.Metrics, g.Name),
+		WithMaxResponseSize(g.MaxResponseSize),
+	)
+}
+
+// client is the transport with the configured middlewares, retries, rate-limiting and authentication.
+// The timeout includes every retry. The chain is built on the first request, see ResetClient.
+func (g *GenAPI) client() HttpClient {
+	g.chainMu.Lock()
+	defer g.chainMu.Unlock()
+	if g.chain == nil {
+		middlewares := append([]Middleware{WithTimeout(g.Timeout)}, g.Middlewares...)
+		middlewares = append(middlewares, g.retry, g.authenticate)
+		g.chain = Chain(g.transport(), middlewares...)
+	}
+	return g.chain
+}
+
+// ResetClient rebuilds the middlewares on the next request. It must be called if the Client, Middlewares,
+// Retry, RateLimit, Auth, Timeout, Logger, Metrics or MaxResponseSize are changed after the first request.
+func (g *GenAPI) ResetClient() {
+	g.chainMu.Lock()
+	defer g.chainMu.Unlock()
+	g.chain = nil
+}
+
+// injectHeaders sets the headers of the api, resolving templates like secrets, unless the request already has them.
+func (g *GenAPI) injectHeaders(next HttpClient) HttpClient {
+	return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+		for k, v := range g.Headers {
+			if r.Header.Get(k) != "" {
+				continue
+			}
+			if strings.Contains(v, "{{") {
+				var err error
+				v, err = g.TemplateString(v, map[string]any{})
+				if err != nil {
+					return nil, fmt.Errorf("failed to template header %s: %w", k, err)
+				}
+			}
+			r.Header.Set(k, v)
+		}
+		return next.Do(r)
+	})
+}
@@ -0,0 +1,261 @@
+package genapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type PaginationStrategy string
+
+const (
+	// Increments a page-number for every request, like ?page=2
+	PaginationPage PaginationStrategy = "page"
+	// Increments an offset by the page-size for every request, like ?offset=20&limit=20
+	PaginationOffset PaginationStrategy = "offset"
+	// Passes a cursor from the previous response, like ?cursor=abc
+	PaginationCursor PaginationStrategy = "cursor"
+	// Follows the rel="next"-url in the Link-header of the previous response
+	PaginationLink PaginationStrategy = "link"
+
+	DefaultMaxPages = 50
+)
+
+type (
+	Pagination struct {
+		Strategy PaginationStrategy `yaml:"strategy" schema:"required"`
+		// Query-parameter for the page-number, offset or cursor.
+		// If empty, the value is only available to templates in Path and Query, as .page, .offset or .cursor
+		Param string `yaml:"param"`
+		// Query-parameter for the page-size, available to templates as .limit
+		SizeParam string `yaml:"sizeParam"`
+		// If set, a page with fewer items than this is treated as the last page.
+		PageSize int `yaml:"pageSize"`
+		// The first page-number for the page-strategy. Defaults to 1
+		StartPage *int `yaml:"startPage"`
+		// gjson-path to the cursor for the next page, within the response.
+		CursorPath string `yaml:"cursorPath"`
+		// Optional gjson-path to a boolean within the response, which is false on the last page, like pageInfo.hasNextPage
+		HasMorePath string `yaml:"hasMorePath"`
+		// Guards against endless pagination. Defaults to DefaultMaxPages
+		MaxPages int `yaml:"maxPages"`
+	}
+	// Information about a response, which is stored alongside the cached body
+	cacheMeta struct {
+		// Url to the next page, from the Link-header
+		Next string `json:"next,omitempty"`
+		// Validators used to revalidate the cached body when it expires
+		ETag         string `json:"etag,omitempty"`
+		LastModified string `json:"lastModified,omitempty"`
+	}
+)
+
+func (p Pagination) Validate() error {
+	switch p.Strategy {
+	case PaginationPage, PaginationOffset, PaginationLink:
+	case PaginationCursor:
+		if p.CursorPath == "" {
+			return fmt.Errorf("cursorPath is required for pagination-strategy %s", p.Strategy)
+		}
+	default:
+		return fmt.Errorf("unknown pagination-strategy %q, expected one of page, offset, cursor, link", p.Strategy)
+	}
+	if p.Strategy == PaginationOffset && p.PageSize <= 0 {
+		return fmt.Errorf("pageSize is required for pagination-strategy %s", p.Strategy)
+	}
+	if p.PageSize < 0 || p.MaxPages < 0 {
+		return fmt.Errorf("pageSize and maxPages must not be negative")
+	}
+	return nil
+}
+
+func cacheMetaKey(cacheKey string) string {
+	return cacheKey + "-meta.json"
+}
+
+// getCacheMeta returns the meta regardless of its age, since it is needed to revalidate expired responses.
+func (g *GenAPI) getCacheMeta(cacheKey string) (cacheMeta, bool) {
+	var meta cacheMeta
+	b, ok := g.getStaleCache(cacheMetaKey(cacheKey))
+	if !ok || len(b) == 0 {
+		return meta, false
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		g.Logger.Error("Failed to unmarshal cached meta", slog.Any("error", err))
+		return meta, false
+	}
+	return meta, true
+}
+
+// runPaginatedEndpoint retrieves every page of the endpoint, and appends the decoded items into responseData,
+// which must be a pointer to a slice.
+// The returned body is a json-array of the raw body of every page.
+func (g *GenAPI) runPaginatedEndpoint(
+	ctx context.Context,
+	endpoint GenAPIEndpoint,
+	data map[string]any,
+	cacheKey string,
+	responseData any,
+) (*http.Response, []byte, error) {
+	p := *endpoint.Pagination
+	if err := p.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid pagination for endpoint %s: %w", endpoint.CompositeKey(), err)
+	}
+	out := reflect.ValueOf(responseData)
+	if out.Kind() != reflect.Pointer || out.Elem().Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("paginated endpoints must decode into a pointer to a slice, got %T", responseData)
+	}
+	maxPages := p.MaxPages
+	if maxPages == 0 {
+		maxPages = DefaultMaxPages
+	}
+	page := 1
+	if p.StartPage != nil {
+		page = *p.StartPage
+	}
+	var (
+		lastResponse *http.Response
+		bodies       []json.RawMessage
+		cursor       string
+		next         *url.URL
+	)
+	// The pagination-values are added to a copy, so that the caller's data is left as it was
+	data = maps.Clone(data)
+	if data == nil {
+		data = map[string]any{}
+	}
+	for i := 0; i < maxPages; i++ {
+		data["page"] = page + i
+		data["offset"] = i * p.PageSize
+		data["limit"] = p.PageSize
+		data["cursor"] = cursor
+		u := next
+		if u == nil {
+			var err error
+			u, err = g.CreateSubURL(g.URL, endpoint, data)
+			if err != nil {
+				return lastResponse, nil, err
+			}
+			q := u.Query()
+			if p.Param != "" {
+				switch p.Strategy {
+				case PaginationPage:
+					q.Set(p.Param, strconv.Itoa(page+i))
+				case PaginationOffset:
+					q.Set(p.Param, strconv.Itoa(i*p.PageSize))
+				case PaginationCursor:
+					if cursor != "" {
+						q.Set(p.Param, cursor)
+					}
+				}
+			}
+			if p.SizeParam != "" && p.PageSize > 0 {
+				q.Set(p.SizeParam, strconv.Itoa(p.PageSize))
+			}
+			u.RawQuery = q.Encode()
+		}
+		// Pages after the first depend on the cursor or next-link of the previous page, which changes if the previous page does
+		pageCacheKey := fmt.Sprintf("%s-page=%d", cacheKey, i+1)
+		if cursor != "" {
+			pageCacheKey += "-cursor=" + shortHash([]byte(cursor))
+		}
+		if next != nil {
+			pageCacheKey += "-next=" + shortHash([]byte(next.String()))
+		}
+		pageData := reflect.New(out.Elem().Type())
+		res, body, meta, err := g.runEndpointPage(ctx, endpoint, u, data, pageCacheKey, pageData.Interface())
+		if res != nil {
+			lastResponse = res
+		}
+		if err != nil {
+			return lastResponse, nil, fmt.Errorf("failed to retrieve page %d: %w", i+1, err)
+		}
+		if json.Valid(body) {
+			bodies = append(bodies, body)
+		} else {
+			b, _ := json.Marshal(string(body))
+			bodies = append(bodies, b)
+		}
+		items := pageData.Elem()
+		out.Elem().Set(reflect.AppendSlice(out.Elem(), items))
+		if items.Len() == 0 || (p.PageSize > 0 && items.Len() < p.PageSize) {
+			break
+		}
+		pageBody := body
+		if endpoint.GraphQL != nil {
+			pageBody = graphQLData(body)
+		}
+		if p.HasMorePath != "" {
+			if hasMore := gjson.GetBytes(pageBody, p.HasMorePath); hasMore.Exists() && !hasMore.Bool() {
+				break
+			}
+		}
+		switch p.Strategy {
+		case PaginationCursor:
+			nextCursor := gjson.GetBytes(pageBody, p.CursorPath).String()
+			if nextCursor == "" || nextCursor == cursor {
+				return paginatedResult(lastResponse, bodies)
+			}
+			cursor = nextCursor
+		case PaginationLink:
+			if meta.Next == "" {
+				return paginatedResult(lastResponse, bodies)
+			}
+			next, err = u.Parse(meta.Next)
+			if err != nil {
+				return lastResponse, nil, fmt.Errorf("failed to parse next-link %s: %w", meta.Next, err)
+			}
+		}
+		if i == maxPages-1 {
+			g.Logger.Warn("Reached max pages for endpoint, the result may be incomplete",
+				slog.String("endpoint", endpoint.CompositeKey()),
+				slog.Int("maxPages", maxPages),
+			)
+		}
+	}
+	return paginatedResult(lastResponse, bodies)
+}
+
+func paginatedResult(res *http.Response, bodies []json.RawMessage) (*http.Response, []byte, error) {
+	b, err := json.Marshal(bodies)
+	return res, b, err
+}
+
+// nextLink returns the absolute url with rel="next" from the Link-headers, if any
+func nextLink(base *url.URL, headers []string) string {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			for _, param := range parts[1:] {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if !strings.EqualFold(rel, "next") {
+						continue
+					}
+					if base == nil {
+						return target
+					}
+					u, err := base.Parse(target)
+					if err != nil {
+						return target
+					}
+					return u.String()
+				}
+			}
+		}
+	}
+	return ""
+}
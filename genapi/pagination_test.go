@@ -0,0 +1,209 @@
+package genapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+type testMapCache map[string][]byte
+
+func (c testMapCache) Retrieve(keyPaths []string, changedAfter time.Time) ([]byte, bool, error) {
+	b, ok := c[strings.Join(keyPaths, "/")]
+	return b, ok, nil
+}
+
+func (c testMapCache) Write(keyPaths []string, value []byte) (string, error) {
+	key := strings.Join(keyPaths, "/")
+	c[key] = value
+	return key, nil
+}
+
+func TestGenAPI_RunEndpoint_Pagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination Pagination
+		cache      testMapCache
+		wantTitles []string
+		wantErr    bool
+	}{
+		{
+			"Should stop at the first empty page",
+			Pagination{Strategy: PaginationPage, Param: "page"},
+			testMapCache{
+				"test/episodes-id=1-page=1.json": []byte(`{"items": [{"t": "a"}, {"t": "b"}]}`),
+				"test/episodes-id=1-page=2.json": []byte(`{"items": [{"t": "c"}]}`),
+				"test/episodes-id=1-page=3.json": []byte(`{"items": []}`),
+			},
+			[]string{"a", "b", "c"},
+			false,
+		},
+		{
+			"Should stop at a page with fewer items than the page-size",
+			Pagination{Strategy: PaginationOffset, Param: "offset", SizeParam: "limit", PageSize: 2},
+			testMapCache{
+				"test/episodes-id=1-page=1.json": []byte(`{"items": [{"t": "a"}, {"t": "b"}]}`),
+				"test/episodes-id=1-page=2.json": []byte(`{"items": [{"t": "c"}]}`),
+			},
+			[]string{"a", "b", "c"},
+			false,
+		},
+		{
+			"Should follow cursors until there are no more",
+			Pagination{Strategy: PaginationCursor, Param: "cursor", CursorPath: "next"},
+			testMapCache{
+				"test/episodes-id=1-page=1.json":                                         []byte(`{"next": "abc", "items": [{"t": "a"}]}`),
+				"test/episodes-id=1-page=2-cursor=" + shortHash([]byte("abc")) + ".json": []byte(`{"next": "", "items": [{"t": "b"}]}`),
+			},
+			[]string{"a", "b"},
+			false,
+		},
+		{
+			"Should follow the next-link",
+			Pagination{Strategy: PaginationLink},
+			testMapCache{
+				"test/episodes-id=1-page=1.json":      []byte(`{"items": [{"t": "a"}]}`),
+				"test/episodes-id=1-page=1-meta.json": []byte(`{"next": "/episodes?page=2"}`),
+				"test/episodes-id=1-page=2-next=" + shortHash([]byte("https://example.com/episodes?page=2")) + ".json": []byte(`{"items": [{"t": "b"}]}`),
+			},
+			[]string{"a", "b"},
+			false,
+		},
+		{
+			"Should stop at max pages",
+			Pagination{Strategy: PaginationPage, MaxPages: 2},
+			testMapCache{
+				"test/episodes-id=1-page=1.json": []byte(`{"items": [{"t": "a"}]}`),
+				"test/episodes-id=1-page=2.json": []byte(`{"items": [{"t": "b"}]}`),
+				"test/episodes-id=1-page=3.json": []byte(`{"items": [{"t": "c"}]}`),
+			},
+			[]string{"a", "b"},
+			false,
+		},
+		{
+			"Should require a cursorPath for cursor-pagination",
+			Pagination{Strategy: PaginationCursor},
+			testMapCache{},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GenAPI{
+				Name:      "test",
+				CacheTime: time.Hour,
+				Endpoint:  Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+				GenAPIOptions: GenAPIOptions{
+					Logger: slog.Default(),
+					Cache:  tt.cache,
+				},
+			}
+			pagination := tt.pagination
+			endpoint := GenAPIEndpoint{
+				Path:        "/episodes",
+				RootMapping: "items",
+				Mapping:     map[string]string{"Title": "t"},
+				Pagination:  &pagination,
+			}
+			var items []rss.Item
+			_, _, err := g.RunEndpoint(context.TODO(), endpoint, map[string]any{"id": 1}, "episodes-", &items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenAPI.RunEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var titles []string
+			for _, item := range items {
+				titles = append(titles, item.Title)
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.wantTitles, ",") {
+				t.Errorf("GenAPI.RunEndpoint() titles = %v, want %v", titles, tt.wantTitles)
+			}
+		})
+	}
+}
+
+func TestGenAPI_RunEndpoint_Pagination_Client(t *testing.T) {
+	// The cursors of the api, which change when the first page changes
+	pages := map[string]string{
+		"":   `{"next": "c1", "items": [{"t": "a"}]}`,
+		"c1": `{"next": "", "items": [{"t": "b"}]}`,
+		"c2": `{"next": "", "items": [{"t": "c"}]}`,
+	}
+	var cursors []string
+	cache := testMapCache{}
+	g := &GenAPI{
+		Name:      "test",
+		CacheTime: time.Hour,
+		Endpoint:  Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+		GenAPIOptions: GenAPIOptions{
+			Logger: slog.Default(),
+			Cache:  cache,
+			Client: testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+				cursor := r.URL.Query().Get("cursor")
+				cursors = append(cursors, cursor)
+				return testResponse(200, pages[cursor]), nil
+			}),
+		},
+	}
+	endpoint := GenAPIEndpoint{
+		Path:        "/episodes/{{.id}}",
+		RootMapping: "items",
+		Mapping:     map[string]string{"Title": "t"},
+		Pagination:  &Pagination{Strategy: PaginationCursor, Param: "cursor", CursorPath: "next"},
+	}
+	run := func(wantTitles string, wantCursors ...string) {
+		t.Helper()
+		cursors = nil
+		data := map[string]any{"id": 1}
+		var items []rss.Item
+		if _, _, err := g.RunEndpoint(context.TODO(), endpoint, data, "episodes-", &items); err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 1 {
+			t.Errorf("expected the data of the caller to be left as it was, got %v", data)
+		}
+		var titles []string
+		for _, item := range items {
+			titles = append(titles, item.Title)
+		}
+		if strings.Join(titles, ",") != wantTitles {
+			t.Errorf("expected the titles %s, got %v", wantTitles, titles)
+		}
+		if strings.Join(cursors, ",") != strings.Join(wantCursors, ",") {
+			t.Errorf("expected requests with the cursors %q, got %q", wantCursors, cursors)
+		}
+	}
+	run("a,b", "", "c1")
+	// Every page is cached
+	run("a,b")
+	// When the first page expires, and has another cursor, the next page is not taken from the cache
+	delete(cache, "test/episodes-id=1-page=1.json")
+	pages[""] = `{"next": "c2", "items": [{"t": "a"}]}`
+	run("a,c", "", "c2")
+}
+
+func Test_nextLink(t *testing.T) {
+	base, _ := url.Parse("https://example.com/api/episodes?page=1")
+	tests := []struct {
+		name    string
+		headers []string
+		want    string
+	}{
+		{"Should find next among multiple links", []string{`<https://example.com/api/episodes?page=1>; rel="prev", <https://example.com/api/episodes?page=3>; rel="next"`}, "https://example.com/api/episodes?page=3"},
+		{"Should resolve relative links", []string{`</api/episodes?page=2>; rel=next`}, "https://example.com/api/episodes?page=2"},
+		{"Should return empty without a next-link", []string{`</api/episodes?page=1>; rel="first"`}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextLink(base, tt.headers); got != tt.want {
+				t.Errorf("nextLink() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
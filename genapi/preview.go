@@ -0,0 +1,267 @@
+package genapi
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/runar-rkmedia/audio-mirror/rss"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type PreviewKind string
+
+const (
+	PreviewItems    PreviewKind = "items"
+	PreviewChannels PreviewKind = "channels"
+)
+
+// Limits the size of the rss-snippet
+const previewMaxRSSItems = 5
+
+type (
+	// MappingPreview is the result of decoding a sample with a mapping, for live-editing of mappings.
+	// Unlike DecodeEndpointData, errors are collected per field instead of failing the whole decoding.
+	MappingPreview struct {
+		// Either []rss.Item or []GenApiChannel
+		Decoded any
+		Errors  []FieldError
+		// Paths within the source-items that are not used by any mapping. Only available for json, xml and rss
+		UnmappedFields []string
+		// Rss-xml for the decoded result, limited to a few items
+		RSS string
+	}
+	FieldError struct {
+		// Index of the item within the source
+		Item int
+		// The mapping-key, like PubDate. Empty if the error is for the whole item
+		Field string
+		Err   string
+	}
+)
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("item %d: %s", e.Item, e.Err)
+	}
+	return fmt.Sprintf("item %d: %s: %s", e.Item, e.Field, e.Err)
+}
+
+// CachedResponse returns a cached response, regardless of its age.
+// Only the responses of the endpoints that have been run by this GenAPI are available,
+// so that other entries in the cache, like credentials, are never exposed.
+func (g *GenAPI) CachedResponse(cacheKey string) ([]byte, bool) {
+	if _, ok := g.responseKeys.Load(cacheKey); !ok {
+		return nil, false
+	}
+	return g.getStaleCache(cacheKey)
+}
+
+// PreviewMapping decodes the data with the endpoint like DecodeEndpointData.
+// An error is only returned if the data cannot be decoded at all, like invalid json or an invalid RootMapping.
+func (g *GenAPI) PreviewMapping(ctx context.Context, endpoint GenAPIEndpoint, kind PreviewKind, data []byte) (MappingPreview, error) {
+	var preview MappingPreview
+	var channels []GenApiChannel
+	var items []rss.Item
+	var out any = &items
+	switch kind {
+	case "", PreviewItems:
+	case PreviewChannels:
+		out = &channels
+	default:
+		return preview, fmt.Errorf("unknown preview-kind %q", kind)
+	}
+	dataType := endpoint.DataType
+	if dataType == "" {
+		dataType = DataTypeJSON
+	}
+	if dataType == DataTypeRSS {
+		endpoint = rssDefaults(endpoint, out)
+	}
+	var resolvers []fieldResolver
+	switch dataType {
+	case DataTypeJSON, DataTypeXML, DataTypeRSS:
+		results, err := jsonItemResults(endpoint, dataType, data)
+		if err != nil {
+			return preview, err
+		}
+		resolvers = make([]fieldResolver, len(results))
+		for i, r := range results {
+			resolvers[i] = gjsonResolver(r)
+		}
+		preview.UnmappedFields = unmappedFields(endpoint.Mapping, results)
+	case DataTypeHTML:
+		var err error
+		resolvers, err = g.htmlItems(endpoint, data)
+		if err != nil {
+			return preview, err
+		}
+	default:
+		return preview, fmt.Errorf("unknown format: '%s' for deserialization", dataType)
+	}
+	keys := make([]string, 0, len(endpoint.Mapping))
+	for k := range endpoint.Mapping {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, resolve := range resolvers {
+		thisJSON, errs := g.previewItem(endpoint, keys, data, resolve)
+		for _, err := range errs {
+			err.Item = i
+			preview.Errors = append(preview.Errors, err)
+		}
+		var err error
+		switch kind {
+		case PreviewChannels:
+			var c GenApiChannel
+			err = json.Unmarshal([]byte(thisJSON), &c)
+			channels = append(channels, c)
+		default:
+			var item rss.Item
+			err = json.Unmarshal([]byte(thisJSON), &item)
+			items = append(items, item)
+		}
+		if err != nil {
+			preview.Errors = append(preview.Errors, FieldError{Item: i, Field: unmarshalErrorField(err), Err: err.Error()})
+		}
+	}
+	var channel rss.Channel
+	if kind == PreviewChannels {
+		preview.Decoded = channels
+		if len(channels) > 0 {
+			channel = channels[0].Channel
+		}
+	} else {
+		preview.Decoded = items
+		channel.Title = "Preview"
+		channel.Item = items[:min(len(items), previewMaxRSSItems)]
+	}
+	b, err := xml.MarshalIndent(rss.RssHeader(channel), "", "  ")
+	if err != nil {
+		return preview, fmt.Errorf("failed to marshal rss: %w", err)
+	}
+	preview.RSS = string(b)
+	return preview, nil
+}
+
+// previewItem is like mapItem, but continues with the other fields if a field fails.
+func (g *GenAPI) previewItem(endpoint GenAPIEndpoint, keys []string, data []byte, resolve fieldResolver) (string, []FieldError) {
+	var errs []FieldError
+	thisJSON, _ := sjson.Set("{}", "_Meta.source", g.Name)
+	if key := endpoint.CompositeKey(); key != "" {
+		thisJSON, _ = sjson.Set(thisJSON, "_Meta.sourceUrl", key)
+	}
+	for _, key := range keys {
+		v, err := g.mapField(key, endpoint.Mapping[key], data, resolve, true)
+		if err != nil {
+			errs = append(errs, FieldError{Field: key, Err: err.Error()})
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		resy, err := sjson.Set(thisJSON, key, v)
+		if err != nil {
+			errs = append(errs, FieldError{Field: key, Err: err.Error()})
+			continue
+		}
+		thisJSON = resy
+	}
+	return thisJSON, errs
+}
+
+func unmarshalErrorField(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeErr.Field
+	}
+	return ""
+}
+
+// unmappedFields returns the leaf-paths within the items that are not used by the mapping
+func unmappedFields(mapping map[string]string, items []gjson.Result) []string {
+	var used []string
+	for _, value := range mapping {
+		source, _, _ := parseMapping(value)
+		// Modifiers, like |@text, are not part of the path
+		source, _, _ = strings.Cut(source, "|")
+		if source == "" || strings.HasPrefix(source, "@") {
+			continue
+		}
+		used = append(used, source)
+	}
+	seen := map[string]bool{}
+	var unmapped []string
+	for _, item := range items {
+		for _, leaf := range leafPaths(item, "") {
+			if seen[leaf] {
+				continue
+			}
+			seen[leaf] = true
+			if !isPathUsed(leaf, used) {
+				unmapped = append(unmapped, leaf)
+			}
+		}
+	}
+	sort.Strings(unmapped)
+	return unmapped
+}
+
+// A path is used if it, or one of its parents, is mapped.
+func isPathUsed(leaf string, used []string) bool {
+	for _, u := range used {
+		if leaf == u || strings.HasPrefix(leaf, u+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// leafPaths returns the gjson-paths to every value within the result.
+// Items in arrays of objects use the #-syntax, like episodes.#.title
+func leafPaths(r gjson.Result, prefix string) []string {
+	var paths []string
+	switch {
+	case r.IsObject():
+		r.ForEach(func(key, value gjson.Result) bool {
+			paths = append(paths, leafPaths(value, joinPath(prefix, escapeGJSONKey(key.String())))...)
+			return true
+		})
+	case r.IsArray():
+		objects := false
+		for _, v := range r.Array() {
+			if v.IsObject() || v.IsArray() {
+				objects = true
+				break
+			}
+		}
+		if !objects {
+			return []string{prefix}
+		}
+		seen := map[string]bool{}
+		for _, v := range r.Array() {
+			for _, p := range leafPaths(v, joinPath(prefix, "#")) {
+				if !seen[p] {
+					seen[p] = true
+					paths = append(paths, p)
+				}
+			}
+		}
+	default:
+		if prefix != "" {
+			paths = append(paths, prefix)
+		}
+	}
+	return paths
+}
+
+var gjsonKeyEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`)
+
+func escapeGJSONKey(key string) string {
+	return gjsonKeyEscaper.Replace(key)
+}
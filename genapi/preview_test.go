@@ -0,0 +1,149 @@
+package genapi
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+func TestGenAPI_PreviewMapping(t *testing.T) {
+	data := `{"episodes": [
+		{"id": "1", "title": "First", "published": "2024-07-01T08:00:00Z", "cover": {"lg": "https://example.com/1.png", "sm": "s"}, "tags": ["a"]},
+		{"id": "2", "title": "Second", "published": "not a date", "guests": [{"name": "x"}]}
+	]}`
+	endpoint := GenAPIEndpoint{
+		RootMapping: "episodes",
+		Mapping: map[string]string{
+			"GUID":      "id",
+			"Title":     "title",
+			"PubDate":   "published |> date",
+			"Image.URL": "cover.lg",
+			"Link":      "title |> nope",
+		},
+	}
+	g := &GenAPI{Name: "test"}
+	got, err := g.PreviewMapping(context.TODO(), endpoint, PreviewItems, []byte(data))
+	if err != nil {
+		t.Fatalf("GenAPI.PreviewMapping() error = %v", err)
+	}
+	wantItems := []rss.Item{
+		{GUID: "1", Title: "First", PubDate: "Mon, 01 Jul 2024 08:00:00 +0000", Image: rss.Image{URL: "https://example.com/1.png"}},
+		{GUID: "2", Title: "Second"},
+	}
+	if diff := deep.Equal(wantItems, got.Decoded); len(diff) != 0 {
+		t.Errorf("decoded not equal %v", diff)
+	}
+	wantErrors := []string{
+		`item 0: Link: invalid mapping for Link: unknown transform "nope"`,
+		`item 1: Link: invalid mapping for Link: unknown transform "nope"`,
+		`item 1: PubDate: failed to transform PubDate: unrecognized date "not a date"`,
+	}
+	var gotErrors []string
+	for _, e := range got.Errors {
+		gotErrors = append(gotErrors, e.Error())
+	}
+	if diff := deep.Equal(wantErrors, gotErrors); len(diff) != 0 {
+		t.Errorf("errors not equal %v", diff)
+	}
+	wantUnmapped := []string{"cover.sm", "guests.#.name", "tags"}
+	if diff := deep.Equal(wantUnmapped, got.UnmappedFields); len(diff) != 0 {
+		t.Errorf("unmapped fields not equal %v", diff)
+	}
+	if !strings.Contains(got.RSS, "<title>First</title>") || !strings.Contains(got.RSS, "<pubDate>Mon, 01 Jul 2024 08:00:00 +0000</pubDate>") {
+		t.Errorf("expected the rss to contain the items, got %s", got.RSS)
+	}
+	// The mappings come from the caller, and must not fill the cache of the GenAPI
+	g.mappings.Range(func(key, _ any) bool {
+		t.Errorf("expected the preview-mapping %v not to be cached", key)
+		return true
+	})
+}
+
+func TestGenAPI_PreviewMapping_Template(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "s3cret")
+	endpoint := GenAPIEndpoint{
+		RootMapping: "episodes",
+		Mapping: map[string]string{
+			"GUID":  "id",
+			"Title": `@@template {{secret "test.token"}}`,
+			"Link":  "@@template {{.g.Name}}",
+		},
+	}
+	g := &GenAPI{Name: "test", GenAPIOptions: GenAPIOptions{Logger: slog.Default(), Secrets: EnvSecrets{}}}
+	got, err := g.PreviewMapping(context.TODO(), endpoint, PreviewItems, []byte(`{"episodes": [{"id": "1"}]}`))
+	if err != nil {
+		t.Fatalf("GenAPI.PreviewMapping() error = %v", err)
+	}
+	if diff := deep.Equal([]rss.Item{{GUID: "1"}}, got.Decoded); len(diff) != 0 {
+		t.Errorf("decoded not equal %v", diff)
+	}
+	wantErrors := []string{
+		`item 0: Link: invalid mapping for Link: templates are not supported in previews`,
+		`item 0: Title: invalid mapping for Title: templates are not supported in previews`,
+	}
+	var gotErrors []string
+	for _, e := range got.Errors {
+		gotErrors = append(gotErrors, e.Error())
+	}
+	if diff := deep.Equal(wantErrors, gotErrors); len(diff) != 0 {
+		t.Errorf("errors not equal %v", diff)
+	}
+	if strings.Contains(got.RSS, "s3cret") || strings.Contains(fmt.Sprint(got.Decoded), "s3cret") {
+		t.Errorf("expected the secret not to be echoed, got %s", got.RSS)
+	}
+}
+
+func TestGenAPI_PreviewMapping_Channels(t *testing.T) {
+	g := &GenAPI{Name: "test"}
+	got, err := g.PreviewMapping(context.TODO(), GenAPIEndpoint{DataType: DataTypeRSS}, PreviewChannels, []byte(testRSSFeed))
+	if err != nil {
+		t.Fatalf("GenAPI.PreviewMapping() error = %v", err)
+	}
+	channels, ok := got.Decoded.([]GenApiChannel)
+	if !ok || len(channels) != 1 || channels[0].Title == "" {
+		t.Fatalf("expected a single channel, got %#v", got.Decoded)
+	}
+	if len(got.Errors) != 0 {
+		t.Errorf("expected no errors, got %v", got.Errors)
+	}
+	if !strings.Contains(got.RSS, "<title>"+channels[0].Title+"</title>") {
+		t.Errorf("expected the rss to contain the channel, got %s", got.RSS)
+	}
+}
+
+func TestGenAPI_CachedResponse(t *testing.T) {
+	cache := testMapCache{"test/auth-token.json": []byte(`{"access_token":"secret"}`)}
+	g := &GenAPI{
+		Name:     "test",
+		Endpoint: Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+		GenAPIOptions: GenAPIOptions{
+			Logger: slog.Default(),
+			Cache:  cache,
+			Client: testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+				return testResponse(200, `[{"id": "1"}]`), nil
+			}),
+		},
+	}
+	if _, ok := g.CachedResponse("episodes-podID=1"); ok {
+		t.Error("expected no cached response before the endpoint has run")
+	}
+	var items []rss.Item
+	if _, _, err := g.RunEndpoint(context.TODO(), GenAPIEndpoint{Path: "episodes", Mapping: map[string]string{"GUID": "id"}}, map[string]any{"podID": 1}, "episodes-", &items); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := g.CachedResponse("episodes-podID=1"); !ok || string(b) != `[{"id": "1"}]` {
+		t.Errorf("expected the cached response of the endpoint, got %s", b)
+	}
+	for _, key := range []string{"auth-token", "episodes-podID=1-meta", "../test/auth-token"} {
+		if b, ok := g.CachedResponse(key); ok {
+			t.Errorf("expected %s to be rejected, got %s", key, b)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrUnsupported     = errors.New("the provider does not support this")
+)
+
+type (
+	// Provider is required of every provider in the Registry
+	Provider interface {
+		FindAllChannels(ctx context.Context) ([]GenAPIChannelList, error)
+		ListEpisodes(ctx context.Context, id string) (*GenAPIEpisodeList, *http.Response, error)
+	}
+	// Searcher is implemented by providers that can search for channels
+	Searcher interface {
+		SearchTitles(ctx context.Context, query string) (GenAPIChannelList, *http.Response, error)
+	}
+	// MediaProber is implemented by providers that can retrieve information about a media-file, like its content-type
+	MediaProber interface {
+		GetMediaInfo(ctx context.Context, mediaURL string) (*http.Response, error)
+	}
+	// Refresher is implemented by providers that cache their channels, and can revalidate a channel now
+	Refresher interface {
+		Refresh(ctx context.Context, id string) (GenApiChannel, error)
+	}
+	// CapabilityDeclarer is implemented by providers that declare their capabilities.
+	// Search and MediaProbing are only kept if the provider also implements Searcher and MediaProber.
+	CapabilityDeclarer interface {
+		Capabilities() Capabilities
+	}
+	// Capabilities are the optional features of a provider
+	Capabilities struct {
+		Search     bool
+		Categories bool
+		// Episodes are listed across several pages
+		EpisodePaging bool
+		MediaProbing  bool
+		// Requests are authenticated
+		Auth bool
+	}
+	// Registry holds the providers by name, and routes requests for a channel to the provider it came from.
+	Registry struct {
+		mu        sync.RWMutex
+		providers map[string]registeredProvider
+	}
+	registeredProvider struct {
+		provider     Provider
+		capabilities Capabilities
+	}
+)
+
+// Capabilities are declared from the endpoints and configuration of the api
+func (g *GenAPI) Capabilities() Capabilities {
+	return Capabilities{
+		Search:        g.EndpointSearchTitles != nil,
+		Categories:    g.EndpointCategories != nil,
+		EpisodePaging: g.EndpointListEpisodes != nil && g.EndpointListEpisodes.Pagination != nil,
+		Auth:          g.Auth != nil,
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]registeredProvider{}}
+}
+
+// Register adds the provider by name. The name is the Source of its channels, and must be unique.
+func (r *Registry) Register(name string, p Provider) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p == nil {
+		return fmt.Errorf("the provider %s is nil", name)
+	}
+	var caps Capabilities
+	d, declared := p.(CapabilityDeclarer)
+	if declared {
+		caps = d.Capabilities()
+	}
+	// Providers that do not declare their capabilities can search if they implement it
+	_, isSearcher := p.(Searcher)
+	caps.Search = isSearcher && (caps.Search || !declared)
+	_, caps.MediaProbing = p.(MediaProber)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[name]; ok {
+		return fmt.Errorf("a provider named %s is already registered", name)
+	}
+	r.providers[name] = registeredProvider{provider: p, capabilities: caps}
+	return nil
+}
+
+// Names returns the names of the registered providers, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, err := r.get(name)
+	return p.provider, err == nil
+}
+
+func (r *Registry) Capabilities(name string) (Capabilities, error) {
+	p, err := r.get(name)
+	return p.capabilities, err
+}
+
+func (r *Registry) get(name string) (registeredProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return p, fmt.Errorf("%w: %q", ErrUnknownProvider, name)
+	}
+	return p, nil
+}
+
+// FindAllChannels lists the channels of the provider, with the Source set to its name if the provider did not set it.
+func (r *Registry) FindAllChannels(ctx context.Context, name string) ([]GenAPIChannelList, error) {
+	p, err := r.get(name)
+	if err != nil {
+		return nil, err
+	}
+	lists, err := p.provider.FindAllChannels(ctx)
+	for _, list := range lists {
+		for i := range list.Channels {
+			if list.Channels[i].Meta.Source == "" {
+				list.Channels[i].Meta.Source = name
+			}
+		}
+	}
+	return lists, err
+}
+
+// ListEpisodes lists the episodes of the channel, with the provider named by its Source.
+func (r *Registry) ListEpisodes(ctx context.Context, channel GenApiChannel) (*GenAPIEpisodeList, *http.Response, error) {
+	p, err := r.get(channel.Meta.Source)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot list episodes for channel %s: %w", channel.Meta.ID, err)
+	}
+	return p.provider.ListEpisodes(ctx, channel.Meta.ID)
+}
+
+// Refresh revalidates the channel now with the provider named by its Source, and returns it with its items.
+func (r *Registry) Refresh(ctx context.Context, channel GenApiChannel) (GenApiChannel, error) {
+	p, err := r.get(channel.Meta.Source)
+	if err != nil {
+		return GenApiChannel{}, fmt.Errorf("cannot refresh channel %s: %w", channel.Meta.ID, err)
+	}
+	refresher, ok := p.provider.(Refresher)
+	if !ok {
+		return GenApiChannel{}, fmt.Errorf("%w: refresh at %s", ErrUnsupported, channel.Meta.Source)
+	}
+	refreshed, err := refresher.Refresh(ctx, channel.Meta.ID)
+	if err != nil {
+		return refreshed, err
+	}
+	if refreshed.Meta.Source == "" {
+		refreshed.Meta.Source = channel.Meta.Source
+	}
+	return refreshed, nil
+}
+
+func (r *Registry) SearchTitles(ctx context.Context, name, query string) (GenAPIChannelList, *http.Response, error) {
+	p, err := r.get(name)
+	if err != nil {
+		return GenAPIChannelList{}, nil, err
+	}
+	s, ok := p.provider.(Searcher)
+	if !ok || !p.capabilities.Search {
+		return GenAPIChannelList{}, nil, fmt.Errorf("%w: search at %s", ErrUnsupported, name)
+	}
+	return s.SearchTitles(ctx, query)
+}
+
+func (r *Registry) GetMediaInfo(ctx context.Context, name, mediaURL string) (*http.Response, error) {
+	p, err := r.get(name)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := p.provider.(MediaProber)
+	if !ok {
+		return nil, fmt.Errorf("%w: media-probing at %s", ErrUnsupported, name)
+	}
+	return m.GetMediaInfo(ctx, mediaURL)
+}
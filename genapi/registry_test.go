@@ -0,0 +1,139 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+type testProvider struct {
+	channels []GenApiChannel
+}
+
+func (p testProvider) FindAllChannels(ctx context.Context) ([]GenAPIChannelList, error) {
+	return []GenAPIChannelList{{Channels: p.channels}}, nil
+}
+
+func (p testProvider) ListEpisodes(ctx context.Context, id string) (*GenAPIEpisodeList, *http.Response, error) {
+	return &GenAPIEpisodeList{Items: []rss.Item{{GUID: id}}}, nil, nil
+}
+
+type testSearchProvider struct{ testProvider }
+
+func (testSearchProvider) SearchTitles(ctx context.Context, query string) (GenAPIChannelList, *http.Response, error) {
+	return GenAPIChannelList{Channels: []GenApiChannel{{Channel: rss.Channel{Title: query}}}}, nil, nil
+}
+
+type testRefreshProvider struct{ testProvider }
+
+func (testRefreshProvider) Refresh(ctx context.Context, id string) (GenApiChannel, error) {
+	return GenApiChannel{Meta: GenApiChannelMeta{ID: id}, Channel: rss.Channel{Item: []rss.Item{{GUID: "refreshed"}}}}, nil
+}
+
+type testDeclaringProvider struct {
+	testSearchProvider
+	caps Capabilities
+}
+
+func (p testDeclaringProvider) Capabilities() Capabilities { return p.caps }
+
+func TestRegistry_Capabilities(t *testing.T) {
+	g := &GenAPI{
+		Name:                 "gen",
+		EndpointCategories:   &GenAPIEndpoint{},
+		EndpointListEpisodes: &GenAPIEndpoint{Pagination: &Pagination{}},
+		Auth:                 StaticAuth{},
+	}
+	tests := []struct {
+		name string
+		p    Provider
+		want Capabilities
+	}{
+		{"Nothing", testProvider{}, Capabilities{}},
+		{"Search is detected", testSearchProvider{}, Capabilities{Search: true}},
+		{"Declared search is kept if implemented", testDeclaringProvider{caps: Capabilities{Search: true, Categories: true}}, Capabilities{Search: true, Categories: true}},
+		{"Search must be declared by declarers", testDeclaringProvider{caps: Capabilities{Auth: true}}, Capabilities{Auth: true}},
+		{"GenAPI without a search-endpoint", g, Capabilities{Categories: true, EpisodePaging: true, Auth: true}},
+		{"GenAPI with a search-endpoint", &GenAPI{EndpointSearchTitles: &GenAPIEndpoint{}}, Capabilities{Search: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			if err := r.Register("p", tt.p); err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.Capabilities("p")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRegistry_Routing(t *testing.T) {
+	r := NewRegistry()
+	a := testProvider{channels: []GenApiChannel{{Meta: GenApiChannelMeta{ID: "1"}}, {Meta: GenApiChannelMeta{ID: "2", Source: "b"}}}}
+	if err := r.Register("a", a); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("b", testSearchProvider{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("a", a); err == nil {
+		t.Errorf("expected an error when registering the same name twice")
+	}
+	if diff := deep.Equal(r.Names(), []string{"a", "b"}); diff != nil {
+		t.Error(diff)
+	}
+
+	lists, err := r.FindAllChannels(context.TODO(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := lists[0].Channels
+	if channels[0].Meta.Source != "a" || channels[1].Meta.Source != "b" {
+		t.Errorf("expected the Source to be set to the provider, unless it was already set, got %s and %s", channels[0].Meta.Source, channels[1].Meta.Source)
+	}
+	list, _, err := r.ListEpisodes(context.TODO(), channels[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Items[0].GUID != "1" {
+		t.Errorf("expected the episodes of channel 1, got %v", list.Items)
+	}
+	if _, _, err := r.ListEpisodes(context.TODO(), GenApiChannel{Meta: GenApiChannelMeta{ID: "3", Source: "c"}}); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider for an unregistered Source, got %v", err)
+	}
+
+	if _, _, err := r.SearchTitles(context.TODO(), "a", "q"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for a provider without search, got %v", err)
+	}
+	found, _, err := r.SearchTitles(context.TODO(), "b", "q")
+	if err != nil || found.Channels[0].Title != "q" {
+		t.Errorf("expected search to be routed to b, got %v %v", found, err)
+	}
+	if _, err := r.GetMediaInfo(context.TODO(), "b", "https://example.com/a.mp3"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for a provider without media-probing, got %v", err)
+	}
+
+	if _, err := r.Refresh(context.TODO(), channels[0]); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for a provider without refresh, got %v", err)
+	}
+	if err := r.Register("c", testRefreshProvider{}); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := r.Refresh(context.TODO(), GenApiChannel{Meta: GenApiChannelMeta{ID: "3", Source: "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Meta.Source != "c" || len(refreshed.Item) != 1 || refreshed.Item[0].GUID != "refreshed" {
+		t.Errorf("expected the refreshed channel of c, got %#v", refreshed)
+	}
+}
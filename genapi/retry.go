@@ -0,0 +1,278 @@
+package genapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// RetryPolicy retries requests with idempotent methods that fail with network-errors,
+	// 429 Too Many Requests, or a 5xx-status, using exponential backoff with jitter.
+	//
+	//	retry:
+	//	  maxAttempts: 4
+	//	  baseDelay: 500ms
+	//	  maxDelay: 30s
+	RetryPolicy struct {
+		// Total number of attempts, including the first. Defaults to 4
+		MaxAttempts int `yaml:"maxAttempts"`
+		// Delay before the first retry, which doubles for every retry. Defaults to 500ms
+		BaseDelay time.Duration `yaml:"baseDelay"`
+		// Upper bound for the backoff. Defaults to 30s
+		MaxDelay time.Duration `yaml:"maxDelay"`
+		// A Retry-After longer than this is not waited for, and the response is returned as is. Defaults to 2m
+		MaxRetryAfter time.Duration `yaml:"maxRetryAfter"`
+	}
+	// RateLimit is a token-bucket, shared by every GenAPI with the same name.
+	//
+	//	rateLimit:
+	//	  rate: 5
+	//	  burst: 10
+	RateLimit struct {
+		// Requests per second
+		Rate float64 `yaml:"rate" schema:"required"`
+		// Maximum number of requests that can be made at once. Defaults to 1
+		Burst int `yaml:"burst"`
+	}
+	rateLimiter struct {
+		mu     sync.Mutex
+		rate   float64
+		burst  float64
+		tokens float64
+		last   time.Time
+		// Set when the server responds with Retry-After, so that every caller backs off
+		pausedUntil time.Time
+	}
+)
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:   4,
+	BaseDelay:     500 * time.Millisecond,
+	MaxDelay:      30 * time.Second,
+	MaxRetryAfter: 2 * time.Minute,
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = map[string]*rateLimiter{}
+)
+
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 || p.BaseDelay < 0 || p.MaxDelay < 0 || p.MaxRetryAfter < 0 {
+		return fmt.Errorf("maxAttempts, baseDelay, maxDelay and maxRetryAfter must not be negative")
+	}
+	if p.MaxDelay > 0 && p.BaseDelay > p.MaxDelay {
+		return fmt.Errorf("baseDelay must not be greater than maxDelay")
+	}
+	return nil
+}
+
+func (r RateLimit) Validate() error {
+	if r.Rate <= 0 {
+		return fmt.Errorf("rate must be greater than 0")
+	}
+	if r.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	return nil
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay == 0 {
+		p.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if p.MaxRetryAfter == 0 {
+		p.MaxRetryAfter = DefaultRetryPolicy.MaxRetryAfter
+	}
+	return p
+}
+
+// backoff returns the delay before the retry following the attempt (zero-based).
+// Half of the delay is random, so that concurrent callers do not retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.BaseDelay) * math.Pow(2, float64(attempt))
+	if d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	return time.Duration(d/2 + rand.Float64()*d/2)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// parseRetryAfter parses the Retry-After header, which is either in seconds or a http-date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// limiterFor returns the limiter shared by every GenAPI with the name.
+// The limit is updated if it has changed.
+func limiterFor(name string, limit RateLimit) *rateLimiter {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	burst := float64(max(limit.Burst, 1))
+	l, ok := rateLimiters[name]
+	if !ok {
+		l = &rateLimiter{tokens: burst}
+		rateLimiters[name] = l
+	}
+	l.mu.Lock()
+	l.rate, l.burst = limit.Rate, burst
+	l.mu.Unlock()
+	return l
+}
+
+// reserve takes a token, and returns how long the caller must wait before it can use it.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if paused := l.pausedUntil.Sub(now); paused > wait {
+		wait = paused
+	}
+	return wait
+}
+
+func (l *rateLimiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	return sleep(ctx, l.reserve(time.Now()))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Do sends the request with the context through the middlewares of the api, with authentication, rate-limiting and retries.
+// Request-bodies must be replayable (see http.Request.GetBody) to be retried.
+func (g *GenAPI) Do(ctx context.Context, r *http.Request) (*http.Response, error) {
+	return g.client().Do(r.WithContext(ctx))
+}
+
+// retry is the middleware for rate-limiting and retries, as configured for the api
+func (g *GenAPI) retry(next HttpClient) HttpClient {
+	var limiter *rateLimiter
+	if g.RateLimit != nil {
+		limiter = limiterFor(g.Name, *g.RateLimit)
+	}
+	policy := RetryPolicy{MaxAttempts: 1}
+	if g.Retry != nil {
+		policy = g.Retry.withDefaults()
+	}
+	return HttpClientFunc(func(r *http.Request) (*http.Response, error) {
+		return g.doRetry(r, next, policy, limiter)
+	})
+}
+
+func (g *GenAPI) doRetry(r *http.Request, next HttpClient, policy RetryPolicy, limiter *rateLimiter) (*http.Response, error) {
+	ctx := r.Context()
+	req := r
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		res, err := next.Do(req)
+		if attempt+1 >= policy.MaxAttempts || !isIdempotent(r.Method) || ctx.Err() != nil {
+			return res, err
+		}
+		if err == nil && !isRetryableStatus(res.StatusCode) {
+			return res, nil
+		}
+		delay := policy.backoff(attempt)
+		if res != nil {
+			if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > policy.MaxRetryAfter {
+					return res, nil
+				}
+				delay = retryAfter
+				if limiter != nil {
+					limiter.pause(time.Now().Add(retryAfter))
+				}
+			}
+		}
+		retryReq, cloneErr := cloneRequest(ctx, r)
+		if cloneErr != nil {
+			return res, err
+		}
+		attrs := []any{slog.String("api", g.Name), slog.String("url", redactURL(r.URL, DefaultRedactQuery)), slog.Int("attempt", attempt+1), slog.Duration("delay", delay)}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		} else {
+			attrs = append(attrs, slog.Int("status", res.StatusCode))
+			// Draining the body allows the connection to be reused
+			io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+		g.Logger.Warn("Request failed, retrying", attrs...)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		req = retryReq
+	}
+}
@@ -0,0 +1,168 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenAPI_Do_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		err          error
+		wantStatus   int
+		wantAttempts int
+	}{
+		{"retries 5xx until success", http.MethodGet, []int{503, 502, 200}, nil, 200, 3},
+		{"retries 429", http.MethodGet, []int{429, 200}, nil, 200, 2},
+		{"gives up after max attempts", http.MethodGet, []int{500, 500, 500, 500, 200}, nil, 500, 3},
+		{"does not retry 4xx", http.MethodGet, []int{404, 200}, nil, 404, 1},
+		{"does not retry non-idempotent methods", http.MethodPost, []int{503, 200}, nil, 503, 1},
+		{"retries network-errors", http.MethodGet, []int{0, 200}, errors.New("connection reset"), 200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			client := testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+				status := tt.statuses[attempts]
+				attempts++
+				if status == 0 {
+					return nil, tt.err
+				}
+				return testResponse(status, ``), nil
+			})
+			g := &GenAPI{
+				Name:          "test-retry",
+				GenAPIOptions: GenAPIOptions{Logger: slog.Default(), Client: client},
+				Retry:         &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond},
+			}
+			r, err := http.NewRequest(tt.method, "https://example.com/api", strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := g.Do(context.TODO(), r)
+			if err != nil {
+				t.Fatalf("GenAPI.Do() error = %v", err)
+			}
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("GenAPI.Do() status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("GenAPI.Do() attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestGenAPI_Do_RetryAfterTooLong(t *testing.T) {
+	attempts := 0
+	client := testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		res := testResponse(429, ``)
+		res.Header.Set("Retry-After", "3600")
+		return res, nil
+	})
+	g := &GenAPI{
+		Name:          "test-retry-after",
+		GenAPIOptions: GenAPIOptions{Logger: slog.Default(), Client: client},
+		Retry:         &RetryPolicy{MaxRetryAfter: time.Minute},
+	}
+	r, _ := http.NewRequest(http.MethodGet, "https://example.com/api", nil)
+	res, err := g.Do(context.TODO(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 429 || attempts != 1 {
+		t.Errorf("expected the 429 to be returned without retrying, got %d after %d attempts", res.StatusCode, attempts)
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Mon, 01 Jul 2024 08:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jul 2024 07:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("parseRetryAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_rateLimiter(t *testing.T) {
+	l := limiterFor("test-rate-limit", RateLimit{Rate: 2, Burst: 2})
+	if l != limiterFor("test-rate-limit", RateLimit{Rate: 2, Burst: 2}) {
+		t.Fatalf("expected the limiter to be shared by name")
+	}
+	now := time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)
+	waits := []time.Duration{
+		l.reserve(now),
+		l.reserve(now),
+		l.reserve(now),
+		l.reserve(now),
+		// One token is refilled after half a second, but two are already reserved
+		l.reserve(now.Add(500 * time.Millisecond)),
+	}
+	want := []time.Duration{0, 0, 500 * time.Millisecond, time.Second, time.Second}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Errorf("reserve() #%d = %v, want %v", i, waits[i], want[i])
+		}
+	}
+	l.pause(now.Add(time.Hour))
+	if wait := l.reserve(now.Add(time.Minute)); wait != 59*time.Minute {
+		t.Errorf("expected the pause to be respected, got %v", wait)
+	}
+}
+
+func TestDefinition_Retry(t *testing.T) {
+	def, err := ParseDefinition("test.yaml", []byte(`
+name: example
+baseUrl: https://example.com
+retry:
+  maxAttempts: 5
+  baseDelay: 1s
+rateLimit:
+  rate: 0.5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := def.NewGenAPI(GenAPIOptions{Logger: slog.Default()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Retry == nil || g.Retry.MaxAttempts != 5 || g.Retry.BaseDelay != time.Second {
+		t.Errorf("unexpected retry-policy %#v", g.Retry)
+	}
+	if g.RateLimit == nil || g.RateLimit.Rate != 0.5 {
+		t.Errorf("unexpected rate-limit %#v", g.RateLimit)
+	}
+	_, err = ParseDefinition("test.yaml", []byte(`
+name: example
+baseUrl: https://example.com
+rateLimit:
+  rate: 0
+`))
+	if !errors.Is(err, ErrInvalidDefinition) || !strings.Contains(err.Error(), "test.yaml:5:3: rateLimit: rate must be greater than 0") {
+		t.Errorf("expected an invalid rate-limit, got %v", err)
+	}
+}
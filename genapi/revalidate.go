@@ -0,0 +1,84 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The api responded with 304 Not Modified, but there is no cached response to use
+var ErrNotModifiedWithoutCache = errors.New("not modified, but there is no cached response")
+
+// setConditionalHeaders asks the api to respond with 304 Not Modified if the cached response is still valid.
+func (m cacheMeta) setConditionalHeaders(r *http.Request) {
+	if m.ETag != "" {
+		r.Header.Set("If-None-Match", m.ETag)
+	}
+	if m.LastModified != "" {
+		r.Header.Set("If-Modified-Since", m.LastModified)
+	}
+}
+
+// setValidators stores the validators from the response. A 304 may omit them, in which case the previous ones are kept.
+func (m *cacheMeta) setValidators(h http.Header) {
+	if etag := h.Get("ETag"); etag != "" {
+		m.ETag = etag
+	}
+	if lastModified := h.Get("Last-Modified"); lastModified != "" {
+		m.LastModified = lastModified
+	}
+}
+
+// GetRevalidated retrieves the url through the cache, for resources that are not mapped by an endpoint, like feeds.
+// A cached body younger than maxAge is used without a request. An older one is revalidated with a conditional request,
+// and replaced if it has changed. The response is nil if the cached body was used without a request.
+func (g *GenAPI) GetRevalidated(ctx context.Context, rawURL, cacheKey string, maxAge time.Duration) (*http.Response, []byte, error) {
+	if maxAge > 0 {
+		if cached, ok := g.retrieveCache(cacheKey, time.Now().Add(-maxAge)); ok && len(cached) > 0 {
+			g.responseKeys.Store(cacheKey, struct{}{})
+			return nil, cached, nil
+		}
+	}
+	stale, staleFound := g.getStaleCache(cacheKey)
+	staleFound = staleFound && len(stale) > 0
+	// The meta is overwritten if it exists, so that the validators of a replaced body are not used
+	meta, metaFound := g.getCacheMeta(cacheKey)
+	r, err := g.NewRequest(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if staleFound {
+		meta.setConditionalHeaders(r)
+	}
+	res, body, l, err := g.doRead(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case res.StatusCode == http.StatusNotModified:
+		if !staleFound {
+			return res, nil, fmt.Errorf("%w: %s", ErrNotModifiedWithoutCache, rawURL)
+		}
+		l.Debug("Not modified, refreshing cache")
+		body = stale
+		meta.setValidators(res.Header)
+	case res.StatusCode >= 400:
+		return res, nil, fmt.Errorf("unsuccessful status-code: %d", res.StatusCode)
+	default:
+		meta = cacheMeta{}
+		meta.setValidators(res.Header)
+	}
+	// Rewriting an unmodified body marks it as fresh
+	if _, err := g.writeCache(cacheKey, body); err != nil {
+		return res, body, err
+	}
+	g.responseKeys.Store(cacheKey, struct{}{})
+	if meta != (cacheMeta{}) || metaFound {
+		if _, err := g.writeCache(cacheMetaKey(cacheKey), meta); err != nil {
+			return res, body, err
+		}
+	}
+	return res, body, nil
+}
@@ -0,0 +1,252 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+func Test_cacheMeta_revalidation(t *testing.T) {
+	meta := cacheMeta{Next: "https://example.com/?page=2"}
+	meta.setValidators(http.Header{
+		"Etag":          {`W/"abc"`},
+		"Last-Modified": {"Mon, 01 Jul 2024 08:00:00 GMT"},
+	})
+	// A 304 without validators keeps the previous ones
+	meta.setValidators(http.Header{})
+	r, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	meta.setConditionalHeaders(r)
+	if got := r.Header.Get("If-None-Match"); got != `W/"abc"` {
+		t.Errorf("If-None-Match = %q", got)
+	}
+	if got := r.Header.Get("If-Modified-Since"); got != "Mon, 01 Jul 2024 08:00:00 GMT" {
+		t.Errorf("If-Modified-Since = %q", got)
+	}
+	if meta.Next != "https://example.com/?page=2" {
+		t.Errorf("expected Next to be kept, got %q", meta.Next)
+	}
+}
+
+type testTimedCache map[string]testTimedEntry
+
+type testTimedEntry struct {
+	value   []byte
+	written time.Time
+}
+
+func (c testTimedCache) Retrieve(keyPaths []string, changedAfter time.Time) ([]byte, bool, error) {
+	e, ok := c[strings.Join(keyPaths, "/")]
+	if !ok || e.written.Before(changedAfter) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (c testTimedCache) Write(keyPaths []string, value []byte) (string, error) {
+	key := strings.Join(keyPaths, "/")
+	c[key] = testTimedEntry{value, time.Now()}
+	return key, nil
+}
+
+func TestGenAPI_GetRevalidated(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	tests := []struct {
+		name        string
+		cache       testTimedCache
+		response    *http.Response
+		wantRequest bool
+		wantBody    string
+		wantETag    string
+		wantErr     error
+	}{
+		{
+			"Should use a fresh body without a request",
+			testTimedCache{"test/feed.xml": {[]byte("cached"), time.Now()}},
+			nil, false, "cached", "", nil,
+		},
+		{
+			"Should keep an expired body that is not modified",
+			testTimedCache{
+				"test/feed.xml":           {[]byte("cached"), old},
+				"test/feed.xml-meta.json": {[]byte(`{"etag": "\"v1\""}`), old},
+			},
+			testResponse(http.StatusNotModified, ""), true, "cached", `"v1"`, nil,
+		},
+		{
+			"Should replace an expired body that has changed",
+			testTimedCache{
+				"test/feed.xml":           {[]byte("cached"), old},
+				"test/feed.xml-meta.json": {[]byte(`{"etag": "\"v1\""}`), old},
+			},
+			&http.Response{StatusCode: 200, Header: http.Header{"Etag": {`"v2"`}}, Body: io.NopCloser(strings.NewReader("changed"))},
+			true, "changed", `"v1"`, nil,
+		},
+		{
+			"Should remove the validators of a replaced body without validators",
+			testTimedCache{
+				"test/feed.xml":           {[]byte("cached"), old},
+				"test/feed.xml-meta.json": {[]byte(`{"etag": "\"v1\""}`), old},
+			},
+			testResponse(200, "changed"), true, "changed", `"v1"`, nil,
+		},
+		{
+			"Should fetch a body that is not cached",
+			testTimedCache{},
+			testResponse(200, "new"), true, "new", "", nil,
+		},
+		{
+			"Should fail on a 304 without a cached body",
+			testTimedCache{},
+			testResponse(http.StatusNotModified, ""), true, "", "", ErrNotModifiedWithoutCache,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := false
+			g := &GenAPI{
+				Name:     "test",
+				Endpoint: Endpoint{URL: &url.URL{}},
+				GenAPIOptions: GenAPIOptions{
+					Logger: slog.Default(),
+					Cache:  tt.cache,
+					Client: testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+						requested = true
+						if got := r.Header.Get("If-None-Match"); got != tt.wantETag {
+							t.Errorf("expected If-None-Match %q, got %q", tt.wantETag, got)
+						}
+						return tt.response, nil
+					}),
+				},
+			}
+			_, body, err := g.GetRevalidated(context.Background(), "https://example.com/feed.xml", "feed.xml", time.Hour)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenAPI.GetRevalidated() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := tt.cache["test/feed.xml"]; ok {
+					t.Errorf("expected nothing to be cached")
+				}
+				return
+			}
+			if requested != tt.wantRequest {
+				t.Errorf("expected a request: %v", tt.wantRequest)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("expected the body %q, got %q", tt.wantBody, body)
+			}
+			if e := tt.cache["test/feed.xml"]; time.Since(e.written) > time.Minute {
+				t.Errorf("expected the cached body to be fresh")
+			}
+			if tt.response != nil && tt.response.StatusCode == 200 {
+				if meta, _ := g.getCacheMeta("feed.xml"); meta.ETag != tt.response.Header.Get("ETag") {
+					t.Errorf("expected the validators of the response to be cached, got %#v", meta)
+				}
+			}
+		})
+	}
+}
+
+func TestGenAPI_RunEndpoint_Revalidation(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	expired := func() testTimedCache {
+		return testTimedCache{
+			"test/episodes-podID=1.json":      {[]byte(`[{"id": "cached"}]`), old},
+			"test/episodes-podID=1-meta.json": {[]byte(`{"etag": "\"v1\"", "lastModified": "Mon, 01 Jul 2024 08:00:00 GMT"}`), old},
+		}
+	}
+	tests := []struct {
+		name     string
+		cache    testTimedCache
+		response *http.Response
+		wantETag string
+		wantGUID string
+		wantMeta string
+		wantErr  error
+	}{
+		{
+			"Should use the expired response when it is not modified",
+			expired(),
+			&http.Response{StatusCode: http.StatusNotModified, Header: http.Header{"Etag": {`"v1"`}}, Body: io.NopCloser(strings.NewReader(""))},
+			`"v1"`, "cached", `{"etag":"\"v1\"","lastModified":"Mon, 01 Jul 2024 08:00:00 GMT"}`, nil,
+		},
+		{
+			"Should replace the expired response when it has changed",
+			expired(),
+			&http.Response{StatusCode: 200, Header: http.Header{"Etag": {`"v2"`}}, Body: io.NopCloser(strings.NewReader(`[{"id": "changed"}]`))},
+			`"v1"`, "changed", `{"etag":"\"v2\""}`, nil,
+		},
+		{
+			"Should remove the validators of a replaced response without validators",
+			expired(),
+			testResponse(200, `[{"id": "changed"}]`),
+			`"v1"`, "changed", `{}`, nil,
+		},
+		{
+			"Should fail on a 304 without a cached response",
+			testTimedCache{},
+			testResponse(http.StatusNotModified, ""),
+			"", "", "", ErrNotModifiedWithoutCache,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			g := &GenAPI{
+				Name:      "test",
+				CacheTime: time.Hour,
+				Endpoint:  Endpoint{URL: &url.URL{Scheme: "https", Host: "example.com"}},
+				GenAPIOptions: GenAPIOptions{
+					Logger: slog.Default(),
+					Cache:  tt.cache,
+					Client: testHTTPFunc(func(r *http.Request) (*http.Response, error) {
+						requests++
+						if got := r.Header.Get("If-None-Match"); got != tt.wantETag {
+							t.Errorf("expected If-None-Match %q, got %q", tt.wantETag, got)
+						}
+						return tt.response, nil
+					}),
+				},
+			}
+			var items []rss.Item
+			endpoint := GenAPIEndpoint{Path: "episodes", Mapping: map[string]string{"GUID": "id"}}
+			_, _, err := g.RunEndpoint(context.Background(), endpoint, map[string]any{"podID": 1}, "episodes-", &items)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenAPI.RunEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != 1 {
+				t.Errorf("expected a single request, got %d", requests)
+			}
+			if err != nil {
+				if len(tt.cache) != 0 {
+					t.Errorf("expected nothing to be cached, got %v", tt.cache)
+				}
+				return
+			}
+			if len(items) != 1 || items[0].GUID != tt.wantGUID {
+				t.Errorf("expected the item %s, got %#v", tt.wantGUID, items)
+			}
+			if e := tt.cache["test/episodes-podID=1.json"]; time.Since(e.written) > time.Minute {
+				t.Errorf("expected the cached response to be fresh")
+			}
+			if got := string(tt.cache["test/episodes-podID=1-meta.json"].value); got != tt.wantMeta {
+				t.Errorf("expected the meta %s, got %s", tt.wantMeta, got)
+			}
+			// The refreshed response is used without a request
+			items = nil
+			if _, _, err := g.RunEndpoint(context.Background(), endpoint, map[string]any{"podID": 1}, "episodes-", &items); err != nil {
+				t.Fatal(err)
+			}
+			if requests != 1 || len(items) != 1 {
+				t.Errorf("expected the refreshed response to be used, got %d requests", requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package genapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+type (
+	SecretStore interface {
+		GetSecret(ctx context.Context, name string) (string, error)
+	}
+	// Resolves secrets from environment-variables.
+	// The secret-name is uppercased, and any dots or dashes are replaced with underscores,
+	// so that the secret "untold.token" is read from UNTOLD_TOKEN
+	EnvSecrets struct {
+		Prefix string
+	}
+	// Resolves secrets from the first store that has the secret
+	SecretStores []SecretStore
+)
+
+func (stores SecretStores) GetSecret(ctx context.Context, name string) (string, error) {
+	for _, s := range stores {
+		v, err := s.GetSecret(ctx, name)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		return v, err
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+func (e EnvSecrets) EnvName(name string) string {
+	name = strings.NewReplacer(".", "_", "-", "_").Replace(name)
+	return e.Prefix + strings.ToUpper(name)
+}
+
+func (e EnvSecrets) GetSecret(ctx context.Context, name string) (string, error) {
+	envName := e.EnvName(name)
+	v := os.Getenv(envName)
+	if v == "" {
+		return "", fmt.Errorf("%w: %s (env %s)", ErrSecretNotFound, name, envName)
+	}
+	return v, nil
+}
+
+func (g *GenAPI) templateFuncs() template.FuncMap {
+	funcs := sprig.FuncMap()
+	funcs["secret"] = func(name string) (string, error) {
+		if g.Secrets == nil {
+			return "", fmt.Errorf("%w: %s (no secret-store configured)", ErrSecretNotFound, name)
+		}
+		return g.Secrets.GetSecret(context.TODO(), name)
+	}
+	return funcs
+}
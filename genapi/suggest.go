@@ -0,0 +1,452 @@
+package genapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Suggestions with a lower confidence are not included
+const suggestMinConfidence = 0.3
+
+// Only the first items are inspected
+const suggestMaxItems = 50
+
+type (
+	// MappingSuggestion is a draft for RootMapping and Mapping, created from a sample response.
+	MappingSuggestion struct {
+		RootMapping string
+		// 0 to 1
+		RootConfidence float64
+		// Sorted by field
+		Fields []FieldSuggestion
+	}
+	FieldSuggestion struct {
+		// The mapping-key, like PubDate
+		Field string
+		// The mapping-value, like published |> date
+		Path string
+		// 0 to 1
+		Confidence float64
+		// Human-readable explanation, like "name matches, values are dates"
+		Reason string
+	}
+	// A field within rss.Item or rss.Channel that can be suggested
+	suggestTarget struct {
+		field string
+		// Normalized names, most likely first
+		names []string
+		shape valueShape
+	}
+	valueShape int
+	// The values found for a path across the inspected items
+	pathSample struct {
+		path   string
+		values []gjson.Result
+	}
+)
+
+const (
+	shapeText valueShape = iota
+	shapeLongText
+	shapeID
+	shapeDate
+	shapeDuration
+	shapeURL
+	shapeAudioURL
+	shapeImageURL
+)
+
+var itemTargets = []suggestTarget{
+	{"Title", []string{"title", "name", "headline", "episodetitle"}, shapeText},
+	{"Description", []string{"description", "desc", "summary", "content", "body", "synopsis", "about", "text"}, shapeLongText},
+	{"Subtitle", []string{"subtitle", "tagline", "shortdescription", "teaser"}, shapeText},
+	{"GUID", []string{"id", "guid", "uuid", "uid", "episodeid", "key", "slug"}, shapeID},
+	{"PubDate", []string{"published", "publishedat", "pubdate", "publicationdate", "releasedate", "releasedat", "airdate", "date", "createdat"}, shapeDate},
+	{"DurationInSeconds", []string{"duration", "durationseconds", "durationms", "length", "runtime"}, shapeDuration},
+	{"Enclosure.URL", []string{"soundurl", "audiourl", "audio", "mediaurl", "enclosure", "streamurl", "downloadurl", "file", "mp3"}, shapeAudioURL},
+	{"Link", []string{"link", "url", "permalink", "weburl", "shareurl", "href"}, shapeURL},
+	{"Image.URL", []string{"image", "imageurl", "cover", "artwork", "thumbnail", "picture", "poster", "img"}, shapeImageURL},
+}
+
+var channelTargets = []suggestTarget{
+	{"Title", []string{"title", "name", "headline", "podcastname"}, shapeText},
+	{"Description", []string{"description", "desc", "summary", "about", "content", "body"}, shapeLongText},
+	{"Subtitle", []string{"subtitle", "tagline", "shortdescription"}, shapeText},
+	{"_Meta.ID", []string{"id", "podcastid", "uuid", "uid", "key", "slug"}, shapeID},
+	{"Author", []string{"author", "producer", "publisher", "creator", "owner", "artist", "host"}, shapeText},
+	{"Language", []string{"language", "lang", "locale"}, shapeText},
+	{"Link.Href", []string{"link", "url", "website", "weburl", "shareurl", "href"}, shapeURL},
+	{"Image.URL", []string{"image", "imageurl", "cover", "artwork", "thumbnail", "picture", "logo"}, shapeImageURL},
+	{"_Meta.LastAired", []string{"lastepisodedate", "lastpublished", "latestepisodedate", "lastaired", "updatedat"}, shapeDate},
+	{"_Meta.Frequency", []string{"frequency", "schedule"}, shapeText},
+}
+
+var (
+	audioExtensions = map[string]bool{".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".wav": true, ".flac": true}
+	imageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".gif": true}
+	htmlTagRe       = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+	nonAlnum        = regexp.MustCompile(`[^a-z0-9]`)
+)
+
+// SuggestMapping inspects a sample json-response, and suggests a RootMapping and a Mapping for
+// channels or items, based on the names of the fields and the shape of their values.
+func SuggestMapping(data []byte, kind PreviewKind) (MappingSuggestion, error) {
+	var s MappingSuggestion
+	if !gjson.ValidBytes(data) {
+		return s, fmt.Errorf("the sample is not valid json")
+	}
+	targets := itemTargets
+	switch kind {
+	case "", PreviewItems:
+	case PreviewChannels:
+		targets = channelTargets
+	default:
+		return s, fmt.Errorf("unknown kind %q", kind)
+	}
+	root := gjson.ParseBytes(data)
+	candidates := arrayCandidates(root, "")
+	if len(candidates) == 0 {
+		return s, fmt.Errorf("found no arrays of objects in the sample")
+	}
+	best, bestScore := "", -1.0
+	var bestItems []gjson.Result
+	for p, items := range candidates {
+		score := scoreRoot(items, targets)
+		// Deterministic with equal scores
+		if score > bestScore || (score == bestScore && p < best) {
+			best, bestScore, bestItems = p, score, items
+		}
+	}
+	s.RootMapping = best
+	s.RootConfidence = math.Round(math.Min(1, bestScore)*100) / 100
+	s.Fields = suggestFields(bestItems, targets)
+	return s, nil
+}
+
+// Mapping returns the suggested fields as a Mapping
+func (s MappingSuggestion) Mapping() map[string]string {
+	m := make(map[string]string, len(s.Fields))
+	for _, f := range s.Fields {
+		m[f.Field] = f.Path
+	}
+	return m
+}
+
+// arrayCandidates finds every array of objects, by their gjson-path. The root is the empty path.
+func arrayCandidates(r gjson.Result, prefix string) map[string][]gjson.Result {
+	found := map[string][]gjson.Result{}
+	switch {
+	case r.IsArray():
+		arr := r.Array()
+		var objects []gjson.Result
+		for _, v := range arr {
+			if v.IsObject() {
+				objects = append(objects, v)
+			}
+		}
+		if len(objects) > 0 && len(objects)*2 >= len(arr) {
+			found[prefix] = objects
+		}
+	case r.IsObject():
+		r.ForEach(func(key, value gjson.Result) bool {
+			for p, items := range arrayCandidates(value, joinPath(prefix, escapeGJSONKey(key.String()))) {
+				found[p] = items
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// scoreRoot favours arrays with several, similar items, that have fields that look like the targets
+func scoreRoot(items []gjson.Result, targets []suggestTarget) float64 {
+	n := min(len(items), suggestMaxItems)
+	items = items[:n]
+	keyCount := map[string]int{}
+	for _, item := range items {
+		item.ForEach(func(key, _ gjson.Result) bool {
+			keyCount[key.String()]++
+			return true
+		})
+	}
+	if len(keyCount) == 0 {
+		return 0
+	}
+	shared := 0.0
+	for _, c := range keyCount {
+		shared += float64(c) / float64(n)
+	}
+	uniformity := shared / float64(len(keyCount))
+	matched := 0
+	for _, t := range targets {
+		for key := range keyCount {
+			if nameScore(key, t.names) >= 0.5 {
+				matched++
+				break
+			}
+		}
+	}
+	size := math.Min(1, math.Log2(float64(n)+1)/4)
+	return 0.2*size + 0.3*uniformity + 0.5*math.Min(1, float64(matched)/4)
+}
+
+func suggestFields(items []gjson.Result, targets []suggestTarget) []FieldSuggestion {
+	items = items[:min(len(items), suggestMaxItems)]
+	samples := map[string]*pathSample{}
+	var paths []string
+	for _, item := range items {
+		for _, p := range leafPaths(item, "") {
+			if strings.Contains(p, "#") {
+				continue
+			}
+			if samples[p] == nil {
+				samples[p] = &pathSample{path: p}
+				paths = append(paths, p)
+			}
+			samples[p].values = append(samples[p].values, item.Get(p))
+		}
+	}
+	sort.Strings(paths)
+	type candidate struct {
+		FieldSuggestion
+		score float64
+	}
+	var candidates []candidate
+	for _, t := range targets {
+		for _, p := range paths {
+			score, reason, mapping := scoreField(t, samples[p], len(items))
+			if score < suggestMinConfidence {
+				continue
+			}
+			candidates = append(candidates, candidate{FieldSuggestion{Field: t.field, Path: mapping, Confidence: math.Round(math.Min(1, score)*100) / 100, Reason: reason}, score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	// Every field, and every path, is only used once
+	usedFields := map[string]bool{}
+	usedPaths := map[string]bool{}
+	var out []FieldSuggestion
+	for _, c := range candidates {
+		if usedFields[c.Field] {
+			continue
+		}
+		source, _, _ := parseMapping(c.Path)
+		if usedPaths[source] {
+			continue
+		}
+		usedFields[c.Field] = true
+		usedPaths[source] = true
+		out = append(out, c.FieldSuggestion)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Field < out[j].Field
+	})
+	return out
+}
+
+func normalizeName(s string) string {
+	return nonAlnum.ReplaceAllString(strings.ToLower(s), "")
+}
+
+// nameScore is 1 for the most likely name, and lower for less likely names and partial matches
+func nameScore(key string, names []string) float64 {
+	k := normalizeName(key)
+	best := 0.0
+	for i, name := range names {
+		// Earlier names are more likely
+		weight := 1 - float64(i)*0.04
+		switch {
+		case k == name:
+			best = math.Max(best, weight)
+		case len(name) >= 4 && strings.Contains(k, name):
+			best = math.Max(best, 0.6*weight)
+		}
+	}
+	return best
+}
+
+// scoreField returns the score for mapping the path to the target, and the mapping-value including transforms.
+func scoreField(t suggestTarget, sample *pathSample, itemCount int) (float64, string, string) {
+	segments := strings.Split(sample.path, ".")
+	name := nameScore(segments[len(segments)-1], t.names)
+	reasons := []string{}
+	if name > 0 {
+		reasons = append(reasons, "name matches")
+	} else if len(segments) > 1 {
+		// Like cover.lg for images
+		if parent := nameScore(segments[len(segments)-2], t.names); parent > 0 {
+			name = 0.7 * parent
+			reasons = append(reasons, "parent matches")
+		}
+	}
+	var strs []string
+	for _, v := range sample.values {
+		if v.Type == gjson.String || v.Type == gjson.Number {
+			if s := strings.TrimSpace(v.String()); s != "" {
+				strs = append(strs, s)
+			}
+		}
+	}
+	if len(strs) == 0 {
+		return 0, "", ""
+	}
+	shape, mapping := shapeScore(t, sample.path, strs, itemCount)
+	if shape > 0 {
+		reasons = append(reasons, "values look right")
+	}
+	score := 0.6*name + 0.4*shape
+	// A value with the wrong shape is unlikely, even if the name matches
+	if shape < 0 {
+		score = 0.6*name + shape
+	}
+	return score, strings.Join(reasons, ", "), mapping
+}
+
+// shapeScore is between -1 and 1, where negative means the values do not fit the target
+func shapeScore(t suggestTarget, p string, values []string, itemCount int) (float64, string) {
+	frac := func(pred func(s string) bool) float64 {
+		n := 0
+		for _, v := range values {
+			if pred(v) {
+				n++
+			}
+		}
+		return float64(n) / float64(len(values))
+	}
+	mapping := p
+	switch t.shape {
+	case shapeText:
+		if frac(isURL) > 0.5 || frac(isNumber) > 0.5 {
+			return -0.5, mapping
+		}
+		return frac(func(s string) bool { return len(s) > 1 && len(s) < 200 }), mapping
+	case shapeLongText:
+		if frac(isURL) > 0.5 {
+			return -0.5, mapping
+		}
+		if frac(func(s string) bool { return htmlTagRe.MatchString(s) }) > 0.2 {
+			mapping += " " + PipelineSeparator + " stripHTML"
+		}
+		avg := 0
+		for _, v := range values {
+			avg += len(v)
+		}
+		avg /= len(values)
+		return math.Min(1, float64(avg)/120), mapping
+	case shapeID:
+		unique := map[string]bool{}
+		for _, v := range values {
+			unique[v] = true
+		}
+		if len(values) < itemCount || frac(func(s string) bool { return len(s) < 100 && !strings.Contains(s, " ") }) < 1 {
+			return -0.5, mapping
+		}
+		return float64(len(unique)) / float64(len(values)), mapping
+	case shapeDate:
+		dates := frac(func(s string) bool {
+			if isNumber(s) && len(s) < 9 {
+				return false
+			}
+			_, err := parseDate(s, "", time.UTC)
+			return err == nil
+		})
+		if dates < 0.5 {
+			return -1, mapping
+		}
+		if frac(func(s string) bool { _, err := time.Parse(time.RFC1123Z, s); return err == nil }) < 1 {
+			mapping += " " + PipelineSeparator + " date"
+		}
+		return dates, mapping
+	case shapeDuration:
+		durations := frac(func(s string) bool { _, err := parseDuration(s, time.Second); return err == nil })
+		if durations < 0.5 {
+			return -1, mapping
+		}
+		unit := ""
+		if strings.HasSuffix(normalizeName(p), "ms") || frac(func(s string) bool {
+			d, err := parseDuration(s, time.Second)
+			// More than 10 days is more likely milliseconds
+			return err == nil && isNumber(s) && d > 240*time.Hour
+		}) > 0.5 {
+			unit = " ms"
+		}
+		if unit != "" || frac(isNumber) < 1 {
+			mapping += " " + PipelineSeparator + " duration" + unit
+		}
+		return durations, mapping
+	case shapeURL:
+		urls := frac(isURL)
+		if urls < 0.5 {
+			return -1, mapping
+		}
+		// Links to files are more likely to be images or enclosures
+		if frac(func(s string) bool { ext := urlExt(s); return audioExtensions[ext] || imageExtensions[ext] }) > 0.5 {
+			return 0.3, mapping
+		}
+		return urls, mapping
+	case shapeAudioURL:
+		if frac(isURL) < 0.5 {
+			return -1, mapping
+		}
+		audio := frac(func(s string) bool {
+			return audioExtensions[urlExt(s)] || strings.Contains(strings.ToLower(s), "audio")
+		})
+		if audio == 0 && frac(func(s string) bool { return imageExtensions[urlExt(s)] }) > 0 {
+			return -1, mapping
+		}
+		return audio, mapping
+	case shapeImageURL:
+		if frac(isURL) < 0.5 {
+			return -1, mapping
+		}
+		images := frac(func(s string) bool { return imageExtensions[urlExt(s)] })
+		if images == 0 && frac(func(s string) bool { return audioExtensions[urlExt(s)] }) > 0 {
+			return -1, mapping
+		}
+		// Prefer the largest image, like cover.lg over cover.sm
+		last := normalizeName(p[strings.LastIndex(p, ".")+1:])
+		switch last {
+		case "lg", "large", "xl", "original", "full", "hires":
+			images += 0.2
+		case "sm", "small", "xs", "thumb", "thumbnail", "tiny":
+			images -= 0.2
+		}
+		return math.Min(1, images), mapping
+	}
+	return 0, mapping
+}
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func urlExt(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(path.Ext(u.Path))
+}
@@ -0,0 +1,102 @@
+package genapi
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestSuggestMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     PreviewKind
+		data     string
+		wantRoot string
+		want     map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "Nested episodes",
+			kind: PreviewItems,
+			data: `{"podcast": {"name": "Podcast", "tags": [{"id": 1}]}, "episodes": {"total": 2, "items": [
+				{"id": "e1", "title": "First", "description": "<p>The first episode, where everything starts and nothing is as it seems</p>", "published": "2024-06-01T08:00:00Z", "duration": 3600, "soundUrl": "https://cdn.example.com/e1.mp3", "cover": {"sm": "https://cdn.example.com/e1-sm.jpg", "lg": "https://cdn.example.com/e1-lg.jpg"}},
+				{"id": "e2", "title": "Second", "description": "<p>The second episode, where nothing starts and everything is as it seems</p>", "published": "2024-06-08T08:00:00Z", "duration": 1800, "soundUrl": "https://cdn.example.com/e2.mp3", "cover": {"sm": "https://cdn.example.com/e2-sm.jpg", "lg": "https://cdn.example.com/e2-lg.jpg"}}
+			]}}`,
+			wantRoot: "episodes.items",
+			want: map[string]string{
+				"Title":             "title",
+				"Description":       "description |> stripHTML",
+				"GUID":              "id",
+				"PubDate":           "published |> date",
+				"DurationInSeconds": "duration",
+				"Enclosure.URL":     "soundUrl",
+				"Image.URL":         "cover.lg",
+			},
+		},
+		{
+			name: "Root array with clock-durations",
+			kind: PreviewItems,
+			data: `[
+				{"guid": "a", "headline": "A", "releaseDate": "Mon, 01 Jul 2024 08:00:00 +0000", "length": "1:02:03", "audio": {"url": "https://example.com/a.m4a"}},
+				{"guid": "b", "headline": "B", "releaseDate": "Mon, 08 Jul 2024 08:00:00 +0000", "length": "45:00", "audio": {"url": "https://example.com/b.m4a"}}
+			]`,
+			wantRoot: "",
+			want: map[string]string{
+				"Title":             "headline",
+				"GUID":              "guid",
+				"PubDate":           "releaseDate",
+				"DurationInSeconds": "length |> duration",
+				"Enclosure.URL":     "audio.url",
+			},
+		},
+		{
+			name: "Channels",
+			kind: PreviewChannels,
+			data: `{"data": [
+				{"id": 1, "name": "Alpha", "author": "Jane", "image": "https://example.com/alpha.png", "lang": "no"},
+				{"id": 2, "name": "Beta", "author": "John", "image": "https://example.com/beta.png", "lang": "en"}
+			]}`,
+			wantRoot: "data",
+			want: map[string]string{
+				"_Meta.ID":  "id",
+				"Title":     "name",
+				"Author":    "author",
+				"Image.URL": "image",
+				"Language":  "lang",
+			},
+		},
+		{
+			name:    "No arrays",
+			data:    `{"title": "x"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid json",
+			data:    `{`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SuggestMapping([]byte(tt.data), tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SuggestMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.RootMapping != tt.wantRoot {
+				t.Errorf("SuggestMapping() root = %q, want %q", got.RootMapping, tt.wantRoot)
+			}
+			if diff := deep.Equal(got.Mapping(), tt.want); diff != nil {
+				t.Errorf("SuggestMapping() mapping %#v", got.Fields)
+				t.Error(diff)
+			}
+			for _, f := range got.Fields {
+				if f.Confidence < suggestMinConfidence || f.Confidence > 1 || f.Reason == "" {
+					t.Errorf("unexpected confidence or reason for %#v", f)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package genapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Mapping-values may be followed by a pipeline of transforms, separated by |>.
+// Arguments are separated by spaces, and may be quoted with "double quotes" (with go-escapes) or 'single quotes' (raw).
+//
+//	published |> date                          -> RFC 2822, like Mon, 01 Jul 2024 08:00:00 +0200
+//	published |> date "02.01.2006 15:04" Europe/Oslo
+//	length |> duration ms                      -> seconds, from milliseconds
+//	length |> duration                         -> seconds, from seconds, ISO 8601 (PT1H2M), 01:02:03 or 1h2m
+//	description |> stripHTML
+//	title |> extract '^Episode \d+: (.*)$'
+//	title |> replace '\s*\(rerun\)$' ''
+//	author |> default Unknown
+//	tags |> join ", "
+//	rating |> number 1
+const PipelineSeparator = "|>"
+
+type (
+	// A transform receives nil if the value was not found. Most transforms return nil for nil.
+	transform    func(v any) (any, error)
+	pipeline     []transform
+	transformDef struct {
+		minArgs, maxArgs int
+		create           func(args []string) (transform, error)
+	}
+	parsedMapping struct {
+		source   string
+		pipeline pipeline
+		err      error
+	}
+)
+
+// The layouts tried by date when no layout is given
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.ANSIC,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+var transforms = map[string]transformDef{
+	"date":      {0, 2, newDateTransform},
+	"duration":  {0, 1, newDurationTransform},
+	"stripHTML": {0, 0, func([]string) (transform, error) { return stringTransform(stripHTML), nil }},
+	"extract":   {1, 2, newExtractTransform},
+	"replace":   {2, 2, newReplaceTransform},
+	"default":   {1, 1, newDefaultTransform},
+	"join":      {0, 1, newJoinTransform},
+	"number":    {0, 1, newNumberTransform},
+}
+
+// parseMapping splits the mapping-value into the source-path and the pipeline.
+func parseMapping(value string) (string, pipeline, error) {
+	parts := splitOutsideQuotes(value, PipelineSeparator)
+	source := strings.TrimSpace(parts[0])
+	var p pipeline
+	for _, part := range parts[1:] {
+		t, err := parseTransform(part)
+		if err != nil {
+			return source, p, err
+		}
+		p = append(p, t)
+	}
+	return source, p, nil
+}
+
+// mapping is parseMapping, cached for the GenAPI, since its mappings are used for every item that is decoded.
+// The cache is bounded by the mappings of the endpoints, so mappings that come from callers, like previews, must not use it
+func (g *GenAPI) mapping(value string) (string, pipeline, error) {
+	if m, ok := g.mappings.Load(value); ok {
+		p := m.(parsedMapping)
+		return p.source, p.pipeline, p.err
+	}
+	source, pipeline, err := parseMapping(value)
+	g.mappings.Store(value, parsedMapping{source: source, pipeline: pipeline, err: err})
+	return source, pipeline, err
+}
+
+func parseTransform(s string) (transform, error) {
+	fields, err := splitArgs(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty transform in pipeline")
+	}
+	name, args := fields[0], fields[1:]
+	def, ok := transforms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %q", name)
+	}
+	if len(args) < def.minArgs || len(args) > def.maxArgs {
+		if def.minArgs == def.maxArgs {
+			return nil, fmt.Errorf("transform %s expects %d arguments, got %d", name, def.minArgs, len(args))
+		}
+		return nil, fmt.Errorf("transform %s expects %d to %d arguments, got %d", name, def.minArgs, def.maxArgs, len(args))
+	}
+	t, err := def.create(args)
+	if err != nil {
+		return nil, fmt.Errorf("transform %s: %w", name, err)
+	}
+	return t, nil
+}
+
+func (p pipeline) apply(v any) (any, error) {
+	var err error
+	for _, t := range p {
+		v, err = t(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// splitOutsideQuotes splits s by sep, unless sep is within quotes
+func splitOutsideQuotes(s, sep string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	s = strings.TrimSpace(s)
+	for s != "" {
+		var arg string
+		switch s[0] {
+		case '\'':
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in %s", s)
+			}
+			arg, s = s[1:end+1], s[end+2:]
+		case '"':
+			q, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted argument in %s: %w", s, err)
+			}
+			arg, _ = strconv.Unquote(q)
+			s = s[len(q):]
+		default:
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			arg, s = s[:end], s[end:]
+		}
+		args = append(args, arg)
+		s = strings.TrimLeft(s, " \t")
+	}
+	return args, nil
+}
+
+// toString converts resolved values to strings. Objects and arrays are returned as json.
+func toString(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// stringTransform creates a transform that skips missing and empty values
+func stringTransform(f func(s string) (string, error)) transform {
+	return func(v any) (any, error) {
+		s, ok := toString(v)
+		if !ok || s == "" {
+			return v, nil
+		}
+		return f(s)
+	}
+}
+
+func newDateTransform(args []string) (transform, error) {
+	loc := time.UTC
+	var layout string
+	if len(args) > 0 {
+		layout = args[0]
+	}
+	if len(args) > 1 {
+		var err error
+		loc, err = time.LoadLocation(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+	return stringTransform(func(s string) (string, error) {
+		t, err := parseDate(s, layout, loc)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(time.RFC1123Z), nil
+	}), nil
+}
+
+// parseDate parses s with the layout, or one of the common layouts if empty.
+// The layouts unix and unixms are for timestamps. Dates without a timezone are parsed in loc.
+func parseDate(s string, layout string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch layout {
+	case "unix", "unixms":
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+		}
+		if layout == "unixms" {
+			return time.UnixMilli(int64(n)).In(loc), nil
+		}
+		return time.Unix(int64(n), 0).In(loc), nil
+	case "":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			// Timestamps in milliseconds are too large to be seconds within a reasonable timespan
+			if n > 1e11 {
+				return time.UnixMilli(int64(n)).In(loc), nil
+			}
+			return time.Unix(int64(n), 0).In(loc), nil
+		}
+		for _, l := range dateLayouts {
+			if t, err := time.ParseInLocation(l, s, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q: %w", s, err)
+	}
+	return t, nil
+}
+
+func newDurationTransform(args []string) (transform, error) {
+	unit := time.Second
+	if len(args) > 0 {
+		switch args[0] {
+		case "s":
+		case "ms":
+			unit = time.Millisecond
+		default:
+			return nil, fmt.Errorf("unknown unit %q, expected s or ms", args[0])
+		}
+	}
+	return stringTransform(func(s string) (string, error) {
+		d, err := parseDuration(s, unit)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(int64(math.Round(d.Seconds())), 10), nil
+	}), nil
+}
+
+var isoDurationRe = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// parseDuration parses numbers in the unit, ISO 8601-durations like PT1H2M3S, clock-durations like 1:02:03 or 02:03,
+// and go-durations like 1h2m3s
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(n * float64(unit)), nil
+	}
+	if m := isoDurationRe.FindStringSubmatch(s); m != nil && s != "P" && s != "PT" {
+		var d time.Duration
+		for i, u := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+			if m[i+1] == "" {
+				continue
+			}
+			n, _ := strconv.ParseFloat(strings.Replace(m[i+1], ",", ".", 1), 64)
+			d += time.Duration(n * float64(u))
+		}
+		return d, nil
+	}
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		var seconds float64
+		for _, p := range parts {
+			n, err := strconv.ParseFloat(p, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			seconds = seconds*60 + n
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// Elements that separate the text into lines
+var htmlBlockElements = map[string]bool{"br": true, "p": true, "div": true, "li": true, "tr": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true}
+
+// stripHTML returns the text of the html, with entities decoded. Block-elements become line-breaks.
+func stripHTML(s string) (string, error) {
+	z := html.NewTokenizer(strings.NewReader(s))
+	var buf bytes.Buffer
+	skip := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return "", z.Err()
+			}
+			var lines []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if line = strings.Join(strings.Fields(line), " "); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return strings.Join(lines, "\n"), nil
+		case html.TextToken:
+			if skip == 0 {
+				buf.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style":
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			}
+			if htmlBlockElements[string(name)] {
+				buf.WriteByte('\n')
+			}
+		}
+	}
+}
+
+func newExtractTransform(args []string) (transform, error) {
+	re, err := regexp.Compile(args[0])
+	if err != nil {
+		return nil, err
+	}
+	group := 0
+	if re.NumSubexp() > 0 {
+		group = 1
+	}
+	if len(args) > 1 {
+		group, err = strconv.Atoi(args[1])
+		if err != nil || group < 0 || group > re.NumSubexp() {
+			return nil, fmt.Errorf("invalid group %q, the expression has %d groups", args[1], re.NumSubexp())
+		}
+	}
+	return func(v any) (any, error) {
+		s, ok := toString(v)
+		if !ok {
+			return nil, nil
+		}
+		m := re.FindStringSubmatch(s)
+		if m == nil {
+			return nil, nil
+		}
+		return m[group], nil
+	}, nil
+}
+
+func newReplaceTransform(args []string) (transform, error) {
+	re, err := regexp.Compile(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return stringTransform(func(s string) (string, error) {
+		return re.ReplaceAllString(s, args[1]), nil
+	}), nil
+}
+
+func newDefaultTransform(args []string) (transform, error) {
+	return func(v any) (any, error) {
+		if s, ok := toString(v); !ok || s == "" {
+			return args[0], nil
+		}
+		return v, nil
+	}, nil
+}
+
+func newJoinTransform(args []string) (transform, error) {
+	sep := ", "
+	if len(args) > 0 {
+		sep = args[0]
+	}
+	return func(v any) (any, error) {
+		list, ok := v.([]any)
+		if !ok {
+			return v, nil
+		}
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := toString(item); ok && s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, sep), nil
+	}, nil
+}
+
+func newNumberTransform(args []string) (transform, error) {
+	decimals := 0
+	if len(args) > 0 {
+		var err error
+		decimals, err = strconv.Atoi(args[0])
+		if err != nil || decimals < 0 {
+			return nil, fmt.Errorf("invalid number of decimals %q", args[0])
+		}
+	}
+	return stringTransform(func(s string) (string, error) {
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid number %q", s)
+		}
+		return strconv.FormatFloat(n, 'f', decimals, 64), nil
+	}), nil
+}
@@ -0,0 +1,116 @@
+package genapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/runar-rkmedia/audio-mirror/rss"
+)
+
+func Test_parseMapping(t *testing.T) {
+	tests := []struct {
+		mapping    string
+		input      any
+		want       any
+		wantSource string
+		wantErr    bool
+	}{
+		{"published |> date", "2024-07-01T08:00:00+02:00", "Mon, 01 Jul 2024 08:00:00 +0200", "published", false},
+		{"published |> date", "1719813600", "Mon, 01 Jul 2024 06:00:00 +0000", "published", false},
+		{"published |> date", "Mon, 1 Jul 2024 08:00:00 GMT", "Mon, 01 Jul 2024 08:00:00 +0000", "published", false},
+		{`published |> date "02.01.2006 15:04" Europe/Oslo`, "01.07.2024 08:00", "Mon, 01 Jul 2024 08:00:00 +0200", "published", false},
+		{"published |> date unixms", "1719813600000", "Mon, 01 Jul 2024 06:00:00 +0000", "published", false},
+		{"published |> date", "yesterday", nil, "published", true},
+		{"published |> date 2006 Not/AZone", nil, nil, "published", true},
+		{"length |> duration", "3723", "3723", "length", false},
+		{"length |> duration", "3723.6", "3724", "length", false},
+		{"length |> duration ms", "3723000", "3723", "length", false},
+		{"length |> duration", "PT1H2M3S", "3723", "length", false},
+		{"length |> duration", "P1DT1S", "86401", "length", false},
+		{"length |> duration", "1:02:03", "3723", "length", false},
+		{"length |> duration", "62:03", "3723", "length", false},
+		{"length |> duration", "1h2m3s", "3723", "length", false},
+		{"length |> duration", "long", nil, "length", true},
+		{"length |> duration hours", nil, nil, "length", true},
+		{"d |> stripHTML", "<p>Hello &amp; <b>welcome</b></p><p>Bye<br/>now</p><script>x()</script>", "Hello & welcome\nBye\nnow", "d", false},
+		{`t |> extract '^Episode \d+: (.*)$'`, "Episode 12: The title", "The title", "t", false},
+		{`t |> extract '\d+'`, "Episode 12: The title", "12", "t", false},
+		{`t |> extract '(\d+)'`, "no number", nil, "t", false},
+		{`t |> extract '(' `, nil, nil, "t", true},
+		{`t |> replace '\s*\(rerun\)$' ''`, "Title (rerun)", "Title", "t", false},
+		{"a |> default Unknown", nil, "Unknown", "a", false},
+		{"a |> default Unknown", "Someone", "Someone", "a", false},
+		{`tags |> join " | "`, []any{"a", "b", 3.0}, "a | b | 3", "tags", false},
+		{`tags |> join`, []any{"a", "", "b"}, "a, b", "tags", false},
+		{"rating |> number 1", "4.25", "4.2", "rating", false},
+		{"rating |> number", "4.5", "4", "rating", false},
+		{"rating |> number", "many", nil, "rating", true},
+		{`d |> stripHTML |> extract '^(\w+)' |> default none`, "<b>Hello</b> world", "Hello", "d", false},
+		{`d |> stripHTML |> extract '^(\d+)' |> default none`, "<b>Hello</b> world", "none", "d", false},
+		{"title|@text |> default x", nil, "x", "title|@text", false},
+		{"a |> nope", nil, nil, "a", true},
+		{"a |> ", nil, nil, "a", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mapping, func(t *testing.T) {
+			source, p, err := parseMapping(tt.mapping)
+			if source != tt.wantSource {
+				t.Errorf("parseMapping() source = %q, want %q", source, tt.wantSource)
+			}
+			var got any
+			if err == nil {
+				got, err = p.apply(tt.input)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := deep.Equal(got, tt.want); len(diff) != 0 {
+				t.Errorf("not equal %v", diff)
+			}
+		})
+	}
+}
+
+func TestGenAPI_mapping(t *testing.T) {
+	g := &GenAPI{}
+	for range 2 {
+		source, p, err := g.mapping("published |> date |> nope")
+		if source != "published" || len(p) != 1 || err == nil {
+			t.Errorf("expected the source, the valid transforms and the error, got %q %d %v", source, len(p), err)
+		}
+	}
+	n := 0
+	g.mappings.Range(func(_, _ any) bool { n++; return true })
+	if n != 1 {
+		t.Errorf("expected the mapping to be cached once, got %d", n)
+	}
+}
+
+func TestGenAPI_DecodeEndpointData_Transforms(t *testing.T) {
+	endpoint := GenAPIEndpoint{
+		Mapping: map[string]string{
+			"Title":             `title |> replace '^\d+\. ' ''`,
+			"Description":       "description |> stripHTML",
+			"DurationInSeconds": "duration |> duration",
+			"PubDate":           "published |> date",
+			"Subtitle":          "subtitle |> default None",
+		},
+	}
+	data := `[{"title": "1. First", "description": "<p>A <i>nice</i> one</p>", "duration": 2400, "published": "2024-07-01T08:00:00Z"}]`
+	want := []rss.Item{{
+		Title:             "First",
+		Description:       "A nice one",
+		DurationInSeconds: "2400",
+		PubDate:           "Mon, 01 Jul 2024 08:00:00 +0000",
+		Subtitle:          "None",
+	}}
+	g := &GenAPI{}
+	var got []rss.Item
+	if err := g.DecodeEndpointData(context.TODO(), endpoint, "", []byte(data), &got); err != nil {
+		t.Fatalf("GenAPI.DecodeEndpointData() error = %v", err)
+	}
+	if diff := deep.Equal(want, got); len(diff) != 0 {
+		t.Fatalf("not equal %v", diff)
+	}
+}
@@ -0,0 +1,214 @@
+package genapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/runar-rkmedia/audio-mirror/rss"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	DataTypeJSON = "json"
+	DataTypeXML  = "xml"
+	// Same as xml, but with defaults for RootMapping and Mapping that fits any podcast-feed.
+	DataTypeRSS = "rss"
+)
+
+var (
+	// Used for the rss-datatype when decoding items, if the endpoint does not declare a Mapping
+	RSSItemMapping = map[string]string{
+		"Title":                   "title|@text",
+		"Description":             "description|@text",
+		"Summary":                 "itunes:summary|@text",
+		"Subtitle":                "itunes:subtitle|@text",
+		"GUID":                    "guid|@text",
+		"DurationInSeconds":       "itunes:duration|@text",
+		"PubDate":                 "pubDate|@text",
+		"Link":                    "link|@text",
+		"Enclosure.URL":           "enclosure._url",
+		"Enclosure.Type":          "enclosure._type",
+		"Enclosure.LengthInBytes": "enclosure._length",
+		"Image.Href":              "itunes:image._href",
+		"ItunesTitle":             "itunes:title|@text",
+		"Episode":                 "itunes:episode|@text",
+		"Season":                  "itunes:season|@text",
+		"EpisodeType":             "itunes:episodeType|@text",
+		"Explicit":                "itunes:explicit|@text",
+		"Block":                   "itunes:block|@text",
+		"Author":                  "itunes:author|@text",
+	}
+	// Used for the rss-datatype when decoding channels, if the endpoint does not declare a Mapping
+	RSSChannelMapping = map[string]string{
+		"Title":       "title|@text",
+		"Description": "description|@text",
+		"Link.Href":   "link|@text",
+		"Language":    "language|@text",
+		"Explicit":    "itunes:explicit|@text",
+		"Image.URL":   "image.url|@text",
+		"Image.Href":  "itunes:image._href",
+		"Locked":      "podcast:locked|@text",
+		"GUID":        "podcast:guid|@text",
+		"Author":      "itunes:author|@text",
+		"Copyright":   "copyright|@text",
+		"Type":        "itunes:type|@text",
+		"Complete":    "itunes:complete|@text",
+		"Summary":     "itunes:summary|@text",
+		"Subtitle":    "itunes:subtitle|@text",
+		"PubDate":     "pubDate|@text",
+		"ItunesTitle": "itunes:title|@text",
+		"NewFeedURL":  "itunes:new-feed-url|@text",
+		"Block":       "itunes:block|@text",
+		"Keywords":    "itunes:keywords|@text",
+		"Owner.Name":  "itunes:owner.itunes:name|@text",
+		"Owner.Email": "itunes:owner.itunes:email|@text",
+	}
+	channelTypes = []reflect.Type{reflect.TypeOf(GenApiChannel{}), reflect.TypeOf(rss.Channel{})}
+)
+
+func init() {
+	// Returns the text-content of an xml-element, regardless of whether it has attributes or not.
+	gjson.AddModifier("text", func(jsonStr, arg string) string {
+		r := gjson.Parse(jsonStr)
+		if r.IsObject() {
+			return r.Get("_text").Raw
+		}
+		return jsonStr
+	})
+}
+
+// rssDefaults sets the RootMapping and Mapping for a podcast-feed, unless they are already set.
+// Channels are used as the root if out is a slice of channels, otherwise the items are used.
+func rssDefaults(endpoint GenAPIEndpoint, out any) GenAPIEndpoint {
+	isChannel := false
+	t := reflect.TypeOf(out)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	for _, ct := range channelTypes {
+		if t == ct {
+			isChannel = true
+		}
+	}
+	if endpoint.RootMapping == "" {
+		if isChannel {
+			endpoint.RootMapping = "rss.channel"
+		} else {
+			endpoint.RootMapping = "rss.channel.item"
+		}
+	}
+	if len(endpoint.Mapping) == 0 {
+		if isChannel {
+			endpoint.Mapping = RSSChannelMapping
+		} else {
+			endpoint.Mapping = RSSItemMapping
+		}
+	}
+	return endpoint
+}
+
+type xmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	text     strings.Builder
+	children []*xmlElement
+}
+
+// XMLToJSON converts an xml-document into json, so that it can be queried with gjson-paths.
+//
+//   - Element-names keep their namespace-prefix, like itunes:duration
+//   - Attributes are prefixed with an underscore, like enclosure._url
+//   - Elements without attributes or children become strings
+//   - The text of elements with attributes or children is stored in _text. Use the modifier @text
+//     to get the text regardless, like guid|@text
+//   - Repeated elements become arrays
+func XMLToJSON(r io.Reader) ([]byte, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "utf-8", "utf8", "us-ascii", "ascii":
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+	root := &xmlElement{}
+	stack := []*xmlElement{root}
+	for {
+		// RawToken is used to keep the namespace-prefixes as they are written in the document
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse xml: %w", err)
+		}
+		current := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{name: xmlName(t.Name)}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				el.attrs = append(el.attrs, attr)
+			}
+			current.children = append(current.children, el)
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			current.text.Write(t)
+		}
+	}
+	return json.Marshal(root.childMap())
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+func (e *xmlElement) childMap() map[string]any {
+	m := map[string]any{}
+	for _, child := range e.children {
+		v := child.value()
+		existing, ok := m[child.name]
+		switch {
+		case !ok:
+			m[child.name] = v
+		default:
+			if list, isList := existing.([]any); isList {
+				m[child.name] = append(list, v)
+			} else {
+				m[child.name] = []any{existing, v}
+			}
+		}
+	}
+	return m
+}
+
+func (e *xmlElement) value() any {
+	text := strings.TrimSpace(e.text.String())
+	if len(e.attrs) == 0 && len(e.children) == 0 {
+		return text
+	}
+	m := e.childMap()
+	for _, attr := range e.attrs {
+		m["_"+xmlName(attr.Name)] = attr.Value
+	}
+	if text != "" {
+		m["_text"] = text
+	}
+	return m
+}
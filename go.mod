@@ -4,6 +4,8 @@ go 1.22.5
 
 require (
 	connectrpc.com/connect v1.16.2
+	github.com/PuerkitoBio/goquery v1.9.2
+	github.com/andybalholm/cascadia v1.3.2
 	github.com/Masterminds/sprig/v3 v3.2.3
 	github.com/go-test/deep v1.1.1
 	github.com/kennygrant/sanitize v1.2.4
@@ -15,7 +17,9 @@ require (
 	github.com/uptrace/bun/driver/sqliteshim v1.2.1
 	github.com/uptrace/bun/extra/bundebug v1.2.1
 	golang.org/x/net v0.27.0
+	golang.org/x/text v0.16.0
 	google.golang.org/protobuf v1.34.2
+	gopkg.in/yaml.v3 v3.0.1
 	hypera.dev/lib v0.0.0-20240408124544-039c39c79498
 )
 
@@ -45,7 +49,6 @@ require (
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.25.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/text v0.16.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	modernc.org/gc/v3 v3.0.0-20240304020402-f0dba7c97c2b // indirect
 	modernc.org/libc v1.49.0 // indirect
@@ -0,0 +1,483 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity of a Finding
+type Severity string
+
+const (
+	// The feed, or the episode, is rejected
+	SeverityError Severity = "error"
+	// The feed is accepted, but may be shown wrongly, or rejected in the future
+	SeverityWarning Severity = "warning"
+)
+
+// Spec is a specification that a feed can be checked against
+type Spec string
+
+const (
+	SpecRSS     Spec = "rss"
+	SpecApple   Spec = "apple"
+	SpecSpotify Spec = "spotify"
+	// The podcast-namespace, Podcasting 2.0
+	SpecPodcast Spec = "podcast"
+)
+
+// Every Spec, in the order they are reported
+var Specs = []Spec{SpecRSS, SpecApple, SpecSpotify, SpecPodcast}
+
+// References to the sections of the specifications
+const (
+	RefRSS             = "https://cyber.harvard.edu/rss/rss.html"
+	RefRFC2822         = "https://www.rfc-editor.org/rfc/rfc2822#section-3.3"
+	RefApple           = "https://podcasters.apple.com/support/823-podcast-requirements"
+	RefAppleArtwork    = "https://podcasters.apple.com/support/896-artwork-requirements"
+	RefAppleCategories = "https://podcasters.apple.com/support/1691-apple-podcasts-categories"
+	RefAppleAudio      = "https://podcasters.apple.com/support/893-audio-requirements"
+	RefSpotify         = "https://providersupport.spotify.com/article/podcast-delivery-specification-1-9"
+	RefPodcast         = "https://github.com/Podcast-Standards-Project/PSP-1-Podcast-RSS-Specification"
+)
+
+// The maximum length of descriptions, in bytes
+const maxDescriptionLength = 4000
+
+var (
+	appleEnclosureTypes   = []string{"audio/mpeg", "audio/x-m4a", "video/mp4", "video/quicktime", "video/x-m4v", "application/pdf"}
+	spotifyEnclosureTypes = []string{"audio/mpeg", "audio/x-m4a", "audio/mp4", "audio/aac"}
+	languagePattern       = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	durationPattern       = regexp.MustCompile(`^(\d+|\d{1,2}:\d{1,2}|\d+:\d{1,2}:\d{1,2})(\.\d+)?$`)
+	// The layouts of RFC 2822, with and without the optional weekday and seconds
+	rfc2822Layouts = []string{
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"Mon, 2 Jan 2006 15:04 -0700",
+		"Mon, 2 Jan 2006 15:04 MST",
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05 MST",
+		"2 Jan 2006 15:04 -0700",
+		"2 Jan 2006 15:04 MST",
+	}
+)
+
+type (
+	// Finding is a rule of a specification that the feed does not follow
+	Finding struct {
+		Severity Severity
+		Spec     Spec
+		// The path to the field, like Item[2].Enclosure.Type. Empty for the whole channel
+		Path string
+		// The id of the rule, like enclosure-type
+		Rule    string
+		Message string
+		// Link to the specification of the rule
+		Reference string
+	}
+	// Report is the result of Check, with the findings in the order of the fields of the feed
+	Report struct {
+		Findings []Finding
+	}
+	CheckOptions struct {
+		// The specifications to check against. Defaults to every Spec
+		Specs []Spec
+		// Measures the artwork. The dimensions of the artwork are not checked without it
+		ImageProber ImageProber
+		// Limits the number of distinct images that are probed, the rest are not checked. No limit if zero
+		MaxImageProbes int
+	}
+	ImageInfo struct {
+		Width, Height int
+		// Like jpeg or png
+		Format string
+		CMYK   bool
+	}
+	// ImageProber measures the image at the url
+	ImageProber interface {
+		ProbeImage(ctx context.Context, imageURL string) (ImageInfo, error)
+	}
+	// HTTPImageProber measures images by decoding the start of the file
+	HTTPImageProber struct {
+		Client interface {
+			Do(r *http.Request) (*http.Response, error)
+		}
+	}
+	checker struct {
+		ctx    context.Context
+		opts   CheckOptions
+		report Report
+		// Probed images by url, since episodes often share the artwork of the channel
+		images map[string]imageResult
+	}
+	imageResult struct {
+		info ImageInfo
+		err  error
+	}
+)
+
+// Check reports how the channel and its items follow the RSS-specification, the requirements of Apple Podcasts and
+// Spotify, and the podcast-namespace. Unlike Validate, it checks every item, and explains every rule that is broken.
+func Check(ctx context.Context, c Channel, opts CheckOptions) Report {
+	if len(opts.Specs) == 0 {
+		opts.Specs = Specs
+	}
+	ch := &checker{ctx: ctx, opts: opts, images: map[string]imageResult{}}
+	ch.channel(c)
+	return ch.report
+}
+
+// Count returns the number of findings with the severity
+func (r Report) Count(severity Severity) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Err joins the findings with SeverityError, or returns nil if there are none
+func (r Report) Err() error {
+	var errs []error
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			errs = append(errs, errors.New(f.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f Finding) String() string {
+	path := f.Path
+	if path == "" {
+		path = "Channel"
+	}
+	return fmt.Sprintf("%s [%s/%s] %s: %s", f.Severity, f.Spec, f.Rule, path, f.Message)
+}
+
+func (p HTTPImageProber) ProbeImage(ctx context.Context, imageURL string) (ImageInfo, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	res, err := p.Client.Do(r)
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("failed to retrieve the image: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return ImageInfo{}, fmt.Errorf("failed to retrieve the image: status-code %d", res.StatusCode)
+	}
+	// The dimensions are in the header, which is within the first few kilobytes, unless there is a large thumbnail
+	cfg, format, err := image.DecodeConfig(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("failed to decode the image: %w", err)
+	}
+	return ImageInfo{Width: cfg.Width, Height: cfg.Height, Format: format, CMYK: cfg.ColorModel == color.CMYKModel}, nil
+}
+
+// add records the finding, if the spec is checked
+func (ch *checker) add(severity Severity, spec Spec, ref, rule, path, format string, args ...any) {
+	if !slices.Contains(ch.opts.Specs, spec) {
+		return
+	}
+	ch.report.Findings = append(ch.report.Findings, Finding{
+		Severity:  severity,
+		Spec:      spec,
+		Path:      path,
+		Rule:      rule,
+		Message:   fmt.Sprintf(format, args...),
+		Reference: ref,
+	})
+}
+
+func (ch *checker) channel(c Channel) {
+	if c.Title == "" {
+		ch.add(SeverityError, SpecRSS, RefRSS, "required", "Title", "the channel must have a title")
+		ch.add(SeverityError, SpecApple, RefApple, "required", "Title", "the channel must have a title")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", "Title", "the channel must have a title")
+	}
+	if c.Link.Href == "" {
+		ch.add(SeverityError, SpecRSS, RefRSS, "required", "Link", "the channel must have a link to its website")
+	}
+	ch.description("Description", c.Description, true)
+	switch {
+	case c.Language == "":
+		ch.add(SeverityError, SpecApple, RefApple, "required", "Language", "the channel must have a language")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", "Language", "the channel must have a language")
+	case !languagePattern.MatchString(c.Language):
+		ch.add(SeverityError, SpecApple, RefApple, "language", "Language", "the language must be an ISO 639 code, like en or nb-NO, was %q", c.Language)
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "language", "Language", "the language must be an ISO 639 code, like en or nb-NO, was %q", c.Language)
+	}
+	ch.explicit("Explicit", c.Explicit, true)
+	ch.categories(c.Category)
+	if c.Image.URL != "" && (c.Image.Title == "" || c.Image.Link == "") {
+		ch.add(SeverityWarning, SpecRSS, RefRSS, "image", "Image", "the image must have a url, title and link")
+	}
+	artwork := ""
+	if c.ItunesImage != nil {
+		artwork = c.ItunesImage.Href
+	}
+	switch {
+	case artwork != "":
+		ch.artwork("ItunesImage", artwork)
+	case c.Image.URL != "":
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "required", "ItunesImage", "the channel must have artwork in itunes:image, the rss-image is not used")
+		ch.artwork("Image.URL", c.Image.URL)
+	default:
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "required", "ItunesImage", "the channel must have artwork")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", "ItunesImage", "the channel must have artwork")
+	}
+	if c.Author == "" {
+		ch.add(SeverityWarning, SpecApple, RefApple, "recommended", "Author", "the channel should have an author")
+		ch.add(SeverityWarning, SpecSpotify, RefSpotify, "recommended", "Author", "the channel should have an author")
+	}
+	if c.Owner == nil || c.Owner.Email == "" {
+		ch.add(SeverityWarning, SpecApple, RefApple, "recommended", "Owner.Email", "the channel should have the email of its owner, which is used to verify ownership")
+		ch.add(SeverityWarning, SpecSpotify, RefSpotify, "recommended", "Owner.Email", "the channel should have the email of its owner, which is used to verify ownership")
+	}
+	// Keywords are compared without case, since Check is also used on feeds that are parsed with ParseOptions.Raw
+	if err := oneOf("Type", strings.ToLower(c.Type), "", "episodic", "serial"); err != nil {
+		ch.add(SeverityError, SpecApple, RefApple, "type", "Type", "the type must be episodic or serial, was %q", c.Type)
+	}
+	if err := oneOf("Complete", strings.ToLower(c.Complete), "", "yes"); err != nil {
+		ch.add(SeverityWarning, SpecApple, RefApple, "complete", "Complete", "the only value of complete is yes, was %q", c.Complete)
+	}
+	ch.date("PubDate", c.PubDate, false)
+	ch.date("LastBuildDate", c.LastBuildDate, false)
+	if err := c.validatePodcast(); err != nil {
+		ch.podcast("", err)
+	}
+	if len(c.Item) == 0 {
+		ch.add(SeverityError, SpecApple, RefApple, "items", "Item", "the channel must have at least one episode")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "items", "Item", "the channel must have at least one episode")
+	}
+	guids := map[string]int{}
+	for i, item := range c.Item {
+		path := fmt.Sprintf("Item[%d]", i)
+		ch.item(path, item, strings.EqualFold(c.Type, "serial"))
+		if item.GUID == "" {
+			continue
+		}
+		if first, ok := guids[item.GUID]; ok {
+			ch.add(SeverityError, SpecApple, RefApple, "guid-unique", path+".GUID", "the guid %q is also used by Item[%d]", item.GUID, first)
+			ch.add(SeverityError, SpecSpotify, RefSpotify, "guid-unique", path+".GUID", "the guid %q is also used by Item[%d]", item.GUID, first)
+			continue
+		}
+		guids[item.GUID] = i
+	}
+}
+
+func (ch *checker) item(path string, item Item, serial bool) {
+	if item.Title == "" {
+		if item.Description == "" {
+			ch.add(SeverityError, SpecRSS, RefRSS, "required", path+".Title", "the item must have a title or a description")
+		}
+		ch.add(SeverityError, SpecApple, RefApple, "required", path+".Title", "the episode must have a title")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", path+".Title", "the episode must have a title")
+	}
+	ch.description(path+".Description", item.Description, false)
+	ch.enclosure(path+".Enclosure", item.Enclosure)
+	if item.GUID == "" {
+		ch.add(SeverityWarning, SpecApple, RefApple, "recommended", path+".GUID", "the episode should have a guid, or it may be duplicated when the feed changes")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", path+".GUID", "the episode must have a guid")
+	}
+	if item.PubDate == "" {
+		ch.add(SeverityWarning, SpecApple, RefApple, "recommended", path+".PubDate", "the episode should have a publication-date")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", path+".PubDate", "the episode must have a publication-date")
+	}
+	ch.date(path+".PubDate", item.PubDate, true)
+	if d := item.DurationInSeconds; d != "" && !durationPattern.MatchString(d) {
+		ch.add(SeverityWarning, SpecApple, RefApple, "duration", path+".DurationInSeconds", "the duration must be seconds, or HH:MM:SS, was %q", d)
+	}
+	ch.explicit(path+".Explicit", item.Explicit, false)
+	if err := positiveInt("Episode", item.Episode); err != nil {
+		ch.add(SeverityError, SpecApple, RefApple, "episode-number", path+".Episode", "the episode-number must be a positive integer, was %q", item.Episode)
+	}
+	episodeType := strings.ToLower(item.EpisodeType)
+	if serial && item.Episode == "" && episodeType != "trailer" && episodeType != "bonus" {
+		ch.add(SeverityWarning, SpecApple, RefApple, "episode-number", path+".Episode", "episodes of serial channels should be numbered")
+	}
+	if err := positiveInt("Season", item.Season); err != nil {
+		ch.add(SeverityError, SpecApple, RefApple, "season-number", path+".Season", "the season-number must be a positive integer, was %q", item.Season)
+	}
+	if err := oneOf("EpisodeType", episodeType, "", "full", "trailer", "bonus"); err != nil {
+		ch.add(SeverityError, SpecApple, RefApple, "episode-type", path+".EpisodeType", "the episode-type must be full, trailer or bonus, was %q", item.EpisodeType)
+	}
+	if item.ItunesImage != nil && item.ItunesImage.Href != "" {
+		ch.artwork(path+".ItunesImage", item.ItunesImage.Href)
+	}
+	if err := item.validatePodcast(); err != nil {
+		ch.podcast(path, err)
+	}
+}
+
+func (ch *checker) description(path, description string, required bool) {
+	switch {
+	case description == "" && required:
+		ch.add(SeverityError, SpecRSS, RefRSS, "required", path, "the channel must have a description")
+		ch.add(SeverityError, SpecApple, RefApple, "required", path, "the channel must have a description")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", path, "the channel must have a description")
+	case len(description) > maxDescriptionLength:
+		ch.add(SeverityError, SpecApple, RefApple, "description-length", path, "the description must be at most %d bytes, was %d", maxDescriptionLength, len(description))
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "description-length", path, "the description must be at most %d bytes, was %d", maxDescriptionLength, len(description))
+	}
+}
+
+func (ch *checker) explicit(path, explicit string, required bool) {
+	switch strings.ToLower(explicit) {
+	case "true", "false":
+	case "":
+		if required {
+			ch.add(SeverityError, SpecApple, RefApple, "required", path, "the channel must be marked as explicit or not")
+		}
+	case "yes", "no", "clean", "explicit":
+		ch.add(SeverityWarning, SpecApple, RefApple, "explicit", path, "explicit should be true or false, %q is deprecated", explicit)
+	default:
+		ch.add(SeverityError, SpecApple, RefApple, "explicit", path, "explicit must be true or false, was %q", explicit)
+	}
+}
+
+func (ch *checker) categories(categories []Category) {
+	if len(categories) == 0 {
+		ch.add(SeverityError, SpecApple, RefAppleCategories, "required", "Category", "the channel must have a category")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", "Category", "the channel must have a category")
+	}
+	if len(categories) > 2 {
+		ch.add(SeverityWarning, SpecApple, RefAppleCategories, "category-count", "Category", "only the first two of the %d categories are used", len(categories))
+	}
+	for i, c := range categories {
+		path := fmt.Sprintf("Category[%d]", i)
+		subcategories, ok := AppleCategories[c.AttrText]
+		if !ok {
+			ch.add(SeverityError, SpecApple, RefAppleCategories, "category", path, "%q is not a category of Apple Podcasts", c.AttrText)
+			continue
+		}
+		if c.Category != nil && !slices.Contains(subcategories, c.Category.AttrText) {
+			ch.add(SeverityError, SpecApple, RefAppleCategories, "category", path+".Category", "%q is not a subcategory of %s", c.Category.AttrText, c.AttrText)
+		}
+	}
+}
+
+func (ch *checker) enclosure(path string, e Enclosure) {
+	if e.URL == "" {
+		ch.add(SeverityError, SpecApple, RefApple, "required", path+".URL", "the episode must have an enclosure with the media-file")
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "required", path+".URL", "the episode must have an enclosure with the media-file")
+		return
+	}
+	if u, err := url.Parse(e.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ch.add(SeverityError, SpecRSS, RefRSS, "enclosure-url", path+".URL", "the url must be an absolute http(s)-url, was %q", e.URL)
+	}
+	switch {
+	case e.Type == "":
+		ch.add(SeverityError, SpecRSS, RefRSS, "required", path+".Type", "the enclosure must have a type")
+	case !slices.Contains(appleEnclosureTypes, e.Type):
+		ch.add(SeverityError, SpecApple, RefAppleAudio, "enclosure-type", path+".Type", "the type must be one of %s, was %q", strings.Join(appleEnclosureTypes, ", "), e.Type)
+	}
+	if e.Type != "" && !slices.Contains(spotifyEnclosureTypes, e.Type) {
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "enclosure-type", path+".Type", "the type must be one of %s, was %q", strings.Join(spotifyEnclosureTypes, ", "), e.Type)
+	}
+	if e.LengthInBytes == "" {
+		ch.add(SeverityError, SpecRSS, RefRSS, "required", path+".LengthInBytes", "the enclosure must have the length of the file in bytes")
+	} else if n, err := strconv.ParseInt(e.LengthInBytes, 10, 64); err != nil || n < 0 {
+		ch.add(SeverityError, SpecRSS, RefRSS, "enclosure-length", path+".LengthInBytes", "the length must be a number of bytes, was %q", e.LengthInBytes)
+	} else if n == 0 {
+		ch.add(SeverityWarning, SpecApple, RefApple, "enclosure-length", path+".LengthInBytes", "the length should be the size of the file, not 0")
+	}
+}
+
+// date checks that the date is in the format of RFC 2822, which is what the RSS-specification requires
+func (ch *checker) date(path, s string, notInFuture bool) {
+	if s == "" {
+		return
+	}
+	t, err := parseRFC2822(s)
+	if err != nil {
+		if _, lenientErr := ParseDate(s); lenientErr == nil {
+			ch.add(SeverityWarning, SpecRSS, RefRFC2822, "date", path, "the date is not RFC 2822, and may be misread by players: %q", s)
+		} else {
+			ch.add(SeverityError, SpecRSS, RefRFC2822, "date", path, "the date cannot be read, it must be RFC 2822, like Mon, 02 Jan 2006 15:04:05 -0700: %q", s)
+		}
+		return
+	}
+	if i := strings.Index(s, ","); i >= 0 && strings.TrimSpace(s[:i]) != t.Weekday().String()[:3] {
+		ch.add(SeverityWarning, SpecRSS, RefRFC2822, "date", path, "the weekday does not match the date %q, which is a %s", s, t.Weekday())
+	}
+	if notInFuture && t.After(time.Now().Add(24*time.Hour)) {
+		ch.add(SeverityWarning, SpecApple, RefApple, "date", path, "the publication-date is in the future, so the episode may be hidden: %q", s)
+	}
+}
+
+func parseRFC2822(s string) (time.Time, error) {
+	v := strings.Join(strings.Fields(s), " ")
+	for _, l := range rfc2822Layouts {
+		if t, err := time.Parse(l, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("the date %q is not RFC 2822", s)
+}
+
+// artwork checks the dimensions and format of the image, if there is an ImageProber
+func (ch *checker) artwork(path, imageURL string) {
+	if ch.opts.ImageProber == nil {
+		return
+	}
+	res, ok := ch.images[imageURL]
+	if !ok && ch.opts.MaxImageProbes > 0 && len(ch.images) >= ch.opts.MaxImageProbes {
+		return
+	}
+	if !ok {
+		res.info, res.err = ch.opts.ImageProber.ProbeImage(ch.ctx, imageURL)
+		ch.images[imageURL] = res
+	}
+	if res.err != nil {
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "artwork", path, "the artwork could not be read: %v", res.err)
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "artwork", path, "the artwork could not be read: %v", res.err)
+		return
+	}
+	info := res.info
+	if info.Format != "jpeg" && info.Format != "png" {
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "artwork-format", path, "the artwork must be jpeg or png, was %s", info.Format)
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "artwork-format", path, "the artwork must be jpeg or png, was %s", info.Format)
+	}
+	if info.CMYK {
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "artwork-color", path, "the artwork must be in the RGB color space, not CMYK")
+	}
+	if info.Width != info.Height {
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "artwork-size", path, "the artwork must be square, was %dx%d", info.Width, info.Height)
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "artwork-size", path, "the artwork must be square, was %dx%d", info.Width, info.Height)
+	}
+	if info.Width < 1400 || info.Height < 1400 || info.Width > 3000 || info.Height > 3000 {
+		ch.add(SeverityError, SpecApple, RefAppleArtwork, "artwork-size", path, "the artwork must be between 1400x1400 and 3000x3000 pixels, was %dx%d", info.Width, info.Height)
+	}
+	if info.Width < 640 || info.Height < 640 {
+		ch.add(SeverityError, SpecSpotify, RefSpotify, "artwork-size", path, "the artwork must be at least 640x640 pixels, was %dx%d", info.Width, info.Height)
+	}
+}
+
+// podcast reports the errors of the podcast-namespace, which are joined by validatePodcast
+func (ch *checker) podcast(path string, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		ch.add(SeverityError, SpecPodcast, RefPodcast, "podcast-namespace", path, "%v", e)
+	}
+}
@@ -0,0 +1,322 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+type testImageProber map[string]ImageInfo
+
+func (p testImageProber) ProbeImage(ctx context.Context, imageURL string) (ImageInfo, error) {
+	info, ok := p[imageURL]
+	if !ok {
+		return info, errors.New("not found")
+	}
+	return info, nil
+}
+
+func testCompliantChannel() Channel {
+	return Channel{
+		Title:       "Podcast",
+		Description: "About things",
+		Link:        Link{Href: "https://example.com"},
+		Language:    "nb-NO",
+		Category:    []Category{{AttrText: "Society & Culture", Category: &Subcategory{AttrText: "Documentary"}}},
+		Explicit:    "false",
+		Image:       Image{URL: "https://example.com/cover.jpg", Title: "Podcast", Link: "https://example.com"},
+		ItunesImage: &ItunesImage{Href: "https://example.com/cover.jpg"},
+		Author:      "Jane",
+		Owner:       &Owner{Name: "Jane", Email: "jane@example.com"},
+		PubDate:     "Tue, 03 Sep 2024 08:00:00 +0000",
+		Item: []Item{
+			{
+				Title:             "Episode 1",
+				GUID:              "ep-1",
+				PubDate:           "Tue, 03 Sep 2024 08:00:00 +0000",
+				Enclosure:         Enclosure{URL: "https://example.com/ep-1.mp3", Type: "audio/mpeg", LengthInBytes: "1234"},
+				DurationInSeconds: "01:02:03",
+			},
+			{
+				Title:     "Episode 2",
+				GUID:      "ep-2",
+				PubDate:   "10 Sep 2024 08:00 GMT",
+				Enclosure: Enclosure{URL: "https://example.com/ep-2.m4a", Type: "audio/x-m4a", LengthInBytes: "5678"},
+			},
+		},
+	}
+}
+
+var testProber = testImageProber{
+	"https://example.com/cover.jpg": {Width: 3000, Height: 3000, Format: "jpeg"},
+	"https://example.com/small.png": {Width: 1000, Height: 1000, Format: "png"},
+	"https://example.com/wide.jpg":  {Width: 3000, Height: 1500, Format: "jpeg"},
+	"https://example.com/cmyk.jpg":  {Width: 1400, Height: 1400, Format: "jpeg", CMYK: true},
+}
+
+func TestCheck(t *testing.T) {
+	type finding struct {
+		Severity Severity
+		Spec     Spec
+		Rule     string
+		Path     string
+	}
+	tests := []struct {
+		name   string
+		modify func(c *Channel)
+		specs  []Spec
+		want   []finding
+	}{
+		{"Compliant", func(c *Channel) {}, nil, nil},
+		{
+			"Missing channel fields",
+			func(c *Channel) { c.Title, c.Language, c.Explicit, c.Category = "", "", "", nil },
+			[]Spec{SpecApple},
+			[]finding{
+				{SeverityError, SpecApple, "required", "Title"},
+				{SeverityError, SpecApple, "required", "Language"},
+				{SeverityError, SpecApple, "required", "Explicit"},
+				{SeverityError, SpecApple, "required", "Category"},
+			},
+		},
+		{
+			"Description length",
+			func(c *Channel) { c.Item[1].Description = strings.Repeat("a", 4001) },
+			nil,
+			[]finding{
+				{SeverityError, SpecApple, "description-length", "Item[1].Description"},
+				{SeverityError, SpecSpotify, "description-length", "Item[1].Description"},
+			},
+		},
+		{
+			"Category taxonomy",
+			func(c *Channel) {
+				c.Category = []Category{{AttrText: "Podcasts"}, {AttrText: "News", Category: &Subcategory{AttrText: "Documentary"}}}
+			},
+			nil,
+			[]finding{
+				{SeverityError, SpecApple, "category", "Category[0]"},
+				{SeverityError, SpecApple, "category", "Category[1].Category"},
+			},
+		},
+		{
+			"Deprecated explicit",
+			func(c *Channel) { c.Explicit, c.Item[0].Explicit = "clean", "maybe" },
+			nil,
+			[]finding{
+				{SeverityWarning, SpecApple, "explicit", "Explicit"},
+				{SeverityError, SpecApple, "explicit", "Item[0].Explicit"},
+			},
+		},
+		{
+			"Enclosure",
+			func(c *Channel) {
+				c.Item[0].Enclosure = Enclosure{URL: "/ep-1.ogg", Type: "audio/ogg", LengthInBytes: "1 MB"}
+				c.Item[1].Enclosure = Enclosure{URL: "https://example.com/ep-2.pdf", Type: "application/pdf"}
+			},
+			nil,
+			[]finding{
+				{SeverityError, SpecRSS, "enclosure-url", "Item[0].Enclosure.URL"},
+				{SeverityError, SpecApple, "enclosure-type", "Item[0].Enclosure.Type"},
+				{SeverityError, SpecSpotify, "enclosure-type", "Item[0].Enclosure.Type"},
+				{SeverityError, SpecRSS, "enclosure-length", "Item[0].Enclosure.LengthInBytes"},
+				{SeverityError, SpecSpotify, "enclosure-type", "Item[1].Enclosure.Type"},
+				{SeverityError, SpecRSS, "required", "Item[1].Enclosure.LengthInBytes"},
+			},
+		},
+		{
+			"GUID",
+			func(c *Channel) { c.Item[1].GUID = "ep-1"; c.Item = append(c.Item, c.Item[0]); c.Item[2].GUID = "" },
+			nil,
+			[]finding{
+				{SeverityError, SpecApple, "guid-unique", "Item[1].GUID"},
+				{SeverityError, SpecSpotify, "guid-unique", "Item[1].GUID"},
+				{SeverityWarning, SpecApple, "recommended", "Item[2].GUID"},
+				{SeverityError, SpecSpotify, "required", "Item[2].GUID"},
+			},
+		},
+		{
+			"Dates",
+			func(c *Channel) {
+				c.PubDate = "Mon, 03 Sep 2024 08:00:00 +0000"
+				c.LastBuildDate = "2024-09-03T08:00:00Z"
+				c.Item[0].PubDate = "yesterday"
+				c.Item[1].PubDate = "Fri, 01 Jan 2100 08:00:00 +0000"
+			},
+			nil,
+			[]finding{
+				{SeverityWarning, SpecRSS, "date", "PubDate"},
+				{SeverityWarning, SpecRSS, "date", "LastBuildDate"},
+				{SeverityError, SpecRSS, "date", "Item[0].PubDate"},
+				{SeverityWarning, SpecApple, "date", "Item[1].PubDate"},
+			},
+		},
+		{
+			"Artwork",
+			func(c *Channel) {
+				c.ItunesImage.Href = "https://example.com/small.png"
+				c.Item[0].ItunesImage = &ItunesImage{Href: "https://example.com/wide.jpg"}
+				c.Item[1].ItunesImage = &ItunesImage{Href: "https://example.com/cmyk.jpg"}
+			},
+			nil,
+			[]finding{
+				{SeverityError, SpecApple, "artwork-size", "ItunesImage"},
+				{SeverityError, SpecApple, "artwork-size", "Item[0].ItunesImage"},
+				{SeverityError, SpecSpotify, "artwork-size", "Item[0].ItunesImage"},
+				{SeverityError, SpecApple, "artwork-color", "Item[1].ItunesImage"},
+			},
+		},
+		{
+			"Missing artwork",
+			func(c *Channel) { c.ItunesImage = nil },
+			[]Spec{SpecApple},
+			[]finding{{SeverityError, SpecApple, "required", "ItunesImage"}},
+		},
+		{
+			"Serial without episode-numbers",
+			func(c *Channel) { c.Type = "serial"; c.Item[0].Episode = "1"; c.Item[1].Season = "0" },
+			nil,
+			[]finding{
+				{SeverityWarning, SpecApple, "episode-number", "Item[1].Episode"},
+				{SeverityError, SpecApple, "season-number", "Item[1].Season"},
+			},
+		},
+		{
+			"Podcast-namespace",
+			func(c *Channel) { c.Locked = "maybe" },
+			nil,
+			[]finding{{SeverityError, SpecPodcast, "podcast-namespace", ""}},
+		},
+		{
+			"No items",
+			func(c *Channel) { c.Item = nil },
+			[]Spec{SpecSpotify},
+			[]finding{{SeverityError, SpecSpotify, "items", "Item"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testCompliantChannel()
+			tt.modify(&c)
+			report := Check(context.Background(), c, CheckOptions{Specs: tt.specs, ImageProber: testProber})
+			var got []finding
+			for _, f := range report.Findings {
+				if f.Message == "" || f.Reference == "" {
+					t.Errorf("expected the finding to have a message and a reference, got %#v", f)
+				}
+				got = append(got, finding{f.Severity, f.Spec, f.Rule, f.Path})
+			}
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+			if (report.Err() == nil) != (report.Count(SeverityError) == 0) {
+				t.Errorf("expected Err to match the count of errors, got %v", report.Err())
+			}
+		})
+	}
+}
+
+func TestCheck_ProbesOnce(t *testing.T) {
+	probes := 0
+	c := testCompliantChannel()
+	for i := range c.Item {
+		c.Item[i].ItunesImage = c.ItunesImage
+	}
+	Check(context.Background(), c, CheckOptions{ImageProber: testProberFunc(func(ctx context.Context, imageURL string) (ImageInfo, error) {
+		probes++
+		return testProber.ProbeImage(ctx, imageURL)
+	})})
+	if probes != 1 {
+		t.Errorf("expected the shared artwork to be probed once, got %d", probes)
+	}
+	c.Item[0].ItunesImage = &ItunesImage{Href: "https://example.com/small.png"}
+	probes = 0
+	report := Check(context.Background(), c, CheckOptions{MaxImageProbes: 1, ImageProber: testProberFunc(func(ctx context.Context, imageURL string) (ImageInfo, error) {
+		probes++
+		return testProber.ProbeImage(ctx, imageURL)
+	})})
+	if probes != 1 || report.Count(SeverityError) != 0 {
+		t.Errorf("expected only the first image to be probed, got %d probes and %v", probes, report.Err())
+	}
+}
+
+type testProberFunc func(ctx context.Context, imageURL string) (ImageInfo, error)
+
+func (f testProberFunc) ProbeImage(ctx context.Context, imageURL string) (ImageInfo, error) {
+	return f(ctx, imageURL)
+}
+
+func TestCheck_Parse(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+<channel>
+	<title>Podcast</title>
+	<description>About things</description>
+	<link>https://example.com</link>
+	<language>nb-NO</language>
+	<itunes:category text="Society &amp; Culture"/>
+	<itunes:explicit>clean</itunes:explicit>
+	<itunes:image href="https://example.com/cover.jpg"/>
+	<itunes:author>Jane</itunes:author>
+	<itunes:owner><itunes:name>Jane</itunes:name><itunes:email>jane@example.com</itunes:email></itunes:owner>
+	<itunes:type>Serial</itunes:type>
+	<podcast:guid>917393E3-1B1E-5CEF-ACE4-EDAA54E1F810</podcast:guid>
+	<pubDate>Mon, 03 Sep 2024 08:00:00 +0000</pubDate>
+	<lastBuildDate>2024-09-03T08:00:00Z</lastBuildDate>
+	<item>
+		<title>Episode 1</title>
+		<guid>ep-1</guid>
+		<pubDate>Tue, 03 Sep 2024 08:00:00 +0000</pubDate>
+		<itunes:episode>1</itunes:episode>
+		<itunes:episodeType>Full</itunes:episodeType>
+		<enclosure url="https://example.com/ep-1.mp3" type="audio/mpeg" length="1234"/>
+	</item>
+</channel>
+</rss>`
+	type finding struct {
+		Severity Severity
+		Spec     Spec
+		Rule     string
+		Path     string
+	}
+	tests := []struct {
+		name string
+		opts ParseOptions
+		want []finding
+	}{
+		{
+			"Should find the problems of the raw values",
+			ParseOptions{Raw: true},
+			[]finding{
+				{SeverityWarning, SpecApple, "explicit", "Explicit"},
+				{SeverityWarning, SpecRSS, "date", "PubDate"},
+				{SeverityWarning, SpecRSS, "date", "LastBuildDate"},
+				{SeverityError, SpecPodcast, "podcast-namespace", ""},
+			},
+		},
+		{
+			// This is why Check should be given feeds that are parsed with ParseOptions.Raw
+			"Should hide the problems when normalized",
+			ParseOptions{},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseWithOptions(strings.NewReader(feed), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []finding
+			for _, f := range Check(context.Background(), *c, CheckOptions{}).Findings {
+				got = append(got, finding{f.Severity, f.Spec, f.Rule, f.Path})
+			}
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
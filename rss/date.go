@@ -0,0 +1,79 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The layouts tried by ParseDate, after the weekday is removed and the timezone is normalized
+var dateLayouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04:05 MST",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Timezones that are commonly used in feeds, but that time.Parse does not know the offset of
+var dateZones = map[string]string{
+	"GMT":  "+0000",
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"CET":  "+0100",
+	"CEST": "+0200",
+}
+
+// ParseDate parses the dates that are found in real-world feeds, which often deviate from RFC 822.
+// The weekday is ignored, since it is often missing, misspelled or wrong, and dates without a timezone are in UTC.
+func ParseDate(s string) (time.Time, error) {
+	v := strings.Join(strings.Fields(s), " ")
+	if i := strings.Index(v, ","); i >= 0 && !strings.ContainsAny(v[:i], "0123456789") {
+		v = strings.TrimSpace(v[i+1:])
+	}
+	if i := strings.LastIndex(v, " "); i >= 0 {
+		if offset, ok := dateZones[strings.ToUpper(v[i+1:])]; ok {
+			v = v[:i+1] + offset
+		}
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// normalizeDate formats the date as RFC 1123, or returns it trimmed if it cannot be parsed
+func normalizeDate(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	t, err := ParseDate(s)
+	if err != nil {
+		return s
+	}
+	return t.Format(time.RFC1123Z)
+}
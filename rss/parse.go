@@ -0,0 +1,575 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var ErrNoChannel = errors.New("the feed has no channel")
+
+// Extension is an element of a feed that is not modelled, like the elements of other namespaces.
+// Parse keeps them, so that they are written back when the channel is marshalled.
+type Extension struct {
+	// The name, with the prefix, like media:content
+	XMLName  xml.Name
+	Attrs    []xml.Attr  `xml:",any,attr"`
+	Text     string      `xml:",chardata"`
+	Children []Extension `xml:",any"`
+}
+
+// The prefixes that are used for the namespaces that this package knows, by their normalized uri.
+// Feeds often use other uris for the same namespace, or do not declare them at all.
+var knownNamespaces = map[string]string{
+	"www.itunes.com/dtds/podcast-1.0.dtd":                                 "itunes",
+	"www.itunesu.com/feed":                                                "itunesu",
+	"podcastindex.org/namespace/1.0":                                      "podcast",
+	"github.com/podcastindex-org/podcast-namespace/blob/main/docs/1.0.md": "podcast",
+	"www.google.com/schemas/play-podcasts/1.0":                            "googleplay",
+	"www.w3.org/2005/atom":                                                "atom",
+	"purl.org/rss/1.0/modules/content":                                    "content",
+	"www.w3.org/1999/02/22-rdf-syntax-ns#":                                "rdf",
+	"purl.org/rss/1.0":                                                    "",
+	"backend.userland.com/rss2":                                           "",
+	"blogs.law.harvard.edu/tech/rss":                                      "",
+}
+
+// The prefixes that are declared by RssHeader, and therefore not declared on extensions
+var headerPrefixes = []string{"", "atom", "itunes", "itunesu", "podcast", "googleplay"}
+
+var encodingDeclaration = regexp.MustCompile(`^<\?xml[^>]*encoding\s*=\s*["']([^"']+)["']`)
+
+type (
+	// A parsed element
+	node struct {
+		// The name with the prefix, like itunes:image
+		name     string
+		prefix   string
+		space    string
+		attrs    []xml.Attr
+		text     strings.Builder
+		children []*node
+	}
+	parser struct {
+		// The prefixes of the declared namespaces, by their uri
+		prefixes map[string]string
+		// Keep the values as written, see ParseOptions.Raw
+		raw bool
+	}
+	ParseOptions struct {
+		// The content-type of the response, used for the character encoding
+		ContentType string
+		// Keep dates, itunes:explicit and keywords like itunes:type as they are written in the feed,
+		// instead of normalizing them. Use this for feeds that are given to Check,
+		// since the normalized values would hide the problems it looks for.
+		Raw bool
+	}
+)
+
+// Parse reads a RSS 2.0 feed, with the itunes and podcast namespaces, into a Channel. See ParseWithContentType.
+func Parse(r io.Reader) (*Channel, error) {
+	return ParseWithContentType(r, "")
+}
+
+// ParseWithContentType reads a RSS 2.0 feed, with the itunes and podcast namespaces, into a Channel. See ParseWithOptions.
+func ParseWithContentType(r io.Reader, contentType string) (*Channel, error) {
+	return ParseWithOptions(r, ParseOptions{ContentType: contentType})
+}
+
+// ParseWithOptions reads a RSS 2.0 feed, with the itunes and podcast namespaces, into a Channel.
+// It is lenient, since real-world feeds often are not valid:
+//   - The character encoding is read from the byte order mark, the xml-declaration or the content-type, in that order.
+//     Bytes that are not valid in UTF-8 feeds are read as Windows-1252.
+//   - Namespaces are recognized by their prefix if they are not declared, or by their uri if they use another prefix.
+//   - HTML entities are allowed, and unclosed elements are closed.
+//   - Dates are normalized to RFC 1123, if they can be parsed. See ParseDate.
+//     itunes:explicit is normalized to true or false, and keywords like itunes:type are lowercased.
+//     Neither is done if opts.Raw is set.
+//   - Elements that are not modelled are kept as Extensions.
+func ParseWithOptions(r io.Reader, opts ParseOptions) (*Channel, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the feed: %w", err)
+	}
+	b, err = toUTF8(b, opts.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{prefixes: map[string]string{}, raw: opts.Raw}
+	root, err := p.parse(b)
+	if err != nil {
+		return nil, err
+	}
+	var channelNode *node
+	if root.name == "channel" {
+		channelNode = root
+	} else {
+		channelNode = root.child("channel")
+	}
+	if channelNode == nil {
+		return nil, ErrNoChannel
+	}
+	c := p.parseChannel(channelNode)
+	// RSS 1.0 has the items next to the channel
+	if channelNode != root {
+		for _, n := range root.children {
+			if n.name == "item" {
+				c.Item = append(c.Item, p.parseItem(n))
+			}
+		}
+	}
+	return &c, nil
+}
+
+// toUTF8 decodes the feed to UTF-8
+func toUTF8(b []byte, contentType string) ([]byte, error) {
+	label := ""
+	switch {
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}):
+		label = "utf-8"
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		label = "utf-16be"
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		label = "utf-16le"
+	}
+	if label == "" {
+		if m := encodingDeclaration.FindSubmatch(b); m != nil {
+			label = string(m[1])
+		}
+	}
+	if label == "" && contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			label = params["charset"]
+		}
+	}
+	enc, name := charset.Lookup(label)
+	if enc != nil && name != "utf-8" {
+		decoded, err := enc.NewDecoder().Bytes(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the feed from %s: %w", name, err)
+		}
+		b = decoded
+	} else if !utf8.Valid(b) {
+		b = repairUTF8(b)
+	}
+	return bytes.TrimPrefix(b, []byte("\ufeff")), nil
+}
+
+// repairUTF8 reads the bytes that are not valid UTF-8 as Windows-1252, which is the most common mistake
+func repairUTF8(b []byte) []byte {
+	out := make([]byte, 0, len(b)+len(b)/8)
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			r = charmap.Windows1252.DecodeByte(b[0])
+		}
+		out = utf8.AppendRune(out, r)
+		b = b[size:]
+	}
+	return out
+}
+
+// parse reads the document into a tree of nodes, and returns the root
+func (p *parser) parse(b []byte) (*node, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	// The document is already decoded by toUTF8
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	var root *node
+	var stack []*node
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the feed: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := p.newNode(t)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, ErrNoChannel
+	}
+	return root, nil
+}
+
+func (p *parser) newNode(t xml.StartElement) *node {
+	// The declarations must be known before the name is resolved, since they may be declared on the element itself
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			if _, ok := p.prefixes[a.Value]; !ok {
+				p.prefixes[a.Value] = a.Name.Local
+			}
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			if _, ok := p.prefixes[a.Value]; !ok {
+				p.prefixes[a.Value] = ""
+			}
+		}
+	}
+	n := &node{space: t.Name.Space, prefix: p.prefix(t.Name.Space)}
+	n.name = prefixed(n.prefix, t.Name.Local)
+	for _, a := range t.Attr {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue
+		}
+		a.Name = xml.Name{Local: prefixed(p.prefix(a.Name.Space), a.Name.Local)}
+		n.attrs = append(n.attrs, a)
+	}
+	return n
+}
+
+// prefix returns the prefix for the namespace, preferring the prefixes of the known namespaces
+func (p *parser) prefix(space string) string {
+	if space == "" {
+		return ""
+	}
+	if space == "http://www.w3.org/XML/1998/namespace" {
+		return "xml"
+	}
+	if prefix, ok := knownNamespaces[normalizeNamespace(space)]; ok {
+		return prefix
+	}
+	if prefix, ok := p.prefixes[space]; ok {
+		return prefix
+	}
+	// The prefix was not declared, and the decoder leaves it as the space
+	return space
+}
+
+func normalizeNamespace(space string) string {
+	s := strings.ToLower(strings.TrimSpace(space))
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	return strings.TrimRight(s, "/")
+}
+
+func prefixed(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// value returns the trimmed text of the node
+func (n *node) value() string {
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(n.text.String())
+}
+
+func (n *node) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// extension returns the node as an Extension, declaring its namespace unless it is declared by RssHeader or the parent
+func (n *node) extension(parentPrefix string) Extension {
+	e := Extension{XMLName: xml.Name{Local: n.name}, Text: n.value()}
+	if n.prefix != parentPrefix && n.prefix != n.space && !slices.Contains(headerPrefixes, n.prefix) {
+		e.Attrs = append(e.Attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + n.prefix}, Value: n.space})
+	}
+	e.Attrs = append(e.Attrs, n.attrs...)
+	for _, c := range n.children {
+		e.Children = append(e.Children, c.extension(n.prefix))
+	}
+	return e
+}
+
+func (p *parser) date(s string) string {
+	if p.raw {
+		return s
+	}
+	return normalizeDate(s)
+}
+
+func (p *parser) explicit(s string) string {
+	if p.raw {
+		return s
+	}
+	return normalizeExplicit(s)
+}
+
+// keyword lowercases values that are case-insensitive keywords, like itunes:type
+func (p *parser) keyword(s string) string {
+	if p.raw {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// normalizeExplicit returns true or false for the values of itunes:explicit, which are written in many ways
+func normalizeExplicit(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "yes", "explicit":
+		return "true"
+	case "false", "no", "clean":
+		return "false"
+	}
+	return s
+}
+
+func (p *parser) parseChannel(n *node) Channel {
+	var c Channel
+	seen := map[string]bool{}
+	for _, e := range n.children {
+		// Only the first of the elements that are not repeated is used, the others are kept as extensions
+		single := func() bool {
+			if seen[e.name] {
+				return false
+			}
+			seen[e.name] = true
+			return true
+		}
+		switch {
+		case e.name == "item":
+			c.Item = append(c.Item, p.parseItem(e))
+		case e.name == "itunes:category":
+			c.Category = append(c.Category, parseCategory(e))
+		case e.name == "podcast:person":
+			c.PodcastPersons = append(c.PodcastPersons, parsePerson(e))
+		case e.name == "title" && single():
+			c.Title = e.value()
+		case e.name == "description" && single():
+			c.Description = e.value()
+		case e.name == "link" && single():
+			// Marshalled channels have the link in the href-attribute
+			c.Link = Link{Href: e.value(), Rel: e.attr("rel"), Type: e.attr("type")}
+			if c.Link.Href == "" {
+				c.Link.Href = e.attr("href")
+			}
+		case e.name == "language" && single():
+			c.Language = e.value()
+		case e.name == "copyright" && single():
+			c.Copyright = e.value()
+		case e.name == "webMaster" && single():
+			c.WebMaster = e.value()
+		case e.name == "managingEditor" && single():
+			c.ManagingEditor = e.value()
+		case e.name == "pubDate" && single():
+			c.PubDate = p.date(e.value())
+		case e.name == "lastBuildDate" && single():
+			c.LastBuildDate = p.date(e.value())
+		case e.name == "image" && single():
+			c.Image = Image{URL: e.child("url").value(), Title: e.child("title").value(), Link: e.child("link").value()}
+		case e.name == "itunes:image" && single():
+			c.ItunesImage = parseItunesImage(e)
+		case e.name == "itunes:explicit" && single():
+			c.Explicit = p.explicit(e.value())
+		case e.name == "itunes:author" && single():
+			c.Author = e.value()
+		case e.name == "itunes:type" && single():
+			c.Type = p.keyword(e.value())
+		case e.name == "itunes:complete" && single():
+			c.Complete = p.keyword(e.value())
+		case e.name == "itunes:title" && single():
+			c.ItunesTitle = e.value()
+		case e.name == "itunes:new-feed-url" && single():
+			c.NewFeedURL = e.value()
+		case e.name == "itunes:block" && single():
+			c.Block = e.value()
+		case e.name == "itunes:owner" && single():
+			c.Owner = &Owner{Name: e.child("itunes:name").value(), Email: e.child("itunes:email").value()}
+		case e.name == "itunes:keywords" && single():
+			c.Keywords = e.value()
+		case e.name == "itunes:summary" && single():
+			c.Summary = e.value()
+		case e.name == "itunes:subtitle" && single():
+			c.Subtitle = e.value()
+		case e.name == "podcast:locked" && single():
+			c.Locked = p.keyword(e.value())
+		case e.name == "podcast:guid" && single():
+			c.GUID = p.keyword(e.value())
+		case e.name == "podcast:txt" && single():
+			c.PodcastTxt = &PodastTXT{Text: e.value(), Purpose: e.attr("purpose")}
+		case e.name == "podcast:funding" && single():
+			c.PodcastFunding = &PodcastFunding{Text: e.value(), URL: e.attr("url")}
+		case e.name == "podcast:location" && single():
+			c.PodcastLocation = parseLocation(e)
+		case e.name == "podcast:images" && single():
+			c.PodcastImages = &PodcastImages{Srcset: e.attr("srcset")}
+		default:
+			c.Extensions = append(c.Extensions, e.extension(""))
+		}
+	}
+	return c
+}
+
+func (p *parser) parseItem(n *node) Item {
+	var item Item
+	seen := map[string]bool{}
+	for _, e := range n.children {
+		single := func() bool {
+			if seen[e.name] {
+				return false
+			}
+			seen[e.name] = true
+			return true
+		}
+		switch {
+		case e.name == "podcast:transcript":
+			item.PodcastTranscripts = append(item.PodcastTranscripts, PodcastTranscript{
+				URL:      e.attr("url"),
+				Type:     e.attr("type"),
+				Language: e.attr("language"),
+				Rel:      e.attr("rel"),
+			})
+		case e.name == "podcast:soundbite":
+			item.PodcastSoundbites = append(item.PodcastSoundbites, PodcastSoundbite{StartTime: e.attr("startTime"), Duration: e.attr("duration"), Title: e.value()})
+		case e.name == "podcast:person":
+			item.PodcastPersons = append(item.PodcastPersons, parsePerson(e))
+		case e.name == "podcast:alternateEnclosure":
+			item.PodcastAlternateEnclosures = append(item.PodcastAlternateEnclosures, parseAlternateEnclosure(e))
+		case e.name == "title" && single():
+			item.Title = e.value()
+		case e.name == "description" && single():
+			item.Description = e.value()
+		case e.name == "link" && single():
+			item.Link = e.value()
+		case e.name == "guid" && single():
+			item.GUID = e.value()
+		case e.name == "pubDate" && single():
+			item.PubDate = p.date(e.value())
+		case e.name == "category" && single():
+			item.Category = ItemCategory{Text: e.value(), Code: e.attr("code")}
+		case e.name == "enclosure" && single():
+			item.Enclosure = Enclosure{URL: e.attr("url"), Type: e.attr("type"), LengthInBytes: e.attr("length")}
+		case e.name == "itunes:summary" && single():
+			item.Summary = e.value()
+		case e.name == "itunes:subtitle" && single():
+			item.Subtitle = e.value()
+		case e.name == "itunes:duration" && single():
+			item.DurationInSeconds = e.value()
+		case e.name == "itunes:title" && single():
+			item.ItunesTitle = e.value()
+		case e.name == "itunes:episode" && single():
+			item.Episode = e.value()
+		case e.name == "itunes:season" && single():
+			item.Season = e.value()
+		case e.name == "itunes:episodeType" && single():
+			item.EpisodeType = p.keyword(e.value())
+		case e.name == "itunes:explicit" && single():
+			item.Explicit = p.explicit(e.value())
+		case e.name == "itunes:block" && single():
+			item.Block = e.value()
+		case e.name == "itunes:author" && single():
+			item.Author = e.value()
+		case e.name == "itunes:image" && single():
+			item.ItunesImage = parseItunesImage(e)
+		case e.name == "podcast:chapters" && single():
+			item.PodcastChapters = &PodcastChapters{URL: e.attr("url"), Type: e.attr("type")}
+		case e.name == "podcast:season" && single():
+			item.PodcastSeason = &PodcastSeason{Number: e.value(), Name: e.attr("name")}
+		case e.name == "podcast:episode" && single():
+			item.PodcastEpisode = &PodcastEpisode{Number: e.value(), Display: e.attr("display")}
+		case e.name == "podcast:location" && single():
+			item.PodcastLocation = parseLocation(e)
+		case e.name == "podcast:images" && single():
+			item.PodcastImages = &PodcastImages{Srcset: e.attr("srcset")}
+		default:
+			item.Extensions = append(item.Extensions, e.extension(""))
+		}
+	}
+	return item
+}
+
+// parseItunesImage reads the href, or the text, which some feeds use instead
+func parseItunesImage(n *node) *ItunesImage {
+	href := n.attr("href")
+	if href == "" {
+		href = n.value()
+	}
+	if href == "" {
+		href = n.child("url").value()
+	}
+	if href == "" {
+		return nil
+	}
+	return &ItunesImage{Href: href}
+}
+
+func parseCategory(n *node) Category {
+	c := Category{AttrText: n.attr("text")}
+	if sub := n.child("itunes:category"); sub != nil {
+		c.Category = &Subcategory{AttrText: sub.attr("text")}
+	}
+	return c
+}
+
+func parsePerson(n *node) PodcastPerson {
+	return PodcastPerson{Name: n.value(), Role: n.attr("role"), Group: n.attr("group"), Img: n.attr("img"), Href: n.attr("href")}
+}
+
+func parseLocation(n *node) *PodcastLocation {
+	return &PodcastLocation{Name: n.value(), Geo: n.attr("geo"), OSM: n.attr("osm")}
+}
+
+func parseAlternateEnclosure(n *node) PodcastAlternateEnclosure {
+	a := PodcastAlternateEnclosure{
+		Type:    n.attr("type"),
+		Length:  n.attr("length"),
+		Bitrate: n.attr("bitrate"),
+		Height:  n.attr("height"),
+		Lang:    n.attr("lang"),
+		Title:   n.attr("title"),
+		Rel:     n.attr("rel"),
+		Codecs:  n.attr("codecs"),
+		Default: n.attr("default"),
+	}
+	for _, c := range n.children {
+		switch c.name {
+		case "podcast:source":
+			a.Sources = append(a.Sources, PodcastSource{URI: c.attr("uri"), ContentType: c.attr("contentType")})
+		case "podcast:integrity":
+			a.Integrity = &PodcastIntegrity{Type: c.attr("type"), Value: c.attr("value")}
+		}
+	}
+	return a
+}
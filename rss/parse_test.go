@@ -0,0 +1,204 @@
+package rss
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+const testFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="https://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:pod="https://podcastindex.org/namespace/1.0" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+	<title><![CDATA[Tom & Jerry's <podcast>]]></title>
+	<description>Caf&eacute; talk&nbsp;&amp; more &copy;</description>
+	<link>https://example.com</link>
+	<language>no</language>
+	<generator>Hand-written</generator>
+	<pubDate>Tue, 3 Sep 2024 8:00:00 GMT</pubDate>
+	<lastBuildDate>not a date</lastBuildDate>
+	<image><url>https://example.com/cover.png</url><title>Tom</title><link>https://example.com</link></image>
+	<itunes:image href="https://example.com/artwork.png"/>
+	<itunes:explicit>clean</itunes:explicit>
+	<itunes:category text="Society &amp; Culture"><itunes:category text="Documentary"/></itunes:category>
+	<itunes:owner><itunes:name>Jane</itunes:name><itunes:email>jane@example.com</itunes:email></itunes:owner>
+	<itunes:type>Serial</itunes:type>
+	<pod:guid>917393E3-1B1E-5CEF-ACE4-EDAA54E1F810</pod:guid>
+	<pod:funding url="https://example.com/donate">Support us</pod:funding>
+	<media:thumbnail url="https://example.com/thumb.png"/>
+	<item>
+		<title>Episode 1</title>
+		<description><![CDATA[<p>Hello <b>world</b></p>]]></description>
+		<guid isPermaLink="false">ep-1</guid>
+		<pubDate>Wednesday, 04 Sep 2024 10:30:00 +0200</pubDate>
+		<enclosure url="https://example.com/ep-1.mp3" type="audio/mpeg" length="1234"/>
+		<itunes:duration>01:02:03</itunes:duration>
+		<itunes:episode> 1 </itunes:episode>
+		<itunes:season>2</itunes:season>
+		<itunes:explicit>yes</itunes:explicit>
+		<pod:transcript url="https://example.com/ep-1.vtt" type="text/vtt"/>
+		<pod:person role="guest" href="https://example.com/john">John</pod:person>
+		<pod:alternateEnclosure type="audio/opus" bitrate="64000">
+			<pod:source uri="https://example.com/ep-1.opus"/>
+			<pod:integrity type="sri" value="sha384-abc"/>
+		</pod:alternateEnclosure>
+		<media:content url="https://example.com/ep-1.mp4" medium="video"><media:title>Video</media:title></media:content>
+	</item>
+</channel>
+</rss>`
+
+func TestParse(t *testing.T) {
+	c, err := Parse(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Channel{
+		Title:          "Tom & Jerry's <podcast>",
+		Description:    "Café talk & more ©",
+		Link:           Link{Href: "https://example.com"},
+		Language:       "no",
+		PubDate:        "Tue, 03 Sep 2024 08:00:00 +0000",
+		LastBuildDate:  "not a date",
+		Image:          Image{URL: "https://example.com/cover.png", Title: "Tom", Link: "https://example.com"},
+		ItunesImage:    &ItunesImage{Href: "https://example.com/artwork.png"},
+		Explicit:       "false",
+		Category:       []Category{{AttrText: "Society & Culture", Category: &Subcategory{AttrText: "Documentary"}}},
+		Owner:          &Owner{Name: "Jane", Email: "jane@example.com"},
+		Type:           "serial",
+		GUID:           "917393e3-1b1e-5cef-ace4-edaa54e1f810",
+		PodcastFunding: &PodcastFunding{Text: "Support us", URL: "https://example.com/donate"},
+		Extensions: []Extension{
+			{XMLName: xml.Name{Local: "generator"}, Text: "Hand-written"},
+			{XMLName: xml.Name{Local: "media:thumbnail"}, Attrs: []xml.Attr{
+				{Name: xml.Name{Local: "xmlns:media"}, Value: "http://search.yahoo.com/mrss/"},
+				{Name: xml.Name{Local: "url"}, Value: "https://example.com/thumb.png"},
+			}},
+		},
+		Item: []Item{{
+			Title:              "Episode 1",
+			Description:        "<p>Hello <b>world</b></p>",
+			GUID:               "ep-1",
+			PubDate:            "Wed, 04 Sep 2024 10:30:00 +0200",
+			Enclosure:          Enclosure{URL: "https://example.com/ep-1.mp3", Type: "audio/mpeg", LengthInBytes: "1234"},
+			DurationInSeconds:  "01:02:03",
+			Episode:            "1",
+			Season:             "2",
+			Explicit:           "true",
+			PodcastTranscripts: []PodcastTranscript{{URL: "https://example.com/ep-1.vtt", Type: "text/vtt"}},
+			PodcastPersons:     []PodcastPerson{{Name: "John", Role: "guest", Href: "https://example.com/john"}},
+			PodcastAlternateEnclosures: []PodcastAlternateEnclosure{{
+				Type:      "audio/opus",
+				Bitrate:   "64000",
+				Sources:   []PodcastSource{{URI: "https://example.com/ep-1.opus"}},
+				Integrity: &PodcastIntegrity{Type: "sri", Value: "sha384-abc"},
+			}},
+			Extensions: []Extension{{
+				XMLName: xml.Name{Local: "media:content"},
+				Attrs: []xml.Attr{
+					{Name: xml.Name{Local: "xmlns:media"}, Value: "http://search.yahoo.com/mrss/"},
+					{Name: xml.Name{Local: "url"}, Value: "https://example.com/ep-1.mp4"},
+					{Name: xml.Name{Local: "medium"}, Value: "video"},
+				},
+				Children: []Extension{{XMLName: xml.Name{Local: "media:title"}, Text: "Video"}},
+			}},
+		}},
+	}
+	if diff := deep.Equal(*c, want); diff != nil {
+		t.Error(diff)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected the parsed channel to be valid, got %v", err)
+	}
+}
+
+func TestParse_Lenient(t *testing.T) {
+	tests := []struct {
+		name        string
+		feed        string
+		contentType string
+		wantTitle   string
+		wantItems   int
+		wantErr     error
+	}{
+		{"Undeclared namespaces", `<rss><channel><title>A</title><itunes:explicit>true</itunes:explicit><podcast:locked>yes</podcast:locked></channel></rss>`, "", "A", 0, nil},
+		{"Latin-1 by the declaration", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Bl\xe5b\xe6r</title></channel></rss>", "", "Blåbær", 0, nil},
+		{"Latin-1 by the content-type", "<rss><channel><title>Bl\xe5b\xe6r</title></channel></rss>", "application/rss+xml; charset=iso-8859-1", "Blåbær", 0, nil},
+		{"Windows-1252 in a UTF-8 feed", "<?xml version=\"1.0\" encoding=\"utf-8\"?><rss><channel><title>Blåbær \x93quoted\x94</title></channel></rss>", "", "Blåbær “quoted”", 0, nil},
+		{"Byte order mark", "\xef\xbb\xbf<rss><channel><title>A</title></channel></rss>", "", "A", 0, nil},
+		{"Unknown entities and bare ampersands", `<rss><channel><title>Q&A &unknown; &hellip;</title></channel></rss>`, "", "Q&A &unknown; …", 0, nil},
+		{"Unclosed elements", `<rss><channel><title>A</title><item><title>1</title><br></item></channel></rss>`, "", "A", 1, nil},
+		{"RSS 1.0", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"><channel><title>A</title></channel><item><title>1</title></item></rdf:RDF>`, "", "A", 1, nil},
+		{"Not a feed", `<html><body>Not found</body></html>`, "", "", 0, ErrNoChannel},
+		{"Empty", ``, "", "", 0, ErrNoChannel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseWithContentType(strings.NewReader(tt.feed), tt.contentType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseWithContentType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.Title != tt.wantTitle {
+				t.Errorf("expected the title %q, got %q", tt.wantTitle, c.Title)
+			}
+			if len(c.Item) != tt.wantItems {
+				t.Errorf("expected %d items, got %d", tt.wantItems, len(c.Item))
+			}
+			if len(c.Extensions) != 0 {
+				t.Errorf("expected no extensions, got %#v", c.Extensions)
+			}
+		})
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	c, err := Parse(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := xml.Marshal(RssHeader(*c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The marshalled feed has the elements that are derived by WithItunesDefaults
+	if diff := deep.Equal(*parsed, c.WithItunesDefaults()); diff != nil {
+		t.Errorf("expected the feed to be the same after marshalling it:\n%v\n%s", diff, b)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2024, 9, 3, 8, 5, 0, 0, time.UTC)
+	for _, s := range []string{
+		"Tue, 03 Sep 2024 08:05:00 +0000",
+		"Tue, 3 Sep 2024 08:05:00 GMT",
+		"Mon, 03 Sep 2024 08:05:00 GMT",
+		"Tuesday, 3 September 2024 08:05:00 UT",
+		"03 Sep 2024 04:05:00 EDT",
+		"3 Sep 24 08:05:00 Z",
+		"Tue,  03   Sep 2024 10:05 +0200",
+		"Tue, 03 Sep 2024 10:05:00 +02:00",
+		"2024-09-03T08:05:00Z",
+		"2024-09-03 08:05:00",
+	} {
+		got, err := ParseDate(s)
+		if err != nil {
+			t.Errorf("ParseDate(%q) error = %v", s, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", s, got, want)
+		}
+	}
+	if _, err := ParseDate("yesterday"); err == nil {
+		t.Error("expected an error for an unrecognized date")
+	}
+}
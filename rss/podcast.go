@@ -0,0 +1,227 @@
+package rss
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Elements of the podcast-namespace (Podcasting 2.0), as recommended by PSP-1.
+// https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+
+type (
+	// Links to a transcript or closed captions of the episode. There may be several, in different formats or languages
+	PodcastTranscript struct {
+		URL string `xml:"url,attr"`
+		// The mime-type, like text/vtt, application/x-subrip or application/json
+		Type     string `xml:"type,attr"`
+		Language string `xml:"language,attr,omitempty"`
+		// Set to captions if the transcript is closed captions
+		Rel string `xml:"rel,attr,omitempty"`
+	}
+	// Links to the chapters of the episode
+	PodcastChapters struct {
+		URL string `xml:"url,attr"`
+		// Typically application/json+chapters
+		Type string `xml:"type,attr"`
+	}
+	// A part of the episode that is suitable for sharing
+	PodcastSoundbite struct {
+		// In seconds, with decimals
+		StartTime string `xml:"startTime,attr"`
+		// In seconds, with decimals
+		Duration string `xml:"duration,attr"`
+		Title    string `xml:",chardata"`
+	}
+	// A person who is involved with the podcast or the episode
+	PodcastPerson struct {
+		Name string `xml:",chardata"`
+		// Like host or guest. Defaults to host
+		Role string `xml:"role,attr,omitempty"`
+		// Like cast or writing. Defaults to cast
+		Group string `xml:"group,attr,omitempty"`
+		// Url to a picture of the person
+		Img string `xml:"img,attr,omitempty"`
+		// Url to a page about the person
+		Href string `xml:"href,attr,omitempty"`
+	}
+	// The location that the podcast or episode is about
+	PodcastLocation struct {
+		Name string `xml:",chardata"`
+		// A geo-uri, like geo:59.91,10.75
+		Geo string `xml:"geo,attr,omitempty"`
+		// An OpenStreetMap-id, like R406091
+		OSM string `xml:"osm,attr,omitempty"`
+	}
+	PodcastSeason struct {
+		Number string `xml:",chardata"`
+		Name   string `xml:"name,attr,omitempty"`
+	}
+	PodcastEpisode struct {
+		// May have decimals
+		Number string `xml:",chardata"`
+		// Shown instead of the number, like Ch.3
+		Display string `xml:"display,attr,omitempty"`
+	}
+	// Artwork in several sizes, in the format of the html srcset-attribute
+	PodcastImages struct {
+		Srcset string `xml:"srcset,attr"`
+	}
+	// Another version of the episode's media, like in another bitrate or as video
+	PodcastAlternateEnclosure struct {
+		Type    string `xml:"type,attr"`
+		Length  string `xml:"length,attr,omitempty"`
+		Bitrate string `xml:"bitrate,attr,omitempty"`
+		Height  string `xml:"height,attr,omitempty"`
+		Lang    string `xml:"lang,attr,omitempty"`
+		Title   string `xml:"title,attr,omitempty"`
+		Rel     string `xml:"rel,attr,omitempty"`
+		Codecs  string `xml:"codecs,attr,omitempty"`
+		// Set to true for the enclosure that is the same as the Enclosure of the item
+		Default string `xml:"default,attr,omitempty"`
+		// At least one source is required
+		Sources   []PodcastSource   `xml:"podcast:source"`
+		Integrity *PodcastIntegrity `xml:"podcast:integrity"`
+	}
+	PodcastSource struct {
+		URI         string `xml:"uri,attr"`
+		ContentType string `xml:"contentType,attr,omitempty"`
+	}
+	PodcastIntegrity struct {
+		// One of sri or pgp-signature
+		Type  string `xml:"type,attr"`
+		Value string `xml:"value,attr"`
+	}
+)
+
+// The namespace for PodcastGUID
+var podcastGUIDNamespace = [16]byte{0xea, 0xd4, 0xc2, 0x36, 0xbf, 0x58, 0x58, 0xc6, 0xa2, 0xc6, 0xa6, 0xb2, 0x8d, 0x12, 0x8c, 0xb6}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// PodcastGUID returns the podcast:guid for the feed-url, which is a UUIDv5 of the url without the scheme and trailing slashes.
+func PodcastGUID(feedURL string) string {
+	u := feedURL
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	u = strings.TrimRight(u, "/")
+	h := sha1.New()
+	h.Write(podcastGUIDNamespace[:])
+	h.Write([]byte(u))
+	s := h.Sum(nil)
+	s[6] = (s[6] & 0x0f) | 0x50
+	s[8] = (s[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", s[0:4], s[4:6], s[6:8], s[8:10], s[10:16])
+}
+
+func (c Channel) validatePodcast() error {
+	errs := []error{
+		oneOf("Locked", c.Locked, "", "yes", "no"),
+		validGUID("GUID", c.GUID),
+	}
+	if t := c.PodcastTxt; t != nil {
+		errs = append(errs, req("PodcastTxt.Text", t.Text), maxLen("PodcastTxt.Text", t.Text, 4000), maxLen("PodcastTxt.Purpose", t.Purpose, 128))
+	}
+	if f := c.PodcastFunding; f != nil {
+		errs = append(errs, req("PodcastFunding.URL", f.URL), maxLen("PodcastFunding.Text", f.Text, 128))
+	}
+	errs = append(errs, validatePersons(c.PodcastPersons), c.PodcastLocation.validate(), c.PodcastImages.validate())
+	return errors.Join(errs...)
+}
+
+func (item Item) validatePodcast() error {
+	var errs []error
+	for i, t := range item.PodcastTranscripts {
+		f := fmt.Sprintf("PodcastTranscripts[%d]", i)
+		errs = append(errs, req(f+".URL", t.URL), req(f+".Type", t.Type))
+	}
+	if c := item.PodcastChapters; c != nil {
+		errs = append(errs, req("PodcastChapters.URL", c.URL), req("PodcastChapters.Type", c.Type))
+	}
+	for i, s := range item.PodcastSoundbites {
+		f := fmt.Sprintf("PodcastSoundbites[%d]", i)
+		errs = append(errs, seconds(f+".StartTime", s.StartTime, true), seconds(f+".Duration", s.Duration, false), maxLen(f+".Title", s.Title, 128))
+	}
+	if s := item.PodcastSeason; s != nil {
+		errs = append(errs, req("PodcastSeason.Number", s.Number), positiveInt("PodcastSeason.Number", s.Number), maxLen("PodcastSeason.Name", s.Name, 128))
+	}
+	if e := item.PodcastEpisode; e != nil {
+		errs = append(errs, req("PodcastEpisode.Number", e.Number), maxLen("PodcastEpisode.Display", e.Display, 32))
+		if _, err := strconv.ParseFloat(e.Number, 64); e.Number != "" && err != nil {
+			errs = append(errs, fmt.Errorf("PodcastEpisode.Number must be a number, was %q", e.Number))
+		}
+	}
+	for i, a := range item.PodcastAlternateEnclosures {
+		f := fmt.Sprintf("PodcastAlternateEnclosures[%d]", i)
+		errs = append(errs, req(f+".Type", a.Type), maxLen(f+".Title", a.Title, 32))
+		if len(a.Sources) == 0 {
+			errs = append(errs, fmt.Errorf("%s must have at least one source", f))
+		}
+		for j, s := range a.Sources {
+			errs = append(errs, req(fmt.Sprintf("%s.Sources[%d].URI", f, j), s.URI))
+		}
+		if in := a.Integrity; in != nil {
+			errs = append(errs, oneOf(f+".Integrity.Type", in.Type, "sri", "pgp-signature"), req(f+".Integrity.Value", in.Value))
+		}
+	}
+	errs = append(errs, validatePersons(item.PodcastPersons), item.PodcastLocation.validate(), item.PodcastImages.validate())
+	return errors.Join(errs...)
+}
+
+func validatePersons(persons []PodcastPerson) error {
+	var errs []error
+	for i, p := range persons {
+		f := fmt.Sprintf("PodcastPersons[%d]", i)
+		errs = append(errs, req(f+".Name", p.Name), maxLen(f+".Name", p.Name, 128))
+	}
+	return errors.Join(errs...)
+}
+
+func (l *PodcastLocation) validate() error {
+	if l == nil {
+		return nil
+	}
+	err := errors.Join(req("PodcastLocation.Name", l.Name), maxLen("PodcastLocation.Name", l.Name, 128))
+	if l.Geo != "" && !strings.HasPrefix(l.Geo, "geo:") {
+		err = errors.Join(err, fmt.Errorf("PodcastLocation.Geo must be a geo-uri, like geo:59.91,10.75, was %q", l.Geo))
+	}
+	return err
+}
+
+func (img *PodcastImages) validate() error {
+	if img == nil {
+		return nil
+	}
+	return req("PodcastImages.Srcset", img.Srcset)
+}
+
+func validGUID(fieldname, value string) error {
+	if value == "" || uuidPattern.MatchString(value) {
+		return nil
+	}
+	return fmt.Errorf("%s must be a lowercase uuid, see PodcastGUID, was %q", fieldname, value)
+}
+
+func maxLen(fieldname, value string, max int) error {
+	if n := utf8.RuneCountInString(value); n > max {
+		return fmt.Errorf("%s must be at most %d characters, was %d", fieldname, max, n)
+	}
+	return nil
+}
+
+// seconds validates a required number of seconds, which may have decimals
+func seconds(fieldname, value string, allowZero bool) error {
+	if value == "" {
+		return ErrReq(fieldname)
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 || (n == 0 && !allowZero) {
+		return fmt.Errorf("%s must be a positive number of seconds, was %q", fieldname, value)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package rss
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestPodcastGUID(t *testing.T) {
+	// The example from the specification of podcast:guid
+	want := "917393e3-1b1e-5cef-ace4-edaa54e1f810"
+	for _, feedURL := range []string{"https://mp3s.nashownotes.com/pc20rss.xml", "http://mp3s.nashownotes.com/pc20rss.xml/", "mp3s.nashownotes.com/pc20rss.xml"} {
+		if got := PodcastGUID(feedURL); got != want {
+			t.Errorf("PodcastGUID(%s) = %s, want %s", feedURL, got, want)
+		}
+	}
+}
+
+func TestItem_Validate_Podcast(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    Item
+		wantErr string
+	}{
+		{"Valid", Item{
+			PodcastTranscripts: []PodcastTranscript{{URL: "https://example.com/ep.vtt", Type: "text/vtt"}},
+			PodcastChapters:    &PodcastChapters{URL: "https://example.com/ep.json", Type: "application/json+chapters"},
+			PodcastSoundbites:  []PodcastSoundbite{{StartTime: "0", Duration: "30.5"}},
+			PodcastPersons:     []PodcastPerson{{Name: "Jane", Role: "guest"}},
+			PodcastSeason:      &PodcastSeason{Number: "2", Name: "Oslo"},
+			PodcastEpisode:     &PodcastEpisode{Number: "3.5"},
+			PodcastLocation:    &PodcastLocation{Name: "Oslo", Geo: "geo:59.91,10.75"},
+			PodcastAlternateEnclosures: []PodcastAlternateEnclosure{{
+				Type:      "audio/opus",
+				Sources:   []PodcastSource{{URI: "https://example.com/ep.opus"}},
+				Integrity: &PodcastIntegrity{Type: "sri", Value: "sha384-abc"},
+			}},
+		}, ""},
+		{"Transcript without a type", Item{PodcastTranscripts: []PodcastTranscript{{URL: "https://example.com/ep.vtt"}}}, "PodcastTranscripts[0].Type is required"},
+		{"Soundbite without a duration", Item{PodcastSoundbites: []PodcastSoundbite{{StartTime: "1", Duration: "0"}}}, "PodcastSoundbites[0].Duration must be a positive number of seconds"},
+		{"Season is not a number", Item{PodcastSeason: &PodcastSeason{Number: "two"}}, "PodcastSeason.Number must be a positive integer"},
+		{"Episode-display is too long", Item{PodcastEpisode: &PodcastEpisode{Number: "1", Display: strings.Repeat("a", 33)}}, "PodcastEpisode.Display must be at most 32 characters"},
+		{"Person without a name", Item{PodcastPersons: []PodcastPerson{{Role: "host"}}}, "PodcastPersons[0].Name is required"},
+		{"Location with an invalid geo", Item{PodcastLocation: &PodcastLocation{Name: "Oslo", Geo: "59.91,10.75"}}, "PodcastLocation.Geo must be a geo-uri"},
+		{"Alternate enclosure without a source", Item{PodcastAlternateEnclosures: []PodcastAlternateEnclosure{{Type: "audio/opus"}}}, "PodcastAlternateEnclosures[0] must have at least one source"},
+		{"Images without a srcset", Item{PodcastImages: &PodcastImages{}}, "PodcastImages.Srcset is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.item.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Item.Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Item.Validate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChannel_Validate_Podcast(t *testing.T) {
+	c := Channel{
+		Locked:         "maybe",
+		GUID:           "not-a-uuid",
+		PodcastFunding: &PodcastFunding{Text: "Support us"},
+		Item:           []Item{{PodcastChapters: &PodcastChapters{}}},
+	}
+	err := c.Validate()
+	for _, want := range []string{"Locked must be one of", "GUID must be a lowercase uuid", "PodcastFunding.URL is required", "Item[0]: PodcastChapters.URL is required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Channel.Validate() error = %v, want %s", err, want)
+		}
+	}
+}
+
+func TestItem_MarshalPodcast(t *testing.T) {
+	item := Item{
+		Title:             "Episode 1",
+		PodcastPersons:    []PodcastPerson{{Name: "Jane", Role: "host"}},
+		PodcastSeason:     &PodcastSeason{Number: "2"},
+		PodcastSoundbites: []PodcastSoundbite{{StartTime: "10", Duration: "30", Title: "The best part"}},
+		PodcastAlternateEnclosures: []PodcastAlternateEnclosure{{
+			Type:    "audio/opus",
+			Sources: []PodcastSource{{URI: "https://example.com/ep.opus"}},
+		}},
+	}
+	b, err := xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	for _, want := range []string{
+		`<podcast:soundbite startTime="10" duration="30">The best part</podcast:soundbite>`,
+		`<podcast:person role="host">Jane</podcast:person>`,
+		`<podcast:season>2</podcast:season>`,
+		`<podcast:alternateEnclosure type="audio/opus"><podcast:source uri="https://example.com/ep.opus"></podcast:source></podcast:alternateEnclosure>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the item to contain %s, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"podcast:chapters", "podcast:episode", "podcast:location", "podcast:images"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected the item not to contain %s, got:\n%s", unwanted, out)
+		}
+	}
+}
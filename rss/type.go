@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 )
 
 type Rss struct {
@@ -26,18 +27,23 @@ type Channel struct {
 	Link        Link   `xml:"link"`
 	Language    string `xml:"language"`
 	// Strict requirement for values. https://podcasters.apple.com/support/1691-apple-podcasts-categories
-	// TODO: add a method that ensures correct categories
+	// See AppleCategories, which are checked by Check
 	// max 2
 	Category []Category `xml:"itunes:category"`
-	Explicit string     `xml:"explicit"`
-	Image    Image      `xml:"image"`
+	// One of true or false. Apple also accepts the older values yes, no and clean
+	Explicit string `xml:"itunes:explicit"`
+	// The rss-image, which requires the url, title and link.
+	Image Image `xml:"image"`
+	// The artwork, which is required by Apple Podcasts. Set from the Image by WithItunesDefaults
+	ItunesImage *ItunesImage `xml:"itunes:image"`
 
 	// Recommended fields
 
-	// Tells podcast hosting platforms whether they are allowed to import this feed
-	Locked string `xml:"podcast:locked"`
+	// Tells podcast hosting platforms whether they are allowed to import this feed. One of yes or no
+	Locked string `xml:"podcast:locked,omitempty"`
 	// The globally unique identifier (GUID) for a podcast. The value is a UUIDv5, and generated from the RSS feed URL, with the protocol scheme and trailing slashes stripped off, combined with a unique "podcast" namespace which has a UUID of ead4c236-bf58-58c6-a2c6-a6b28d128cb6.
-	GUID string `xml:"podcast:guid"`
+	// See PodcastGUID
+	GUID string `xml:"podcast:guid,omitempty"`
 	// The group, person, or people responsible for creating the podcast.
 	Author string `xml:"itunes:author"`
 
@@ -45,26 +51,38 @@ type Channel struct {
 
 	// The copyright details for a podcast.
 	Copyright string `xml:"copyright"`
-	// A free-form text field to present a string in a podcast fee
-	PodcastTxt PodastTXT `xml:"podcast:txt"`
+	// A free-form text field to present a string in a podcast feed
+	PodcastTxt *PodastTXT `xml:"podcast:txt"`
 	// This element specifies the donation/funding links for the podcast. The content of the tag is the recommended string to be used with the link.
-	PodcastFunding PodcastFunding `xml:"podcast:funding"`
-	// Specifies the podcast as either episodic or se
+	PodcastFunding  *PodcastFunding  `xml:"podcast:funding"`
+	PodcastPersons  []PodcastPerson  `xml:"podcast:person"`
+	PodcastLocation *PodcastLocation `xml:"podcast:location"`
+	PodcastImages   *PodcastImages   `xml:"podcast:images"`
+	// Specifies the podcast as either episodic or serial.
 	// episodic is the default and assumed if this element is not present. This element is required for serial podcasts.
-	Type string `xml:"itunes:episodic"`
+	Type string `xml:"itunes:type,omitempty"`
 	// Specifies that a podcast is complete and will not post any more episodes in the future.
 	// The only valid value for this element is yes. All other values will be ignored.
-	Complete string `xml:"itunes:complete"`
+	Complete string `xml:"itunes:complete,omitempty"`
+	// The title of the podcast at Apple Podcasts, if it differs from Title
+	ItunesTitle string `xml:"itunes:title,omitempty"`
+	// The new url of the feed, if it has moved
+	NewFeedURL string `xml:"itunes:new-feed-url,omitempty"`
+	// Set to Yes to hide the podcast from Apple Podcasts
+	Block string `xml:"itunes:block,omitempty"`
+	// Contact-information for the owner of the podcast. Not shown publicly
+	Owner *Owner `xml:"itunes:owner"`
 
 	WebMaster      string `xml:"webMaster"`
 	ManagingEditor string `xml:"managingEditor"`
-	Owner          Owner  `xml:"owner"`
-	Keywords       string `xml:"keywords"`
+	Keywords       string `xml:"itunes:keywords,omitempty"`
 	PubDate        string `xml:"pubDate"`
-	Summary        string `xml:"summary"`
-	Subtitle       string `xml:"subtitle"`
+	Summary        string `xml:"itunes:summary,omitempty"`
+	Subtitle       string `xml:"itunes:subtitle,omitempty"`
 	LastBuildDate  string `xml:"lastBuildDate"`
 	Item           []Item `xml:"item"`
+	// Elements that are not modelled, kept by Parse
+	Extensions []Extension `xml:",any"`
 }
 
 type PodastTXT struct {
@@ -84,13 +102,59 @@ func (c Channel) Validate() (err error) {
 		req("Language", c.Language),
 		minmax("Category", len(c.Category), 1, 2),
 		req("Explicit", c.Explicit),
+		oneOf("Explicit", c.Explicit, "", "true", "false", "yes", "no", "clean"),
 		req("Image", c.Image.URL),
 		oneOf("Type", c.Type, "", "episodic", "serial"),
 		oneOf("Complete", c.Complete, "", "yes"),
+		c.validatePodcast(),
 	)
+	for i, item := range c.Item {
+		if itemErr := item.Validate(); itemErr != nil {
+			err = errors.Join(err, fmt.Errorf("Item[%d]: %w", i, itemErr))
+		}
+	}
 	return err
 }
 
+func (item Item) Validate() error {
+	return errors.Join(
+		oneOf("EpisodeType", item.EpisodeType, "", "full", "trailer", "bonus"),
+		oneOf("Explicit", item.Explicit, "", "true", "false", "yes", "no", "clean"),
+		positiveInt("Episode", item.Episode),
+		positiveInt("Season", item.Season),
+		item.validatePodcast(),
+	)
+}
+
+// WithItunesDefaults returns a copy of the channel, with the itunes-fields that can be derived from other fields set:
+//   - The itunes:image of the channel and its items, from their Image
+//   - The title and link of the rss-image, which are required by the rss-specification
+func (c Channel) WithItunesDefaults() Channel {
+	if c.Image.URL == "" {
+		c.Image.URL = c.Image.Href
+	}
+	if c.Image.URL != "" {
+		if c.Image.Title == "" {
+			c.Image.Title = c.Title
+		}
+		if c.Image.Link == "" {
+			c.Image.Link = c.Link.Href
+		}
+	}
+	if c.ItunesImage == nil {
+		c.ItunesImage = c.Image.itunes()
+	}
+	items := make([]Item, len(c.Item))
+	for i, item := range c.Item {
+		if item.ItunesImage == nil {
+			item.ItunesImage = item.Image.itunes()
+		}
+		items[i] = item
+	}
+	c.Item = items
+	return c
+}
+
 func req[T comparable](fieldname string, value T) error {
 	if value == *new(T) {
 		return ErrReq(fieldname)
@@ -105,6 +169,16 @@ func oneOf[T comparable](fieldname string, value T, options ...T) error {
 	return fmt.Errorf("%s must be one of %v", fieldname, options)
 }
 
+func positiveInt(fieldname string, value string) error {
+	if value == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(value); err != nil || n < 1 {
+		return fmt.Errorf("%s must be a positive integer, was %q", fieldname, value)
+	}
+	return nil
+}
+
 func minmax(fieldname string, length, min, max int) error {
 	if length < min {
 		return fmt.Errorf("%s has invalid length, must be greater than %d, was %d", fieldname, min, length)
@@ -129,25 +203,42 @@ func RssHeader(channel Channel) Rss {
 		Podcast:    "https://podcastindex.org/namespace/1.0",
 		Googleplay: "http://www.google.com/schemas/play-podcasts/1.0",
 		Version:    "2.0",
-		Channel:    channel,
+		Channel:    channel.WithItunesDefaults(),
 	}
 }
 
 type Image struct {
-	Text  string `xml:",chardata"`
-	Href  string `xml:"href,attr"`
+	Text string `xml:",chardata"`
+	// The artwork for itunes:image, if it differs from the URL. See Channel.WithItunesDefaults
+	Href  string `xml:"-"`
 	URL   string `xml:"url"`
 	Title string `xml:"title"`
 	Link  string `xml:"link"`
 }
+
+// itunes returns the artwork, preferring the Href, or nil if the image has none
+func (img Image) itunes() *ItunesImage {
+	href := img.Href
+	if href == "" {
+		href = img.URL
+	}
+	if href == "" {
+		return nil
+	}
+	return &ItunesImage{Href: href}
+}
+
+// ItunesImage is the artwork. Apple requires it to be a square jpg or png, between 1400x1400 and 3000x3000 pixels
+type ItunesImage struct {
+	Href string `xml:"href,attr"`
+}
 type Owner struct {
-	Text  string `xml:",chardata"`
-	Name  string `xml:"name"`
-	Email string `xml:"email"`
+	Name  string `xml:"itunes:name"`
+	Email string `xml:"itunes:email"`
 }
 type Category struct {
-	AttrText string      `xml:"text,attr"`
-	Category Subcategory `xml:"category"`
+	AttrText string       `xml:"text,attr"`
+	Category *Subcategory `xml:"itunes:category"`
 }
 type Subcategory struct {
 	AttrText string `xml:"text,attr"`
@@ -160,18 +251,48 @@ type Link struct {
 }
 
 type Item struct {
-	Title             string       `xml:"title"`
-	Description       string       `xml:"description"`
-	Summary           string       `xml:"summary"`
-	Subtitle          string       `xml:"subtitle"`
-	Category          ItemCategory `xml:"category"`
-	Enclosure         Enclosure    `xml:"enclosure"`
-	GUID              string       `xml:"guid"`
-	DurationInSeconds string       `xml:"duration"`
-	PubDate           string       `xml:"pubDate"`
-	Link              string       `xml:"link"`
-	// Not par
-	Image Image `xml:"-"`
+	Title       string       `xml:"title"`
+	Description string       `xml:"description"`
+	Summary     string       `xml:"itunes:summary,omitempty"`
+	Subtitle    string       `xml:"itunes:subtitle,omitempty"`
+	Category    ItemCategory `xml:"category"`
+	Enclosure   Enclosure    `xml:"enclosure"`
+	GUID        string       `xml:"guid"`
+	// The duration in seconds, or as HH:MM:SS
+	DurationInSeconds string `xml:"itunes:duration,omitempty"`
+	PubDate           string `xml:"pubDate"`
+	Link              string `xml:"link"`
+	// The title of the episode, without the number of the episode or season
+	ItunesTitle string `xml:"itunes:title,omitempty"`
+	// The number of the episode, within the season if there is one. Must be a positive integer
+	Episode string `xml:"itunes:episode,omitempty"`
+	// The number of the season. Must be a positive integer
+	Season string `xml:"itunes:season,omitempty"`
+	// One of full (the default), trailer or bonus
+	EpisodeType string `xml:"itunes:episodeType,omitempty"`
+	// One of true or false. Defaults to the Explicit of the channel
+	Explicit string `xml:"itunes:explicit,omitempty"`
+	// Set to Yes to hide the episode from Apple Podcasts
+	Block  string `xml:"itunes:block,omitempty"`
+	Author string `xml:"itunes:author,omitempty"`
+	// Not part of the rss-specification for items, but used for the itunes:image. See Channel.WithItunesDefaults
+	Image       Image        `xml:"-"`
+	ItunesImage *ItunesImage `xml:"itunes:image"`
+
+	// Podcasting 2.0, see podcast.go
+
+	PodcastTranscripts         []PodcastTranscript         `xml:"podcast:transcript"`
+	PodcastChapters            *PodcastChapters            `xml:"podcast:chapters"`
+	PodcastSoundbites          []PodcastSoundbite          `xml:"podcast:soundbite"`
+	PodcastPersons             []PodcastPerson             `xml:"podcast:person"`
+	PodcastSeason              *PodcastSeason              `xml:"podcast:season"`
+	PodcastEpisode             *PodcastEpisode             `xml:"podcast:episode"`
+	PodcastLocation            *PodcastLocation            `xml:"podcast:location"`
+	PodcastImages              *PodcastImages              `xml:"podcast:images"`
+	PodcastAlternateEnclosures []PodcastAlternateEnclosure `xml:"podcast:alternateEnclosure"`
+
+	// Elements that are not modelled, kept by Parse
+	Extensions []Extension `xml:",any"`
 }
 
 type Enclosure struct {
@@ -0,0 +1,84 @@
+package rss
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRssHeader_Itunes(t *testing.T) {
+	channel := Channel{
+		Title:       "Example podcast",
+		Description: "All about examples",
+		Link:        Link{Href: "https://example.com"},
+		Explicit:    "false",
+		Image:       Image{URL: "https://example.com/cover.png"},
+		Owner:       &Owner{Name: "Jane", Email: "jane@example.com"},
+		Category:    []Category{{AttrText: "Society & Culture", Category: &Subcategory{AttrText: "Documentary"}}},
+		Type:        "serial",
+		Summary:     "Summary",
+		Item: []Item{
+			{
+				Title:             "Episode 1",
+				GUID:              "ep-1",
+				DurationInSeconds: "1234",
+				Season:            "2",
+				Episode:           "1",
+				EpisodeType:       "full",
+				Image:             Image{URL: "https://example.com/ep-1.png"},
+			},
+			{Title: "Episode 2", GUID: "ep-2"},
+		},
+	}
+	b, err := xml.Marshal(RssHeader(channel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	for _, want := range []string{
+		`<itunes:explicit>false</itunes:explicit>`,
+		`<image><url>https://example.com/cover.png</url><title>Example podcast</title><link>https://example.com</link></image>`,
+		`<itunes:image href="https://example.com/cover.png"></itunes:image>`,
+		`<itunes:owner><itunes:name>Jane</itunes:name><itunes:email>jane@example.com</itunes:email></itunes:owner>`,
+		`<itunes:category text="Society &amp; Culture"><itunes:category text="Documentary"></itunes:category></itunes:category>`,
+		`<itunes:type>serial</itunes:type>`,
+		`<itunes:summary>Summary</itunes:summary>`,
+		`<itunes:duration>1234</itunes:duration>`,
+		`<itunes:episode>1</itunes:episode><itunes:season>2</itunes:season><itunes:episodeType>full</itunes:episodeType>`,
+		`<itunes:image href="https://example.com/ep-1.png"></itunes:image></item>`,
+		`<guid>ep-2</guid><pubDate></pubDate><link></link></item>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the feed to contain %s, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"<explicit>", "<owner>", "<summary>", "<duration>", "itunes:episodic", "<itunes:complete>"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected the feed not to contain %s, got:\n%s", unwanted, out)
+		}
+	}
+	if channel.Item[0].ItunesImage != nil {
+		t.Errorf("expected RssHeader not to change the items of the channel")
+	}
+}
+
+func TestItem_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    Item
+		wantErr bool
+	}{
+		{"Empty", Item{}, false},
+		{"Valid", Item{Episode: "1", Season: "3", EpisodeType: "bonus", Explicit: "true"}, false},
+		{"Episode is not a number", Item{Episode: "one"}, true},
+		{"Season is zero", Item{Season: "0"}, true},
+		{"Unknown episodeType", Item{EpisodeType: "extra"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.item.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Item.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
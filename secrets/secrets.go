@@ -0,0 +1,257 @@
+// Package secrets stores credentials, like api-tokens, encrypted at rest.
+//
+// Values are encrypted with AES-GCM using a master-key, which is never stored.
+// Secrets are read from the database every time they are used, so that a secret
+// can be rotated while the server is running.
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/runar-rkmedia/audio-mirror/db"
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+)
+
+const (
+	// Environment-variable with the master-key
+	MasterKeyEnv = "AUDIO_MIRROR_MASTER_KEY"
+	// Environment-variable with comma-separated previous master-keys, so that a server can read secrets
+	// while they are re-encrypted with its master-key by RotateMasterKey in another process
+	PreviousMasterKeysEnv = "AUDIO_MIRROR_PREVIOUS_MASTER_KEYS"
+)
+
+// Tokens of authenticators are stored as secrets with this prefix, followed by the name of the api
+const tokenSecretPrefix = "auth-token."
+
+var (
+	ErrNoMasterKey = errors.New("no master-key")
+	ErrUnknownKey  = errors.New("secret is encrypted with an unknown master-key")
+)
+
+type (
+	Repository interface {
+		GetSecret(ctx context.Context, name string) (db.Secret, error)
+		ListSecrets(ctx context.Context) ([]db.Secret, error)
+		UpsertSecret(ctx context.Context, secret db.Secret) error
+		DeleteSecret(ctx context.Context, name string) error
+	}
+	Store struct {
+		repo    Repository
+		mu      sync.RWMutex
+		current masterKey
+		// Previous master-keys, which can still be used to decrypt secrets that are not yet rotated
+		previous map[string]masterKey
+	}
+	masterKey struct {
+		id   string
+		aead cipher.AEAD
+	}
+)
+
+// NewStore creates a store which encrypts secrets with the master-key.
+// The previous keys are only used for decryption, and secrets encrypted with
+// them are re-encrypted with the current key by RotateMasterKey
+func NewStore(repo Repository, key string, previousKeys ...string) (*Store, error) {
+	current, err := newMasterKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{repo: repo, current: current, previous: map[string]masterKey{}}
+	for _, k := range previousKeys {
+		prev, err := newMasterKey(k)
+		if err != nil {
+			return nil, err
+		}
+		s.previous[prev.id] = prev
+	}
+	return s, nil
+}
+
+// NewStoreFromEnv reads the master-key from AUDIO_MIRROR_MASTER_KEY, and the previous keys from AUDIO_MIRROR_PREVIOUS_MASTER_KEYS
+func NewStoreFromEnv(repo Repository) (*Store, error) {
+	var previous []string
+	for _, k := range strings.Split(os.Getenv(PreviousMasterKeysEnv), ",") {
+		if k != "" {
+			previous = append(previous, k)
+		}
+	}
+	return NewStore(repo, os.Getenv(MasterKeyEnv), previous...)
+}
+
+// Any passphrase can be used as a master-key, it is hashed into a 256-bit key.
+func newMasterKey(key string) (masterKey, error) {
+	if key == "" {
+		return masterKey{}, ErrNoMasterKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return masterKey{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return masterKey{}, err
+	}
+	// The id is derived from the key, but does not reveal it
+	idSum := sha256.Sum256(sum[:])
+	return masterKey{id: hex.EncodeToString(idSum[:4]), aead: aead}, nil
+}
+
+func (k masterKey) encrypt(name, value string) (db.Secret, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return db.Secret{}, fmt.Errorf("failed to create nonce: %w", err)
+	}
+	return db.Secret{
+		Name:  name,
+		KeyID: k.id,
+		Nonce: nonce,
+		// The name is used as additional data, so that a ciphertext cannot be moved to another secret.
+		Ciphertext: k.aead.Seal(nil, nonce, []byte(value), []byte(name)),
+	}, nil
+}
+
+func (k masterKey) decrypt(secret db.Secret) (string, error) {
+	plain, err := k.aead.Open(nil, secret.Nonce, secret.Ciphertext, []byte(secret.Name))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret %s: %w", secret.Name, err)
+	}
+	return string(plain), nil
+}
+
+func (s *Store) key(id string) (masterKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id == s.current.id {
+		return s.current, nil
+	}
+	if k, ok := s.previous[id]; ok {
+		return k, nil
+	}
+	return masterKey{}, fmt.Errorf("%w (%s)", ErrUnknownKey, id)
+}
+
+// GetSecret implements genapi.SecretStore.
+func (s *Store) GetSecret(ctx context.Context, name string) (string, error) {
+	secret, err := s.repo.GetSecret(ctx, name)
+	if errors.Is(err, db.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s", genapi.ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	k, err := s.key(secret.KeyID)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return k.decrypt(secret)
+}
+
+// SetSecret creates or replaces the secret. Requests made after this will use the new value.
+func (s *Store) SetSecret(ctx context.Context, name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret-name is required")
+	}
+	s.mu.RLock()
+	current := s.current
+	s.mu.RUnlock()
+	secret, err := current.encrypt(name, value)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpsertSecret(ctx, secret)
+}
+
+// GetToken implements genapi.TokenStore. Returns nil if there is no token for the key
+func (s *Store) GetToken(ctx context.Context, key string) (*genapi.Token, error) {
+	v, err := s.GetSecret(ctx, tokenSecretPrefix+key)
+	if errors.Is(err, genapi.ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t genapi.Token
+	if err := json.Unmarshal([]byte(v), &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token %s: %w", key, err)
+	}
+	return &t, nil
+}
+
+// SetToken implements genapi.TokenStore. The token is encrypted like any other secret
+func (s *Store) SetToken(ctx context.Context, key string, token *genapi.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return s.SetSecret(ctx, tokenSecretPrefix+key, string(b))
+}
+
+func (s *Store) DeleteSecret(ctx context.Context, name string) error {
+	return s.repo.DeleteSecret(ctx, name)
+}
+
+// ListSecrets returns the names of all secrets, but not their values.
+func (s *Store) ListSecrets(ctx context.Context) ([]string, error) {
+	secrets, err := s.repo.ListSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(secrets))
+	for i, secret := range secrets {
+		names[i] = secret.Name
+	}
+	return names, nil
+}
+
+// RotateMasterKey re-encrypts every secret with the new key, which then becomes the current key.
+// The old key is kept as a previous key, so secrets can still be read if the rotation fails halfway.
+// Returns the number of secrets that were re-encrypted.
+func (s *Store) RotateMasterKey(ctx context.Context, newKey string) (int, error) {
+	next, err := newMasterKey(newKey)
+	if err != nil {
+		return 0, err
+	}
+	secrets, err := s.repo.ListSecrets(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rotated := 0
+	for _, secret := range secrets {
+		if secret.KeyID == next.id {
+			continue
+		}
+		k, err := s.key(secret.KeyID)
+		if err != nil {
+			return rotated, fmt.Errorf("secret %s: %w", secret.Name, err)
+		}
+		value, err := k.decrypt(secret)
+		if err != nil {
+			return rotated, err
+		}
+		encrypted, err := next.encrypt(secret.Name, value)
+		if err != nil {
+			return rotated, err
+		}
+		if err := s.repo.UpsertSecret(ctx, encrypted); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	s.mu.Lock()
+	s.previous[s.current.id] = s.current
+	s.current = next
+	s.mu.Unlock()
+	return rotated, nil
+}
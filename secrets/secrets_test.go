@@ -0,0 +1,189 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/runar-rkmedia/audio-mirror/db"
+	"github.com/runar-rkmedia/audio-mirror/genapi"
+)
+
+func newTestStore(t *testing.T, key string) (*Store, *db.DB) {
+	t.Helper()
+	d, err := db.CreateDatabase(db.DBOptions{FilePath: "file:" + t.Name() + "?mode=memory&cache=shared"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.DB.Close() })
+	s, err := NewStore(d, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, d
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.TODO()
+	s, d := newTestStore(t, "correct horse battery staple")
+	if _, err := s.GetSecret(ctx, "untold.token"); !errors.Is(err, genapi.ErrSecretNotFound) {
+		t.Fatalf("expected ErrSecretNotFound, got %v", err)
+	}
+	if err := s.SetSecret(ctx, "untold.token", "Bearer abc"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := d.GetSecret(ctx, "untold.token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stored.Ciphertext) == "Bearer abc" || len(stored.Nonce) == 0 {
+		t.Fatalf("expected the secret to be encrypted, got %#v", stored)
+	}
+	// Rotating the secret itself
+	if err := s.SetSecret(ctx, "untold.token", "Bearer def"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetSecret(ctx, "untold.token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bearer def" {
+		t.Errorf("GetSecret() = %q, want %q", got, "Bearer def")
+	}
+
+	// A ciphertext cannot be moved to another secret
+	stored, _ = d.GetSecret(ctx, "untold.token")
+	stored.Name = "other.token"
+	if err := d.UpsertSecret(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetSecret(ctx, "other.token"); err == nil {
+		t.Errorf("expected decryption of a moved secret to fail")
+	}
+
+	wrongKey, err := NewStore(d, "wrong key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongKey.GetSecret(ctx, "untold.token"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestStore_RotateMasterKey(t *testing.T) {
+	ctx := context.TODO()
+	s, d := newTestStore(t, "old key")
+	for _, name := range []string{"a.token", "b.token"} {
+		if err := s.SetSecret(ctx, name, name+"-value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := s.RotateMasterKey(ctx, "new key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 secrets to be rotated, got %d", n)
+	}
+	// A store created with only the new key must be able to read the secrets
+	rotated, err := NewStore(d, "new key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.token", "b.token"} {
+		for _, store := range []*Store{s, rotated} {
+			got, err := store.GetSecret(ctx, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != name+"-value" {
+				t.Errorf("GetSecret(%s) = %q", name, got)
+			}
+		}
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	if _, err := NewStore(nil, ""); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("expected ErrNoMasterKey, got %v", err)
+	}
+}
+
+func TestStore_Token(t *testing.T) {
+	ctx := context.TODO()
+	s, d := newTestStore(t, "key")
+	if token, err := s.GetToken(ctx, "untold"); err != nil || token != nil {
+		t.Fatalf("expected no token, got %v, %v", token, err)
+	}
+	want := &genapi.Token{AccessToken: "access", RefreshToken: "refresh"}
+	if err := s.SetToken(ctx, "untold", want); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := d.GetSecret(ctx, "auth-token.untold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(stored.Ciphertext), "refresh") {
+		t.Errorf("expected the token to be encrypted, got %s", stored.Ciphertext)
+	}
+	got, err := s.GetToken(ctx, "untold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *want {
+		t.Errorf("GetToken() = %#v, want %#v", got, want)
+	}
+}
+
+func TestStore_RotateMasterKey_Live(t *testing.T) {
+	ctx := context.TODO()
+	old, d := newTestStore(t, "old key")
+	if err := old.SetSecret(ctx, "a.token", "a-value"); err != nil {
+		t.Fatal(err)
+	}
+	// The server is restarted with the new key, and the old key as a previous key
+	live, err := NewStore(d, "new key", "old key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := live.SetSecret(ctx, "b.token", "b-value"); err != nil {
+		t.Fatal(err)
+	}
+	// Then another process rotates the secrets, while the server is running
+	if _, err := old.RotateMasterKey(ctx, "new key"); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewStore(d, "new key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.token", "b.token"} {
+		for _, store := range []*Store{live, rotated} {
+			got, err := store.GetSecret(ctx, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.TrimSuffix(name, ".token") + "-value"; got != want {
+				t.Errorf("GetSecret(%s) = %q, want %q", name, got, want)
+			}
+		}
+	}
+}
+
+func TestNewStoreFromEnv(t *testing.T) {
+	ctx := context.TODO()
+	old, d := newTestStore(t, "old key")
+	if err := old.SetSecret(ctx, "a.token", "a-value"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(MasterKeyEnv, "new key")
+	t.Setenv(PreviousMasterKeysEnv, "other key,old key")
+	s, err := NewStoreFromEnv(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetSecret(ctx, "a.token"); err != nil || got != "a-value" {
+		t.Errorf("expected the secret to be read with a previous key, got %q %v", got, err)
+	}
+}